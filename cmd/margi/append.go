@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gcaixeta/marginalia/internal/note"
+	"github.com/gcaixeta/marginalia/internal/storage"
+)
+
+const appendUsage = `margi append <note> "text" | margi append <note> --stdin`
+
+// runAppend adds a timestamped line to the end of a note, without opening
+// the editor
+func runAppend(args []string, sync storage.Backup) {
+	fs := newFlagSet("append")
+	fromStdin := fs.Bool("stdin", false, "read the text from stdin")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		usageError(err, appendUsage)
+		exit(2)
+	}
+
+	var text string
+	switch {
+	case *fromStdin && len(positional) == 1:
+		input, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading stdin: %v\n", err)
+			exit(1)
+		}
+		text = string(input)
+	case !*fromStdin && len(positional) == 2:
+		text = positional[1]
+	default:
+		usageError(fmt.Errorf("expected a note and either a text or --stdin"), appendUsage)
+		exit(2)
+	}
+	if strings.TrimSpace(text) == "" {
+		fmt.Fprintln(os.Stderr, "Nothing to append")
+		exit(1)
+	}
+
+	file, err := resolveAppendNote(positional[0], *fromStdin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(1)
+	}
+
+	store, err := storage.Store()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening notes: %v\n", err)
+		exit(1)
+	}
+	content, err := store.Read(file.Collection, file.Name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading note: %v\n", err)
+		exit(1)
+	}
+	if err := store.Write(file.Collection, file.Name, note.AppendEntry(content, text, time.Now())); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing note: %v\n", err)
+		exit(1)
+	}
+	fmt.Printf("✓ appended to %s/%s\n", file.Collection, file.Name)
+
+	if sync != nil {
+		if err := sync.CommitAndPush("append: " + file.Collection + "/" + file.Name); err != nil {
+			fmt.Printf("Warning: backup failed: %v\n", err)
+		}
+	}
+}
+
+// resolveAppendNote is resolveNote for scripts: when several notes match
+// and stdin can't be used to pick one, the matches are reported instead
+func resolveAppendNote(term string, fromStdin bool) (*storage.FileItem, error) {
+	if !fromStdin && isTerminal(os.Stdin) {
+		return resolveNote(term)
+	}
+
+	files, err := storage.ListAllFiles()
+	if err != nil {
+		return nil, err
+	}
+	matches := matchNotes(files, term)
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no notes found matching: %s", term)
+	case 1:
+		return &matches[0], nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d notes match %s, be more specific:", len(matches), term)
+	for _, file := range matches {
+		fmt.Fprintf(&b, "\n  %s/%s", file.Collection, file.Name)
+	}
+	return nil, fmt.Errorf("%s", b.String())
+}
+
+// pipeWait is how long pipedInput waits for piped data to start arriving
+const pipeWait = 200 * time.Millisecond
+
+// pipedInput returns what is piped or redirected into stdin, or nothing
+// when stdin is a terminal, a device such as /dev/null, or a pipe that
+// stays silent for wait: a cron job or wrapper may leave an open pipe that
+// never delivers anything, and reading it would block forever.
+func pipedInput(wait time.Duration) ([]byte, error) {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return nil, nil
+	}
+	if info.Mode().IsRegular() {
+		return io.ReadAll(os.Stdin)
+	}
+	if info.Mode()&os.ModeNamedPipe == 0 {
+		return nil, nil
+	}
+
+	type chunk struct {
+		data []byte
+		err  error
+	}
+	first := make(chan chunk, 1)
+	go func() {
+		buf := make([]byte, 4096)
+		n, err := os.Stdin.Read(buf)
+		first <- chunk{buf[:n], err}
+	}()
+
+	select {
+	case c := <-first:
+		if c.err == io.EOF {
+			return c.data, nil
+		}
+		if c.err != nil {
+			return nil, c.err
+		}
+		rest, err := io.ReadAll(os.Stdin)
+		return append(c.data, rest...), err
+	case <-time.After(wait):
+		return nil, nil
+	}
+}
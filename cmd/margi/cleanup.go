@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// cleanups run when margi exits, see exit. The mutex keeps a signal from
+// running them while the main goroutine registers or runs them.
+var (
+	cleanupMu sync.Mutex
+	cleanups  []func()
+	cleaned   bool
+)
+
+// addCleanup registers f to run when margi exits. When margi is already
+// exiting, f runs right away.
+func addCleanup(f func()) {
+	cleanupMu.Lock()
+	defer cleanupMu.Unlock()
+	if cleaned {
+		f()
+		return
+	}
+	cleanups = append(cleanups, f)
+}
+
+// runCleanups runs the cleanups, most recently added first, once. A
+// concurrent call waits for the first to finish.
+func runCleanups() {
+	cleanupMu.Lock()
+	defer cleanupMu.Unlock()
+	if cleaned {
+		return
+	}
+	cleaned = true
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		cleanups[i]()
+	}
+}
+
+// exit runs the cleanups and ends margi with code. Commands call it instead
+// of os.Exit, so nothing registered in cleanups is skipped.
+func exit(code int) {
+	runCleanups()
+	os.Exit(code)
+}
+
+// cleanUpOnSignal makes interrupted commands clean up too
+func cleanUpOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		exit(1)
+	}()
+}
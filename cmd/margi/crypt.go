@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gcaixeta/marginalia/internal/config"
+	"github.com/gcaixeta/marginalia/internal/crypt"
+	"github.com/gcaixeta/marginalia/internal/editor"
+	"github.com/gcaixeta/marginalia/internal/storage"
+	"golang.org/x/term"
+)
+
+// passphraseEnv is the environment variable holding the passphrase of an
+// encrypted vault, to skip the prompt
+const passphraseEnv = "MARGI_PASSPHRASE"
+
+// useEncryption unlocks the vault when its notes are encrypted and makes
+// every command go through the encrypted store. Notes written before
+// encryption was enabled are encrypted on the way.
+func useEncryption(vault config.VaultConfig) error {
+	if !vault.Encryption.Enabled {
+		return nil
+	}
+	dataDir, err := storage.DataDir()
+	if err != nil {
+		return err
+	}
+
+	key, err := crypt.Unlock(dataDir, vault.Encryption.Identity, readPassphrase)
+	if err != nil {
+		return err
+	}
+	cacheDir, err := storage.CacheDir()
+	if err != nil {
+		return err
+	}
+	store, err := storage.NewCryptStore(dataDir, cacheDir, key, vault.Encryption.ObfuscateNames)
+	if err != nil {
+		os.RemoveAll(cacheDir)
+		return err
+	}
+	addCleanup(func() { store.Wipe() })
+	storage.UseStore(store)
+
+	sealed, err := store.SealAll()
+	if err != nil {
+		return fmt.Errorf("encrypting notes: %w", err)
+	}
+	if sealed > 0 {
+		fmt.Printf("🔒 encrypted %d note(s)\n", sealed)
+	}
+	return nil
+}
+
+// readPassphrase takes the passphrase from $MARGI_PASSPHRASE or asks for
+// it on the terminal, twice when confirm is set
+func readPassphrase(confirm bool) (string, error) {
+	if pass := os.Getenv(passphraseEnv); pass != "" {
+		return pass, nil
+	}
+
+	// Stdin may carry a note, so ask on the terminal itself
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", fmt.Errorf("no terminal to ask for the passphrase, set $%s", passphraseEnv)
+	}
+	defer tty.Close()
+
+	ask := func(prompt string) (string, error) {
+		fmt.Fprint(tty, prompt)
+		pass, err := term.ReadPassword(int(tty.Fd()))
+		fmt.Fprintln(tty)
+		return string(pass), err
+	}
+	pass, err := ask("Passphrase: ")
+	if err != nil || !confirm {
+		return pass, err
+	}
+	again, err := ask("Repeat passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if again != pass {
+		return "", fmt.Errorf("passphrases don't match")
+	}
+	return pass, nil
+}
+
+// openNote opens a note in the editor. Encrypted notes are decrypted into
+// a temporary file that is wiped once the editor exits.
+func openNote(path, editorCmd string) {
+	store := storage.Encrypted()
+	if store == nil {
+		runEditor(path, editorCmd)
+		return
+	}
+	err := store.Edit(path, func(tmp string) error {
+		return editor.Run(tmp, editorCmd)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving encrypted note: %v\n", err)
+		exit(1)
+	}
+}
+
+// runEditor opens path in the editor, exiting through exit when it fails
+// so the cleanups still run
+func runEditor(path, editorCmd string) {
+	if err := editor.Run(path, editorCmd); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(1)
+	}
+}
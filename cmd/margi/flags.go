@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// newFlagSet returns a FlagSet for a subcommand that reports errors
+// instead of exiting, so each command can print its own usage line.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// parseFlags parses args allowing flags and positional arguments to be
+// interleaved (e.g. `margi list journal --sort mtime`). It returns the
+// positional arguments in order.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// flagSet reports whether the flag called name was given on the command
+// line, to tell `--stdin=false` apart from no flag at all
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// isTerminal reports whether f is connected to a terminal, e.g. whether
+// stdin is interactive or piped
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// usageError prints an error and the usage line of a command to stderr.
+func usageError(err error, usage string) {
+	fmt.Fprintf(os.Stderr, "%v\nUsage: %s\n", err, usage)
+}
+
+// stringList is a flag that can be repeated, e.g. `--tag work --tag q3`.
+// Comma separated values are split as well.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*s = append(*s, v)
+		}
+	}
+	return nil
+}
+
+// keyValues is a repeatable key=value flag, e.g. `--set kind=retro`
+type keyValues map[string]string
+
+func (kv keyValues) String() string {
+	pairs := make([]string, 0, len(kv))
+	for k, v := range kv {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (kv keyValues) Set(value string) error {
+	k, v, ok := strings.Cut(value, "=")
+	if !ok || strings.TrimSpace(k) == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	kv[strings.TrimSpace(k)] = v
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gcaixeta/marginalia/internal/crypt"
+	"github.com/gcaixeta/marginalia/internal/editor"
+	"github.com/gcaixeta/marginalia/internal/storage"
+	"github.com/gcaixeta/marginalia/internal/ui"
+)
+
+const historyUsage = "margi history <note>"
+
+// runHistory browses the revisions of a note, opening them read-only or
+// restoring one as a new commit.
+func runHistory(args []string, editorCmd string, sync *storage.GitSync) {
+	if len(args) != 1 {
+		usageError(fmt.Errorf("expected a note"), historyUsage)
+		exit(2)
+	}
+	if sync == nil {
+		fmt.Fprintln(os.Stderr, "git backup not configured")
+		return
+	}
+
+	file, err := resolveNote(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(1)
+	}
+	title := file.Collection + "/" + file.Name
+
+	revisions, err := sync.Log(storage.NotePath(*file), 0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading history: %v\n", err)
+		exit(1)
+	}
+	if len(revisions) == 0 {
+		fmt.Printf("%s has no committed history\n", title)
+		return
+	}
+
+	cursor := 0
+	for {
+		index, action, err := ui.RunHistoryPicker(title, revisions, sync.Diff, cursor)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		cursor = index
+
+		switch action {
+		case ui.HistoryView:
+			if err := viewRevision(revisions[index], editorCmd, sync); err != nil {
+				fmt.Fprintf(os.Stderr, "Error opening revision: %v\n", err)
+				exit(1)
+			}
+		case ui.HistoryRestore:
+			if err := restoreRevision(*file, revisions[index], sync); err != nil {
+				fmt.Fprintf(os.Stderr, "Error restoring revision: %v\n", err)
+				exit(1)
+			}
+			return
+		default:
+			return
+		}
+	}
+}
+
+// viewRevision opens a revision in the editor from a read-only temporary
+// file, removed once the editor exits
+func viewRevision(rev storage.Revision, editorCmd string, sync *storage.GitSync) error {
+	content, err := sync.Show(rev)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "margi-history-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	// The hash in the name tells the revision apart from the live note
+	path := filepath.Join(dir, rev.Short()+"-"+filepath.Base(rev.Path))
+	if err := os.WriteFile(path, content, 0444); err != nil {
+		return err
+	}
+	err = editor.Run(path, editorCmd)
+	if storage.Encrypted() != nil {
+		os.Chmod(path, 0600)
+		crypt.Wipe(path)
+	}
+	return err
+}
+
+// restoreRevision replaces the note's content with a revision and commits
+// the result, so the restore itself can be undone from the history
+func restoreRevision(file storage.FileItem, rev storage.Revision, sync *storage.GitSync) error {
+	content, err := sync.Show(rev)
+	if err != nil {
+		return err
+	}
+
+	store, err := storage.Store()
+	if err != nil {
+		return err
+	}
+	title := file.Collection + "/" + file.Name
+	current, err := store.Read(file.Collection, file.Name)
+	if err == nil && bytes.Equal(current, content) {
+		fmt.Printf("%s already matches %s\n", title, rev.Short())
+		return nil
+	}
+	// Commit pending edits first so the version being replaced stays in
+	// the history too.
+	if err := sync.CommitAndPush("edit: " + title); err != nil {
+		return err
+	}
+	if err := store.Write(file.Collection, file.Name, content); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ %s restored to %s (%s)\n", title, rev.Short(), rev.Date.Local().Format("2006-01-02 15:04"))
+	if err := sync.CommitAndPush(fmt.Sprintf("restore: %s to %s", title, rev.Short())); err != nil {
+		fmt.Printf("Warning: git sync failed: %v\n", err)
+	}
+	return nil
+}
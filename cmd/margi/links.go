@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gcaixeta/marginalia/internal/links"
+	"github.com/gcaixeta/marginalia/internal/storage"
+)
+
+const (
+	backlinksUsage = "margi backlinks <note>"
+	linksUsage     = "margi links <note> | margi links --broken"
+)
+
+func runBacklinks(args []string) {
+	if len(args) != 1 {
+		usageError(fmt.Errorf("expected one note"), backlinksUsage)
+		exit(2)
+	}
+
+	target, err := resolveNote(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(1)
+	}
+
+	store, files, err := listNotes()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing files: %v\n", err)
+		exit(1)
+	}
+
+	refs, err := links.Backlinks(store, files, *target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error scanning links: %v\n", err)
+		exit(1)
+	}
+
+	if len(refs) == 0 {
+		fmt.Printf("No notes link to %s/%s\n", target.Collection, target.Name)
+		return
+	}
+
+	fmt.Printf("Notes linking to %s/%s:\n\n", target.Collection, target.Name)
+	printReferences(refs)
+}
+
+func runLinks(args []string) {
+	fs := newFlagSet("links")
+	broken := fs.Bool("broken", false, "report links that don't resolve to any note")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		usageError(err, linksUsage)
+		exit(2)
+	}
+	if *broken == (len(positional) == 1) || len(positional) > 1 {
+		usageError(fmt.Errorf("expected either a note or --broken"), linksUsage)
+		exit(2)
+	}
+
+	store, files, err := listNotes()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing files: %v\n", err)
+		exit(1)
+	}
+
+	if *broken {
+		refs, err := links.Broken(store, files)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error scanning links: %v\n", err)
+			exit(1)
+		}
+		if len(refs) == 0 {
+			fmt.Println("✓ No broken links")
+			return
+		}
+		printReferences(refs)
+		fmt.Printf("\n%d broken link(s)\n", len(refs))
+		return
+	}
+
+	source, err := resolveNote(positional[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(1)
+	}
+
+	refs, err := links.Scan(store, []storage.FileItem{*source})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error scanning links: %v\n", err)
+		exit(1)
+	}
+	if len(refs) == 0 {
+		fmt.Printf("%s/%s has no links\n", source.Collection, source.Name)
+		return
+	}
+
+	r := links.NewResolver(files)
+	for _, ref := range refs {
+		if file, ok := r.Resolve(ref.Link, ref.Source); ok {
+			fmt.Printf("%4d: [[%s]] → %s/%s\n", ref.Link.Line, ref.Link.Raw, file.Collection, file.Name)
+		} else {
+			fmt.Printf("%4d: [[%s]] → (broken)\n", ref.Link.Line, ref.Link.Raw)
+		}
+	}
+}
+
+func printReferences(refs []links.Reference) {
+	for _, ref := range refs {
+		fmt.Printf("%s/%s:%d  [[%s]]\n", ref.Source.Collection, ref.Source.Name, ref.Link.Line, ref.Link.Raw)
+	}
+}
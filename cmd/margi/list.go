@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gcaixeta/marginalia/internal/storage"
+)
+
+const listUsage = "margi list [collection] [--tag tag] [--sort name|mtime|size] [--reverse] [--format table|paths|json]"
+
+// listEntry is the JSON representation of a note in `margi list --format json`.
+type listEntry struct {
+	Collection string    `json:"collection"`
+	Name       string    `json:"name"`
+	Path       string    `json:"path"`
+	Modified   time.Time `json:"modified"`
+	Size       int64     `json:"size"`
+}
+
+func listFiles(args []string) {
+	fs := newFlagSet("list")
+	sortBy := fs.String("sort", "name", "sort by name, mtime or size")
+	reverse := fs.Bool("reverse", false, "reverse the sort order")
+	format := fs.String("format", "table", "output format: table, paths or json")
+	var tags stringList
+	fs.Var(&tags, "tag", "only list notes with this tag (repeatable)")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		usageError(err, listUsage)
+		exit(2)
+	}
+	if len(positional) > 1 {
+		usageError(fmt.Errorf("too many arguments"), listUsage)
+		exit(2)
+	}
+
+	collectionName := ""
+	if len(positional) == 1 {
+		collectionName = positional[0]
+	}
+
+	store, files, err := listNotes()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing files: %v\n", err)
+		exit(1)
+	}
+	allFiles := files
+	files = storage.FilterByCollection(files, collectionName)
+
+	if len(tags) > 0 {
+		ix, err := openIndex(store, allFiles)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening search index: %v\n", err)
+			exit(1)
+		}
+		files = ix.FilterTags(files, tags)
+	}
+
+	if err := storage.SortFiles(files, *sortBy, *reverse); err != nil {
+		usageError(err, listUsage)
+		exit(2)
+	}
+
+	switch *format {
+	case "table":
+		printFileTable(files)
+	case "paths":
+		for _, file := range files {
+			fmt.Println(file.Path)
+		}
+	case "json":
+		entries := make([]listEntry, 0, len(files))
+		for _, file := range files {
+			entries = append(entries, listEntry{
+				Collection: file.Collection,
+				Name:       file.Name,
+				Path:       file.Path,
+				Modified:   file.ModTime,
+				Size:       file.Size,
+			})
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(entries); err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
+			exit(1)
+		}
+	default:
+		usageError(fmt.Errorf("unknown format %q", *format), listUsage)
+		exit(2)
+	}
+}
+
+func printFileTable(files []storage.FileItem) {
+	if len(files) == 0 {
+		fmt.Println("No notes found.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COLLECTION\tNAME\tMODIFIED\tSIZE")
+	for _, file := range files {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			file.Collection,
+			file.Name,
+			file.ModTime.Format("2006-01-02 15:04"),
+			formatSize(file.Size),
+		)
+	}
+	w.Flush()
+}
+
+// formatSize formats a byte count as a short human readable string.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gcaixeta/marginalia/internal/storage"
+)
+
+const logUsage = "margi log [note] [--limit n] [--no-diff]"
+
+// runLog prints the backup history: the repository's commits, or the
+// revisions of a single note with the diff each one made.
+func runLog(args []string, sync *storage.GitSync) {
+	fs := newFlagSet("log")
+	limit := fs.Int("limit", 0, "show at most n commits (default 20 for the repository, all for a note)")
+	noDiff := fs.Bool("no-diff", false, "list revisions without their diffs")
+	positional, err := parseFlags(fs, args)
+	if err == nil && len(positional) > 1 {
+		err = fmt.Errorf("too many arguments")
+	}
+	if err == nil && *limit < 0 {
+		err = fmt.Errorf("--limit must not be negative")
+	}
+	if err != nil {
+		usageError(err, logUsage)
+		exit(2)
+	}
+
+	if sync == nil {
+		fmt.Fprintln(os.Stderr, "git backup not configured")
+		return
+	}
+
+	if len(positional) == 0 {
+		if *limit == 0 {
+			*limit = 20
+		}
+		revisions, err := sync.Log("", *limit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading history: %v\n", err)
+			exit(1)
+		}
+		for _, rev := range revisions {
+			fmt.Printf("%s  %s  %s\n", rev.Short(), rev.Date.Local().Format("2006-01-02 15:04"), rev.Subject)
+		}
+		return
+	}
+
+	file, err := resolveNote(positional[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(1)
+	}
+
+	revisions, err := sync.Log(storage.NotePath(*file), *limit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading history: %v\n", err)
+		exit(1)
+	}
+	if len(revisions) == 0 {
+		fmt.Printf("%s/%s has no committed history\n", file.Collection, file.Name)
+		return
+	}
+
+	for i, rev := range revisions {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s  %s  %s  %s\n", rev.Short(), rev.Date.Local().Format("2006-01-02 15:04"), rev.Author, rev.Subject)
+		if rev.OldPath != "" {
+			fmt.Printf("renamed from %s\n", rev.OldPath)
+		}
+		if *noDiff {
+			continue
+		}
+
+		diff, err := sync.Diff(rev)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", rev.Short(), err)
+			continue
+		}
+		fmt.Print(indentDiff(diff))
+	}
+}
+
+// indentDiff drops git's file headers and indents the hunks under their
+// revision line
+func indentDiff(diff string) string {
+	var b strings.Builder
+	inHunk := false
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "diff --git ") {
+			inHunk = false
+			continue
+		}
+		if strings.HasPrefix(line, "@@") {
+			inHunk = true
+		}
+		if inHunk {
+			b.WriteString("    " + line)
+		}
+	}
+	return b.String()
+}
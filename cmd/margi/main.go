@@ -1,20 +1,29 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/gcaixeta/marginalia/internal/collection"
 	"github.com/gcaixeta/marginalia/internal/config"
 	"github.com/gcaixeta/marginalia/internal/editor"
+	"github.com/gcaixeta/marginalia/internal/period"
 	"github.com/gcaixeta/marginalia/internal/slug"
 	"github.com/gcaixeta/marginalia/internal/snippet"
 	"github.com/gcaixeta/marginalia/internal/storage"
 	"github.com/gcaixeta/marginalia/internal/ui"
 )
 
-func editFile(title, editorCmd string, sync *storage.GitSync) {
+func editFile(title, editorCmd string, sync storage.Backup) {
 	files, err := storage.FindFilePath(title)
 	if err != nil {
 		fmt.Printf("Error searching for files: %v\n", err)
@@ -27,17 +36,17 @@ func editFile(title, editorCmd string, sync *storage.GitSync) {
 	}
 
 	if len(files) == 1 {
-		editor.OpenInEditor(files[0], editorCmd)
+		openNote(files[0], editorCmd)
 		if sync != nil {
 			if err := sync.CommitAndPush("edit: " + title); err != nil {
-				fmt.Printf("Warning: git sync failed: %v\n", err)
+				fmt.Printf("Warning: backup failed: %v\n", err)
 			}
 		}
 		return
 	}
 
 	fmt.Println("Multiple files found. Please choose one:")
-	dataDir, _ := storage.DataDir()
+	dataDir, _ := storage.PlainDir()
 	for i, file := range files {
 		relPath, err := filepath.Rel(dataDir, file)
 		if err != nil {
@@ -54,59 +63,47 @@ func editFile(title, editorCmd string, sync *storage.GitSync) {
 		return
 	}
 
-	editor.OpenInEditor(files[choice-1], editorCmd)
+	openNote(files[choice-1], editorCmd)
 	if sync != nil {
 		if err := sync.CommitAndPush("edit: " + title); err != nil {
-			fmt.Printf("Warning: git sync failed: %v\n", err)
+			fmt.Printf("Warning: backup failed: %v\n", err)
 		}
 	}
 }
 
-func newFile(collection, title string) (string, error) {
-
-	dataDir, err := storage.DataDir()
+// newFile creates a note from the template of data.Collection, or from
+// the default note when the collection has no template, followed by body
+func newFile(data snippet.Data, body []byte) (string, error) {
+	store, err := storage.Store()
 	if err != nil {
 		return "", err
 	}
-	collectionPath := filepath.Join(dataDir, collection)
 
-	if err := storage.EnsureDir(collectionPath); err != nil {
+	// Only a missing template falls back to the default; a broken one is
+	// reported so it can be fixed
+	content, err := snippet.ReadSnippet(data)
+	if errors.Is(err, fs.ErrNotExist) {
+		content = snippet.Default(data.Title, data.Collection)
+	} else if err != nil {
 		return "", err
 	}
 
-	filename := slug.MdSlugWithTime(title)
-	filePath := filepath.Join(collectionPath, filename)
-
-	f, err := os.OpenFile(
-		filePath,
-		os.O_CREATE|os.O_EXCL|os.O_WRONLY,
-		0644,
-	)
-
-	if err != nil {
-		if os.IsExist(err) {
-			return "", fmt.Errorf("A file with this name exists in collection %s!", collection)
+	if len(body) > 0 {
+		if !strings.HasSuffix(content, "\n") {
+			content += "\n"
 		}
-
-		return "", err
+		content += strings.TrimRight(string(body), "\n") + "\n"
 	}
 
-	defer f.Close()
-
-	content, err := snippet.ReadSnippet(title, collection)
+	file, err := store.Create(data.Collection, slug.MdSlugWithTime(data.Title), []byte(content))
 	if err != nil {
-		content = snippet.Default(title, collection)
-	}
-
-	if _, err := f.WriteString(content); err != nil {
-		return "", fmt.Errorf("Error writing %s to %s", content, filePath)
+		if errors.Is(err, fs.ErrExist) {
+			return "", fmt.Errorf("A file with this name exists in collection %s!", data.Collection)
+		}
+		return "", err
 	}
 
-	return filePath, nil
-}
-
-func listFiles(textGenre string) {
-	fmt.Println("list files of type", textGenre)
+	return file.Path, nil
 }
 
 func listCollections() {
@@ -133,127 +130,299 @@ func listCollections() {
 	}
 }
 
-func deleteFile(searchTerm string, sync *storage.GitSync) {
-	selectedFile, err := ui.RunDeletePicker(searchTerm)
+func deleteFile(searchTerm string, sync storage.Backup) {
+	selectedFiles, err := ui.RunDeletePicker(searchTerm)
 	if err != nil {
 		fmt.Printf("Operação cancelada: %v\n", err)
 		return
 	}
 
-	if selectedFile == nil {
+	if len(selectedFiles) == 0 {
 		fmt.Println("Nenhum arquivo selecionado.")
 		return
 	}
 
-	dataDir, _ := storage.DataDir()
-	confirmed, err := ui.RunConfirmDialog(selectedFile.Path, dataDir)
+	plainDir, _ := storage.PlainDir()
+	paths := make([]string, len(selectedFiles))
+	for i, file := range selectedFiles {
+		paths[i] = file.Path
+	}
+	action, err := ui.RunConfirmDialog(paths, plainDir)
 	if err != nil {
 		fmt.Printf("Erro ao mostrar diálogo de confirmação: %v\n", err)
 		return
 	}
-
-	if !confirmed {
+	if action == ui.ConfirmCancel {
 		fmt.Println("Exclusão cancelada.")
 		return
 	}
 
-	err = os.Remove(selectedFile.Path)
+	store, err := storage.Store()
 	if err != nil {
-		fmt.Printf("Erro ao excluir arquivo: %v\n", err)
+		fmt.Printf("Erro ao abrir as notas: %v\n", err)
+		return
+	}
+	dataDir, _ := storage.DataDir()
+	trash := storage.NewTrash(dataDir)
+	var deleted []string
+	for _, file := range selectedFiles {
+		name := file.Collection + "/" + file.Name
+		if action == ui.ConfirmPermanent {
+			err = store.Delete(file.Collection, file.Name)
+		} else {
+			_, err = trash.Put(file)
+		}
+		if err != nil {
+			fmt.Printf("Erro ao excluir %s: %v\n", name, err)
+			continue
+		}
+		deleted = append(deleted, name)
+	}
+
+	if len(deleted) == 0 {
 		return
 	}
 
-	fmt.Printf("✓ Arquivo excluído com sucesso: %s/%s\n", selectedFile.Collection, selectedFile.Name)
+	if action == ui.ConfirmPermanent {
+		fmt.Printf("✓ %d arquivo(s) excluído(s) permanentemente\n", len(deleted))
+	} else {
+		fmt.Printf("✓ %d arquivo(s) movido(s) para a lixeira\n", len(deleted))
+		fmt.Println("  Restaure com: margi trash restore <nota>")
+	}
 
 	if sync != nil {
-		if err := sync.CommitAndPush("rm: " + selectedFile.Collection + "/" + selectedFile.Name); err != nil {
-			fmt.Printf("Warning: git sync failed: %v\n", err)
+		message := "rm: " + deleted[0]
+		if len(deleted) > 1 {
+			message = fmt.Sprintf("rm: %d notes\n\n%s", len(deleted), strings.Join(deleted, "\n"))
+		}
+		if err := sync.CommitAndPush(message); err != nil {
+			fmt.Printf("Warning: backup failed: %v\n", err)
 		}
 	}
 }
 
-func runNew(editorCmd string, sync *storage.GitSync) {
-	var collectionName, title string
+func runNew(args []string, editorCmd string, sync storage.Backup) {
+	const usage = "margi new [collection] <title> [--set key=value]... [--no-edit] [--stdin]"
+	fs := newFlagSet("new")
+	values := keyValues{}
+	fs.Var(values, "set", "value of a template prompt, as key=value")
+	noEdit := fs.Bool("no-edit", false, "create the note without opening the editor")
+	fromStdin := fs.Bool("stdin", false, "read the body from stdin; --stdin=false never reads it")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		usageError(err, usage)
+		exit(2)
+	}
+
+	// Input becomes the body of the note, so nothing is left to edit. It
+	// is read first, before any picker or form needs the terminal.
+	var body []byte
+	switch {
+	case *fromStdin:
+		body, err = io.ReadAll(os.Stdin)
+	case !flagSet(fs, "stdin"):
+		body, err = pipedInput(pipeWait)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading stdin: %v\n", err)
+		exit(1)
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		*noEdit = true
+	}
 
-	if len(os.Args) == 3 {
-		title = os.Args[2]
+	var collectionName, title string
+	switch len(positional) {
+	case 1:
+		title = positional[0]
 		selectedCollection, err := ui.RunPicker()
 		if err != nil {
 			fmt.Printf("Operação cancelada: %v\n", err)
 			return
 		}
 		collectionName = selectedCollection
-	} else if len(os.Args) >= 4 {
-		collectionName = os.Args[2]
-		title = os.Args[3]
-	} else {
-		fmt.Fprintln(os.Stderr, "Usage: margi new [title] ou margi new [collection] [title]")
-		return
+	case 2:
+		collectionName, title = positional[0], positional[1]
+	default:
+		usageError(fmt.Errorf("expected a title and optionally a collection"), usage)
+		exit(2)
 	}
 
-	filePath, err := newFile(collectionName, title)
+	if err := askPrompts(collectionName, title, values); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating new file: %v\n", err)
+		exit(1)
+	}
+
+	data := snippet.NewData(title, collectionName, time.Now())
+	data.Values = values
+	createNote(data, body, !*noEdit, editorCmd, sync)
+}
+
+// createNote creates a note from data followed by body, opens it in the
+// editor when edit is set and backs it up
+func createNote(data snippet.Data, body []byte, edit bool, editorCmd string, sync storage.Backup) {
+	filePath, err := newFile(data, body)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating new file: %v\n", err)
-		return
+		exit(1)
+	}
+	if edit {
+		openNote(filePath, editorCmd)
+	} else {
+		fmt.Printf("✓ created %s/%s\n", data.Collection, filepath.Base(filePath))
 	}
-	editor.OpenInEditor(filePath, editorCmd)
 	if sync != nil {
-		if err := sync.CommitAndPush("add: " + title); err != nil {
-			fmt.Printf("Warning: git sync failed: %v\n", err)
+		if err := sync.CommitAndPush("add: " + data.Title); err != nil {
+			fmt.Printf("Warning: backup failed: %v\n", err)
+		}
+	}
+}
+
+// askPrompts fills values with the answers to the prompts of the
+// collection's template. Values given with --set must name one of the
+// prompts; they are checked and not asked again. The rest are asked in a
+// form, or take their initial value when stdin is not a terminal.
+func askPrompts(collection, title string, values keyValues) error {
+	prompts, err := snippet.Prompts(collection)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, len(prompts))
+	for i, p := range prompts {
+		names[i] = p.Name
+	}
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		if slices.Contains(names, key) {
+			continue
 		}
+		if len(names) == 0 {
+			return fmt.Errorf("--set %s: the template of %s declares no prompts", key, collection)
+		}
+		return fmt.Errorf("--set %s: the template of %s has no such prompt (valid: %s)", key, collection, strings.Join(names, ", "))
+	}
+
+	var missing []snippet.Prompt
+	for _, p := range prompts {
+		value, ok := values[p.Name]
+		if !ok {
+			missing = append(missing, p)
+			continue
+		}
+		if err := p.Check(value); err != nil {
+			return err
+		}
+	}
+	if len(missing) == 0 || !isTerminal(os.Stdin) {
+		return nil
 	}
+
+	answers, err := ui.RunPromptForm(title, missing)
+	if err != nil {
+		return err
+	}
+	for k, v := range answers {
+		values[k] = v
+	}
+	return nil
 }
 
-func runSync(sync *storage.GitSync) {
+func runSync(args []string, sync storage.Backup) {
 	if sync == nil {
 		fmt.Fprintln(os.Stderr, "no backup configured")
 		return
 	}
+	gitSync, _ := sync.(*storage.GitSync)
+	if len(args) > 0 {
+		if gitSync == nil {
+			fmt.Fprintf(os.Stderr, "margi sync %s needs git backup\n", args[0])
+			exit(1)
+		}
+		switch args[0] {
+		case "status":
+			syncStatus(gitSync)
+		case "--retry", "-retry":
+			retryPush(gitSync)
+		default:
+			usageError(fmt.Errorf("unknown sync command: %s", args[0]), "margi sync [status|--retry]")
+			exit(2)
+		}
+		return
+	}
 	if err := sync.Synchronize(); err != nil {
 		fmt.Fprintf(os.Stderr, "sync error: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 	if err := sync.CommitAndPush("sync"); err != nil {
 		fmt.Fprintf(os.Stderr, "sync error: %v\n", err)
-		os.Exit(1)
+		exit(1)
+	}
+	if gitSync != nil && gitSync.Pending() > 0 {
+		retryPush(gitSync)
 	}
 }
 
 func main() {
+	defer runCleanups()
+	cleanUpOnSignal()
+
 	cfg, err := config.Load()
 	if err != nil {
 		cfg = config.Default()
 		config.Save(cfg)
 	}
 
+	args, vaultName, err := extractVaultFlag(os.Args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(2)
+	}
+	os.Args = args
+
+	vault, err := useVault(cfg, vaultName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+
+	if err := useEncryption(vault); err != nil {
+		fmt.Fprintf(os.Stderr, "Error unlocking vault: %v\n", err)
+		exit(1)
+	}
+
 	editorCmd := editor.ResolveEditor(cfg.Editor)
 
-	sync, err := storage.NewGitSync(&cfg.Backup)
+	sync, err := storage.NewBackup(&vault.Backup)
 	if err != nil {
-		fmt.Printf("Warning: could not initialize git sync: %v\n", err)
+		fmt.Printf("Warning: could not initialize backup: %v\n", err)
 	}
 	if sync != nil {
 		if err := sync.Synchronize(); err != nil {
-			fmt.Printf("Warning: git sync failed: %v\n", err)
+			fmt.Printf("Warning: backup failed: %v\n", err)
 		}
 	}
+	// History, conflicts and the push queue only exist with git
+	gitSync, _ := sync.(*storage.GitSync)
+	if gitSync != nil {
+		ui.UsePendingCounter(gitSync)
+	}
 
 	if len(os.Args) < 2 {
 		selected, err := ui.RunBrowsePicker()
 		if err != nil || selected == nil {
 			return
 		}
-		editor.OpenInEditor(selected.Path, editorCmd)
+		openNote(selected.Path, editorCmd)
 		if sync != nil {
 			if err := sync.CommitAndPush("edit: " + selected.Collection + "/" + selected.Name); err != nil {
-				fmt.Printf("Warning: git sync failed: %v\n", err)
+				fmt.Printf("Warning: backup failed: %v\n", err)
 			}
 		}
 		return
 	}
 
 	cmds := map[string]func(){
-		"new": func() { runNew(editorCmd, sync) },
+		"new": func() { runNew(os.Args[2:], editorCmd, sync) },
 		"edit": func() {
 			if len(os.Args) < 3 {
 				fmt.Fprintln(os.Stderr, "Usage: margi edit [search_term]")
@@ -268,21 +437,38 @@ func main() {
 			}
 			deleteFile(searchTerm, sync)
 		},
-		"list": func() {
-			if len(os.Args) < 3 {
-				fmt.Fprintln(os.Stderr, "Usage: margi list [collection]")
-				return
-			}
-			listFiles(os.Args[2])
-		},
+		"list":        func() { listFiles(os.Args[2:]) },
+		"search":      func() { runSearch(os.Args[2:]) },
+		"reindex":     runReindex,
+		"tags":        func() { listTags(os.Args[2:]) },
+		"backlinks":   func() { runBacklinks(os.Args[2:]) },
+		"links":       func() { runLinks(os.Args[2:]) },
+		"mv":          func() { runMove(os.Args[2:], sync) },
+		"rename":      func() { runRename(os.Args[2:], sync) },
+		"trash":       func() { runTrash(os.Args[2:], sync) },
 		"collections": listCollections,
-		"sync":        func() { runSync(sync) },
+		"vaults":      func() { listVaults(cfg, vaultName) },
+		"sync":        func() { runSync(os.Args[2:], sync) },
+		"log":         func() { runLog(os.Args[2:], gitSync) },
+		"history":     func() { runHistory(os.Args[2:], editorCmd, gitSync) },
+		"resolve":     func() { runResolve(os.Args[2:], editorCmd, gitSync) },
+		"template":    func() { runTemplate(os.Args[2:], editorCmd) },
+		"append":      func() { runAppend(os.Args[2:], sync) },
+		"today": func() {
+			runPeriodic("today", period.Daily, vault.Periodic.Daily, os.Args[2:], editorCmd, sync)
+		},
+		"week": func() {
+			runPeriodic("week", period.Weekly, vault.Periodic.Weekly, os.Args[2:], editorCmd, sync)
+		},
+		"month": func() {
+			runPeriodic("month", period.Monthly, vault.Periodic.Monthly, os.Args[2:], editorCmd, sync)
+		},
 	}
 
 	fn, ok := cmds[os.Args[1]]
 	if !ok {
 		fmt.Fprintf(os.Stderr, "Unknown action: %s\n", os.Args[1])
-		os.Exit(1)
+		exit(1)
 	}
 	fn()
 }
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gcaixeta/marginalia/internal/collection"
+	"github.com/gcaixeta/marginalia/internal/links"
+	"github.com/gcaixeta/marginalia/internal/note"
+	"github.com/gcaixeta/marginalia/internal/slug"
+	"github.com/gcaixeta/marginalia/internal/storage"
+)
+
+const (
+	mvUsage     = "margi mv <note> <collection>"
+	renameUsage = `margi rename <note> "new title"`
+)
+
+func runMove(args []string, sync storage.Backup) {
+	if len(args) != 2 {
+		usageError(fmt.Errorf("expected a note and a collection"), mvUsage)
+		exit(2)
+	}
+
+	file, err := resolveNote(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(1)
+	}
+
+	collectionName := args[1]
+	if !collection.CollectionExists(collectionName) {
+		collectionName = slug.MakeSlug(collectionName)
+		if collectionName == "" {
+			fmt.Fprintf(os.Stderr, "Invalid collection name: %s\n", args[1])
+			exit(1)
+		}
+		if err := collection.CreateCollection(collectionName); err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating collection: %v\n", err)
+			exit(1)
+		}
+	}
+	if collectionName == file.Collection {
+		fmt.Printf("%s is already in %s\n", file.Name, collectionName)
+		return
+	}
+
+	if err := relocateNote(*file, collectionName, file.Name, "", sync); err != nil {
+		fmt.Fprintf(os.Stderr, "Error moving note: %v\n", err)
+		exit(1)
+	}
+}
+
+func runRename(args []string, sync storage.Backup) {
+	if len(args) != 2 {
+		usageError(fmt.Errorf("expected a note and a title"), renameUsage)
+		exit(2)
+	}
+
+	file, err := resolveNote(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(1)
+	}
+
+	title := args[1]
+	if slug.MakeSlug(title) == "" {
+		fmt.Fprintf(os.Stderr, "Invalid title: %q\n", title)
+		exit(1)
+	}
+
+	if err := relocateNote(*file, file.Collection, slug.Retitle(file.Name, title), title, sync); err != nil {
+		fmt.Fprintf(os.Stderr, "Error renaming note: %v\n", err)
+		exit(1)
+	}
+}
+
+// relocateNote moves file to collectionName/name, sets its title when title
+// is not empty, rewrites the links other notes have to it and commits the
+// result. The move goes through git when sync is configured.
+func relocateNote(file storage.FileItem, collectionName, name, title string, sync storage.Backup) error {
+	store, err := storage.Store()
+	if err != nil {
+		return err
+	}
+
+	moving := collectionName != file.Collection || name != file.Name
+	if moving {
+		if _, err := store.Read(collectionName, name); err == nil {
+			return fmt.Errorf("a file named %s already exists in collection %s", name, collectionName)
+		}
+	}
+
+	// Find the notes linking to file before anything moves.
+	files, err := store.List()
+	if err != nil {
+		return err
+	}
+	resolver := links.NewResolver(files)
+	refs, err := links.Scan(store, files)
+	if err != nil {
+		return err
+	}
+	sources := map[string]storage.FileItem{}
+	for _, ref := range refs {
+		if resolved, ok := resolver.Resolve(ref.Link, ref.Source); ok && resolved.Path == file.Path {
+			sources[ref.Source.Path] = ref.Source
+		}
+	}
+
+	if moving {
+		if sync != nil && storage.Encrypted() == nil {
+			// A backup implies the local store, so notes have real paths.
+			var dataDir string
+			if dataDir, err = storage.DataDir(); err == nil {
+				err = sync.Move(file.Path, filepath.Join(dataDir, collectionName, name))
+			}
+		} else {
+			_, err = store.Move(file.Collection, file.Name, collectionName, name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := updateMovedNote(store, collectionName, name, file.Collection, title); err != nil {
+		return err
+	}
+
+	moved := storage.FileItem{Collection: collectionName, Name: name}
+	rewritten, rewrittenNotes := 0, 0
+	for _, source := range sources {
+		at := source
+		if source.Path == file.Path {
+			at = moved // The note links to itself
+		}
+		sourceCollection, sourceName := at.Collection, at.Name
+
+		content, err := store.Read(sourceCollection, sourceName)
+		if err != nil {
+			return err
+		}
+		updated, n := links.Rewrite(content, func(l links.Link) string {
+			if resolved, ok := resolver.Resolve(l, source); !ok || resolved.Path != file.Path {
+				return ""
+			}
+			return resolver.Retarget(l, at, file, moved)
+		})
+		if n == 0 {
+			continue
+		}
+		if err := store.Write(sourceCollection, sourceName, updated); err != nil {
+			return err
+		}
+		rewritten += n
+		rewrittenNotes++
+	}
+
+	from := file.Collection + "/" + file.Name
+	to := collectionName + "/" + name
+	fmt.Printf("✓ %s → %s\n", from, to)
+	if rewritten > 0 {
+		fmt.Printf("  updated %d link(s) in %d note(s)\n", rewritten, rewrittenNotes)
+	}
+
+	if sync != nil {
+		action := "mv"
+		if title != "" {
+			action = "rename"
+		}
+		if err := sync.CommitAndPush(action + ": " + from + " -> " + to); err != nil {
+			fmt.Printf("Warning: backup failed: %v\n", err)
+		}
+	}
+
+	return nil
+}
+
+// updateMovedNote sets the title of the moved note and, if it records its
+// collection in front matter, the new collection.
+func updateMovedNote(store storage.NoteStore, collectionName, name, oldCollection, title string) error {
+	content, err := store.Read(collectionName, name)
+	if err != nil {
+		return err
+	}
+
+	updated := content
+	if title != "" {
+		updated = note.SetTitle(updated, title)
+	}
+	if collectionName != oldCollection {
+		if meta, _, err := note.Parse(updated); err == nil {
+			if _, ok := meta.Fields["collection"]; ok {
+				updated, _ = note.SetField(updated, "collection", collectionName)
+			}
+		}
+	}
+
+	if bytes.Equal(updated, content) {
+		return nil
+	}
+	return store.Write(collectionName, name, updated)
+}
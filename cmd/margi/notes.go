@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gcaixeta/marginalia/internal/links"
+	"github.com/gcaixeta/marginalia/internal/storage"
+)
+
+// resolveNote finds the note a command argument refers to. term can be
+// anything a [[link]] accepts ("slug", "collection/slug", a file name) or
+// part of a file name. When several notes match, the user picks one.
+func resolveNote(term string) (*storage.FileItem, error) {
+	files, err := storage.ListAllFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	matches := matchNotes(files, term)
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no notes found matching: %s", term)
+	case 1:
+		return &matches[0], nil
+	default:
+		return chooseNote(matches)
+	}
+}
+
+// matchNotes returns the notes term refers to, preferring the notes a link
+// to term could point to over substring matches on the file name. Notes of
+// several collections sharing a slug are all returned, for the user to pick.
+func matchNotes(files []storage.FileItem, term string) []storage.FileItem {
+	l := links.Parse([]byte("[[" + term + "]]"))
+	if len(l) == 1 {
+		if matches := links.NewResolver(files).Matches(l[0]); len(matches) > 0 {
+			return matches
+		}
+	}
+
+	collectionName, name := "", term
+	if i := strings.LastIndex(term, "/"); i >= 0 {
+		collectionName, name = term[:i], term[i+1:]
+	}
+	name = strings.ToLower(name)
+
+	matches := []storage.FileItem{}
+	for _, file := range storage.FilterByCollection(files, collectionName) {
+		if strings.Contains(strings.ToLower(file.Name), name) {
+			matches = append(matches, file)
+		}
+	}
+	return matches
+}
+
+// listNotes returns the note store along with every note in it, for
+// commands that go on to read the notes
+func listNotes() (storage.NoteStore, []storage.FileItem, error) {
+	store, err := storage.Store()
+	if err != nil {
+		return nil, nil, err
+	}
+	files, err := store.List()
+	return store, files, err
+}
+
+// chooseNote asks the user to pick one of several notes
+func chooseNote(files []storage.FileItem) (*storage.FileItem, error) {
+	fmt.Println("Multiple files found. Please choose one:")
+	for i, file := range files {
+		fmt.Printf("[%d] %s/%s\n", i+1, file.Collection, file.Name)
+	}
+
+	var choice int
+	fmt.Print("Enter the number of the file: ")
+	_, err := fmt.Scanf("%d", &choice)
+	if err != nil || choice < 1 || choice > len(files) {
+		return nil, fmt.Errorf("invalid selection")
+	}
+
+	return &files[choice-1], nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gcaixeta/marginalia/internal/config"
+	"github.com/gcaixeta/marginalia/internal/period"
+	"github.com/gcaixeta/marginalia/internal/snippet"
+	"github.com/gcaixeta/marginalia/internal/storage"
+)
+
+// defaultPeriodicCollection holds the periodic notes when the config
+// doesn't name a collection
+const defaultPeriodicCollection = "journal"
+
+// runPeriodic opens the note of the current period of kind, or of the
+// previous or next one, creating it from the collection's template when
+// it doesn't exist yet
+func runPeriodic(name string, kind period.Kind, cfg config.PeriodConfig, args []string, editorCmd string, sync storage.Backup) {
+	usage := fmt.Sprintf("margi %s [--prev | --next]", name)
+	fs := newFlagSet(name)
+	prev := fs.Bool("prev", false, "open the note of the previous period")
+	next := fs.Bool("next", false, "open the note of the next period")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		usageError(err, usage)
+		exit(2)
+	}
+	if len(positional) > 0 {
+		usageError(fmt.Errorf("unexpected argument: %s", positional[0]), usage)
+		exit(2)
+	}
+	if *prev && *next {
+		usageError(fmt.Errorf("--prev and --next can't be used together"), usage)
+		exit(2)
+	}
+
+	start := kind.Start(time.Now())
+	switch {
+	case *prev:
+		start = kind.Shift(start, -1)
+	case *next:
+		start = kind.Shift(start, 1)
+	}
+
+	collectionName := cfg.Collection
+	if collectionName == "" {
+		collectionName = defaultPeriodicCollection
+	}
+	title := kind.Title(start, cfg.Format)
+
+	existing, err := period.Find(collectionName, title)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error searching for %s: %v\n", title, err)
+		exit(1)
+	}
+	if existing != nil {
+		openNote(existing.Path, editorCmd)
+		if sync != nil {
+			if err := sync.CommitAndPush("edit: " + title); err != nil {
+				fmt.Printf("Warning: backup failed: %v\n", err)
+			}
+		}
+		return
+	}
+
+	values := keyValues{}
+	if err := askPrompts(collectionName, title, values); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating new file: %v\n", err)
+		exit(1)
+	}
+	data := snippet.NewData(title, collectionName, time.Now())
+	data.Values = values
+	data.Period = start
+	createNote(data, nil, true, editorCmd, sync)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gcaixeta/marginalia/internal/editor"
+	"github.com/gcaixeta/marginalia/internal/storage"
+	"github.com/gcaixeta/marginalia/internal/ui"
+)
+
+const resolveUsage = "margi resolve [note] [--ours|--theirs|--both|--edit]"
+
+// runResolve settles the notes left conflicted by a pull, either all at
+// once with a flag or one by one in the conflict picker, and commits the
+// merge once none are left.
+func runResolve(args []string, editorCmd string, sync *storage.GitSync) {
+	fs := newFlagSet("resolve")
+	ours := fs.Bool("ours", false, "keep the local version")
+	theirs := fs.Bool("theirs", false, "keep the remote version")
+	both := fs.Bool("both", false, "keep both versions as separate notes")
+	edit := fs.Bool("edit", false, "merge by hand in the editor")
+	positional, err := parseFlags(fs, args)
+	if err == nil && len(positional) > 1 {
+		err = fmt.Errorf("too many arguments")
+	}
+	choice := ui.ConflictQuit
+	flags := 0
+	for _, f := range []struct {
+		set    bool
+		choice ui.ConflictChoice
+	}{{*ours, ui.ConflictOurs}, {*theirs, ui.ConflictTheirs}, {*both, ui.ConflictBoth}, {*edit, ui.ConflictEdit}} {
+		if f.set {
+			choice = f.choice
+			flags++
+		}
+	}
+	if err == nil && flags > 1 {
+		err = fmt.Errorf("--ours, --theirs, --both and --edit are exclusive")
+	}
+	if err != nil {
+		usageError(err, resolveUsage)
+		exit(2)
+	}
+
+	if sync == nil {
+		fmt.Fprintln(os.Stderr, "git backup not configured")
+		return
+	}
+	sync.Wait()
+
+	conflicts, err := sync.Conflicts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing conflicts: %v\n", err)
+		exit(1)
+	}
+	if len(positional) == 1 {
+		conflicts = matchConflicts(conflicts, positional[0])
+		if len(conflicts) == 0 {
+			fmt.Fprintf(os.Stderr, "No conflicted note matches %q\n", positional[0])
+			exit(1)
+		}
+	}
+	if len(conflicts) == 0 {
+		fmt.Println("No conflicts.")
+		if sync.Merging() {
+			commitMerge(sync)
+		}
+		return
+	}
+
+	if choice != ui.ConflictQuit {
+		for _, c := range conflicts {
+			if err := resolveConflict(c, choice, editorCmd, sync); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", c.Rel(), err)
+			}
+		}
+	} else {
+		for len(conflicts) > 0 {
+			c, choice, err := ui.RunConflictPicker(conflicts)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				exit(1)
+			}
+			if choice == ui.ConflictQuit {
+				break
+			}
+			if err := resolveConflict(c, choice, editorCmd, sync); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", c.Rel(), err)
+			}
+
+			if conflicts, err = sync.Conflicts(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error listing conflicts: %v\n", err)
+				exit(1)
+			}
+			if len(positional) == 1 {
+				conflicts = matchConflicts(conflicts, positional[0])
+			}
+		}
+	}
+
+	remaining, err := sync.Conflicts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing conflicts: %v\n", err)
+		exit(1)
+	}
+	if len(remaining) > 0 {
+		fmt.Printf("%d note(s) still conflicted; run margi resolve again when ready\n", len(remaining))
+		return
+	}
+	commitMerge(sync)
+}
+
+func resolveConflict(c storage.Conflict, choice ui.ConflictChoice, editorCmd string, sync *storage.GitSync) error {
+	var how storage.Resolution
+	switch choice {
+	case ui.ConflictOurs:
+		how = storage.ResolveOurs
+	case ui.ConflictTheirs:
+		how = storage.ResolveTheirs
+	case ui.ConflictBoth:
+		how = storage.ResolveBoth
+	case ui.ConflictEdit:
+		err := sync.MergeByHand(c, func(path string) error {
+			return editor.Run(path, editorCmd)
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✓ %s merged\n", c.Rel())
+		return nil
+	default:
+		return nil
+	}
+
+	written, err := sync.Resolve(c, how)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✓ %s resolved\n", c.Rel())
+	if how == storage.ResolveBoth && len(written) > 1 {
+		fmt.Printf("  remote version kept as %s\n", written[1])
+	}
+	return nil
+}
+
+// commitMerge commits and pushes a merge whose conflicts are all resolved
+func commitMerge(sync *storage.GitSync) {
+	if err := sync.CommitAndPush("merge: resolve conflicts"); err != nil {
+		fmt.Fprintf(os.Stderr, "sync error: %v\n", err)
+		exit(1)
+	}
+}
+
+// matchConflicts keeps the conflicts whose "collection/name" contains term
+func matchConflicts(conflicts []storage.Conflict, term string) []storage.Conflict {
+	term = strings.ToLower(term)
+	var matched []storage.Conflict
+	for _, c := range conflicts {
+		if strings.Contains(strings.ToLower(c.Rel()), term) {
+			matched = append(matched, c)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gcaixeta/marginalia/internal/note"
+	"github.com/gcaixeta/marginalia/internal/search"
+	"github.com/gcaixeta/marginalia/internal/storage"
+)
+
+const searchUsage = "margi search <query> [--collection name] [--tag tag] [--context N] [--limit N]"
+
+func runSearch(args []string) {
+	fs := newFlagSet("search")
+	collectionName := fs.String("collection", "", "only search notes in this collection")
+	context := fs.Int("context", 1, "lines of context around each match")
+	limit := fs.Int("limit", 20, "maximum number of notes to show (0 for all)")
+	var tags stringList
+	fs.Var(&tags, "tag", "only search notes with this tag (repeatable)")
+
+	positional, err := parseFlags(fs, args)
+	if err == nil && *context < 0 {
+		err = fmt.Errorf("--context must not be negative")
+	}
+	if err != nil {
+		usageError(err, searchUsage)
+		exit(2)
+	}
+	query := strings.Join(positional, " ")
+	if strings.TrimSpace(query) == "" && len(tags) == 0 {
+		usageError(fmt.Errorf("missing query"), searchUsage)
+		exit(2)
+	}
+
+	store, allFiles, err := listNotes()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing files: %v\n", err)
+		exit(1)
+	}
+	files := storage.FilterByCollection(allFiles, *collectionName)
+	opts := search.Options{Context: *context, Limit: *limit}
+
+	ix, err := openIndex(store, allFiles)
+	if err != nil && len(tags) > 0 {
+		fmt.Fprintf(os.Stderr, "Error opening search index: %v\n", err)
+		exit(1)
+	}
+
+	var results []search.Result
+	switch {
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Warning: search index unavailable, scanning all notes: %v\n", err)
+		results, err = search.Search(store, files, query, opts)
+	case strings.TrimSpace(query) == "":
+		// Tag-only search: list every tagged note, most recent first
+		results = taggedResults(ix.FilterTags(files, tags), opts.Limit)
+	default:
+		results, err = ix.Search(store, ix.FilterTags(files, tags), query, opts)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error searching notes: %v\n", err)
+		exit(1)
+	}
+
+	if len(results) == 0 {
+		fmt.Printf("No notes found matching: %s\n", strings.TrimSpace(query+" "+tagQuery(tags)))
+		return
+	}
+
+	for i, r := range results {
+		if i > 0 {
+			fmt.Println()
+		}
+		printSearchResult(r)
+	}
+}
+
+// taggedResults wraps files in results without matches, newest first
+func taggedResults(files []storage.FileItem, limit int) []search.Result {
+	storage.SortFiles(files, "mtime", true)
+	if limit > 0 && len(files) > limit {
+		files = files[:limit]
+	}
+
+	results := make([]search.Result, 0, len(files))
+	for _, file := range files {
+		results = append(results, search.Result{
+			File:  file,
+			Title: strings.TrimSuffix(file.Name, filepath.Ext(file.Name)),
+		})
+	}
+	return results
+}
+
+func tagQuery(tags []string) string {
+	prefixed := make([]string, len(tags))
+	for i, tag := range tags {
+		prefixed[i] = "#" + note.NormalizeTag(tag)
+	}
+	return strings.Join(prefixed, " ")
+}
+
+// printSearchResult prints a result grep-style: matching lines are marked
+// with ":" and context lines with "-". Overlapping context is printed once.
+func printSearchResult(r search.Result) {
+	if len(r.Matches) == 0 {
+		fmt.Printf("%s/%s — %s\n", r.File.Collection, r.File.Name, r.Title)
+		return
+	}
+	fmt.Printf("%s/%s — %s (score %d)\n", r.File.Collection, r.File.Name, r.Title, r.Score)
+
+	lines := map[int]string{}
+	matched := map[int]bool{}
+	for _, m := range r.Matches {
+		first := m.Line - len(m.Before)
+		for i, line := range m.Before {
+			lines[first+i] = line
+		}
+		lines[m.Line] = m.Text
+		matched[m.Line] = true
+		for i, line := range m.After {
+			lines[m.Line+1+i] = line
+		}
+	}
+
+	numbers := make([]int, 0, len(lines))
+	for n := range lines {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+
+	for i, n := range numbers {
+		if i > 0 && n > numbers[i-1]+1 {
+			fmt.Println("    --")
+		}
+		sep := "-"
+		if matched[n] {
+			sep = ":"
+		}
+		fmt.Printf("%6d%s %s\n", n, sep, lines[n])
+	}
+}
+
+// openIndex loads the search index and brings it up to date with files.
+// files must be every note in the data dir, or the index drops the rest.
+func openIndex(store storage.NoteStore, files []storage.FileItem) (*search.Index, error) {
+	dataDir, err := storage.PlainDir()
+	if err != nil {
+		return nil, err
+	}
+	return search.Refresh(dataDir, store, files)
+}
+
+func runReindex() {
+	dataDir, err := storage.PlainDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving data dir: %v\n", err)
+		exit(1)
+	}
+
+	store, files, err := listNotes()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing files: %v\n", err)
+		exit(1)
+	}
+
+	ix, err := search.OpenIndex(dataDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening index: %v\n", err)
+		exit(1)
+	}
+	if _, err := ix.Rebuild(store, files); err != nil {
+		fmt.Fprintf(os.Stderr, "Error rebuilding index: %v\n", err)
+		exit(1)
+	}
+	if err := ix.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving index: %v\n", err)
+		exit(1)
+	}
+
+	fmt.Printf("✓ Indexed %d note(s)\n", ix.Len())
+}
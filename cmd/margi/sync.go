@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gcaixeta/marginalia/internal/storage"
+)
+
+// syncStatus prints the state of the backup repository
+func syncStatus(sync *storage.GitSync) {
+	status, err := sync.Status()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading sync status: %v\n", err)
+		exit(1)
+	}
+
+	fmt.Printf("Remote:     %s/%s (%s)\n", status.Remote, status.Branch, status.Repo)
+
+	switch {
+	case !status.HasUpstream:
+		fmt.Printf("Ahead:      %d commit(s), remote branch not fetched yet\n", status.Ahead)
+	case status.Ahead == 0 && status.Behind == 0:
+		fmt.Println("Branch:     up to date")
+	default:
+		fmt.Printf("Ahead:      %d commit(s) not pushed\n", status.Ahead)
+		fmt.Printf("Behind:     %d commit(s) not pulled\n", status.Behind)
+	}
+
+	fmt.Printf("Last pull:  %s\n", formatSyncTime(status.LastPull))
+	if status.LastPullError != "" {
+		fmt.Printf("            last attempt failed: %s\n", status.LastPullError)
+	}
+	fmt.Printf("Last push:  %s\n", formatSyncTime(status.LastPush))
+	if status.LastPushError != "" {
+		fmt.Printf("            last attempt failed: %s\n", status.LastPushError)
+	}
+	if pending := sync.Pending(); pending > 0 {
+		fmt.Printf("Pending:    %d commit(s) from failed pushes, next retry %s\n",
+			pending, formatRetryTime(status.NextRetry.Local(), time.Now()))
+		fmt.Println("            push now with: margi sync --retry")
+	}
+
+	if len(status.Conflicts) > 0 {
+		fmt.Printf("\nConflicts (%d), run margi resolve:\n", len(status.Conflicts))
+		for _, c := range status.Conflicts {
+			fmt.Printf("  %s\n", c.Rel())
+		}
+	} else if status.Merging {
+		fmt.Println("\nMerge resolved but not committed yet, run margi sync")
+	}
+
+	if len(status.Uncommitted) > 0 {
+		fmt.Printf("\nUncommitted (%d):\n", len(status.Uncommitted))
+		for _, entry := range status.Uncommitted {
+			fmt.Printf("  %s\n", entry)
+		}
+	}
+}
+
+// formatSyncTime renders a timestamp with how long ago it was
+func formatSyncTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return fmt.Sprintf("%s (%s ago)", t.Format("2006-01-02 15:04"), formatAge(time.Since(t)))
+}
+
+// formatRetryTime renders when a retry runs: the time alone when it is
+// today, with the date otherwise, as a long backoff can push it days out
+func formatRetryTime(t, now time.Time) string {
+	y, m, d := t.Date()
+	if ny, nm, nd := now.Date(); y == ny && m == nm && d == nd {
+		return t.Format("15:04")
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+// formatAge renders a duration the way people say it: "5m", "3h", "2d"
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
+
+// retryPush flushes the commits left behind by failed pushes
+func retryPush(sync *storage.GitSync) {
+	pushed, err := sync.RetryPush()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sync error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "%d commit(s) still not pushed\n", sync.Pending())
+		exit(1)
+	}
+	if pushed > 0 {
+		fmt.Printf("↑ pushed %d pending commit(s)\n", pushed)
+	} else {
+		fmt.Println("Nothing to push.")
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/gcaixeta/marginalia/internal/storage"
+)
+
+const tagsUsage = "margi tags [collection] [--sort count|name]"
+
+func listTags(args []string) {
+	fs := newFlagSet("tags")
+	sortBy := fs.String("sort", "count", "sort by count or name")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		usageError(err, tagsUsage)
+		exit(2)
+	}
+	if len(positional) > 1 {
+		usageError(fmt.Errorf("too many arguments"), tagsUsage)
+		exit(2)
+	}
+	if *sortBy != "count" && *sortBy != "name" {
+		usageError(fmt.Errorf("unknown sort key %q", *sortBy), tagsUsage)
+		exit(2)
+	}
+
+	store, allFiles, err := listNotes()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing files: %v\n", err)
+		exit(1)
+	}
+
+	ix, err := openIndex(store, allFiles)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening search index: %v\n", err)
+		exit(1)
+	}
+
+	counts := map[string]int{}
+	if len(positional) == 1 {
+		for _, file := range storage.FilterByCollection(allFiles, positional[0]) {
+			for _, tag := range ix.Tags(file) {
+				counts[tag]++
+			}
+		}
+	} else {
+		counts = ix.TagCounts()
+	}
+
+	if len(counts) == 0 {
+		fmt.Println("No tags found.")
+		return
+	}
+
+	tags := make([]string, 0, len(counts))
+	for tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if *sortBy == "count" && counts[tags[i]] != counts[tags[j]] {
+			return counts[tags[i]] > counts[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, tag := range tags {
+		fmt.Fprintf(w, "#%s\t%d\n", tag, counts[tag])
+	}
+	w.Flush()
+}
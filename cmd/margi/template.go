@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gcaixeta/marginalia/internal/collection"
+	"github.com/gcaixeta/marginalia/internal/snippet"
+)
+
+const templateUsage = "margi template list | margi template edit <collection> | margi template new <collection> | margi template show <collection>"
+
+func runTemplate(args []string, editorCmd string) {
+	if len(args) == 0 {
+		usageError(fmt.Errorf("missing template action"), templateUsage)
+		exit(2)
+	}
+	if args[0] == "list" {
+		listTemplates()
+		return
+	}
+	if len(args) != 2 {
+		usageError(fmt.Errorf("expected one collection"), templateUsage)
+		exit(2)
+	}
+
+	switch args[0] {
+	case "edit":
+		editTemplate(args[1], editorCmd)
+	case "new":
+		newTemplate(args[1], editorCmd)
+	case "show":
+		showTemplate(args[1])
+	default:
+		usageError(fmt.Errorf("unknown template action: %s", args[0]), templateUsage)
+		exit(2)
+	}
+}
+
+// listTemplates lists every collection along with its template, including
+// templates of collections that have no notes yet
+func listTemplates() {
+	templates, err := snippet.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing templates: %v\n", err)
+		exit(1)
+	}
+	collections, err := collection.ListCollections()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing collections: %v\n", err)
+		exit(1)
+	}
+
+	names := slices.Clone(templates)
+	for _, c := range collections {
+		names = append(names, c.Name)
+	}
+	slices.Sort(names)
+	names = slices.Compact(names)
+
+	templatesDir, _ := snippet.TemplatesDir()
+	fmt.Printf("Templates in %s\n\n", templatesDir)
+	if len(names) == 0 {
+		fmt.Println("No collections or templates yet.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COLLECTION\tTEMPLATE")
+	for _, name := range names {
+		tmpl := "(default)"
+		if slices.Contains(templates, name) {
+			tmpl = name + ".md"
+		}
+		fmt.Fprintf(w, "%s\t%s\n", name, tmpl)
+	}
+	w.Flush()
+
+	if partials, err := snippet.Partials(); err == nil && len(partials) > 0 {
+		fmt.Printf("\nPartials: %s\n", strings.Join(partials, ", "))
+	}
+}
+
+// editTemplate opens the template of name in the editor and checks it
+// still renders once the editor exits
+func editTemplate(name, editorCmd string) {
+	path, err := snippet.Path(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	if _, err := os.Stat(path); err != nil {
+		fmt.Fprintf(os.Stderr, "No template for %s; create one with: margi template new %s\n", name, name)
+		exit(1)
+	}
+	runEditor(path, editorCmd)
+	checkTemplate(name)
+}
+
+// newTemplate scaffolds a template for name from the default note and
+// opens it in the editor
+func newTemplate(name, editorCmd string) {
+	path, err := snippet.Create(name)
+	if errors.Is(err, fs.ErrExist) {
+		fmt.Fprintf(os.Stderr, "%s already has a template; edit it with: margi template edit %s\n", name, name)
+		exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating template: %v\n", err)
+		exit(1)
+	}
+	fmt.Printf("✓ created %s\n", path)
+	runEditor(path, editorCmd)
+	checkTemplate(name)
+}
+
+// showTemplate prints the note the template of name would create
+func showTemplate(name string) {
+	content, err := snippet.Preview(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		fmt.Printf("# %s has no template; new notes look like this:\n\n", name)
+		content, err = snippet.Default(snippet.SampleTitle, name), nil
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	fmt.Print(content)
+}
+
+// checkTemplate reports a template that no longer renders
+func checkTemplate(name string) {
+	if _, err := snippet.Preview(name); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		exit(1)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gcaixeta/marginalia/internal/storage"
+)
+
+const trashUsage = "margi trash list | margi trash restore <note> | margi trash empty [--older-than 30d]"
+
+func runTrash(args []string, sync storage.Backup) {
+	if len(args) == 0 {
+		usageError(fmt.Errorf("missing trash action"), trashUsage)
+		exit(2)
+	}
+
+	trash, err := storage.OpenTrash()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening trash: %v\n", err)
+		exit(1)
+	}
+
+	switch args[0] {
+	case "list":
+		listTrash(trash)
+	case "restore":
+		if len(args) != 2 {
+			usageError(fmt.Errorf("expected one note to restore"), trashUsage)
+			exit(2)
+		}
+		restoreTrash(trash, args[1], sync)
+	case "empty":
+		emptyTrash(trash, args[1:], sync)
+	default:
+		usageError(fmt.Errorf("unknown trash action: %s", args[0]), trashUsage)
+		exit(2)
+	}
+}
+
+func listTrash(trash *storage.Trash) {
+	items, err := trash.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing trash: %v\n", err)
+		exit(1)
+	}
+
+	if len(items) == 0 {
+		fmt.Println("Trash is empty.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COLLECTION\tNAME\tDELETED\tSIZE")
+	for _, item := range items {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			item.Collection,
+			item.Name,
+			item.DeletedAt.Format("2006-01-02 15:04"),
+			formatSize(item.Size),
+		)
+	}
+	w.Flush()
+}
+
+func restoreTrash(trash *storage.Trash, term string, sync storage.Backup) {
+	items, err := trash.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing trash: %v\n", err)
+		exit(1)
+	}
+
+	matches := matchTrash(items, term)
+	var item storage.TrashItem
+	switch len(matches) {
+	case 0:
+		fmt.Fprintf(os.Stderr, "No trashed notes found matching: %s\n", term)
+		exit(1)
+	case 1:
+		item = matches[0]
+	default:
+		fmt.Println("Multiple trashed notes found. Please choose one:")
+		for i, m := range matches {
+			fmt.Printf("[%d] %s/%s (deleted %s)\n", i+1, m.Collection, m.Name, m.DeletedAt.Format("2006-01-02 15:04"))
+		}
+		var choice int
+		fmt.Print("Enter the number of the note to restore: ")
+		if _, err := fmt.Scanf("%d", &choice); err != nil || choice < 1 || choice > len(matches) {
+			fmt.Println("Invalid selection.")
+			return
+		}
+		item = matches[choice-1]
+	}
+
+	if _, err := trash.Restore(item); err != nil {
+		fmt.Fprintf(os.Stderr, "Error restoring note: %v\n", err)
+		exit(1)
+	}
+	fmt.Printf("✓ Restored %s/%s\n", item.Collection, item.Name)
+
+	if sync != nil {
+		if err := sync.CommitAndPush("restore: " + item.Collection + "/" + item.Name); err != nil {
+			fmt.Printf("Warning: backup failed: %v\n", err)
+		}
+	}
+}
+
+// matchTrash returns the trashed notes whose "collection/name" contains term
+func matchTrash(items []storage.TrashItem, term string) []storage.TrashItem {
+	term = strings.ToLower(term)
+	matches := []storage.TrashItem{}
+	for _, item := range items {
+		if strings.Contains(strings.ToLower(item.Collection+"/"+item.Name), term) {
+			matches = append(matches, item)
+		}
+	}
+	return matches
+}
+
+func emptyTrash(trash *storage.Trash, args []string, sync storage.Backup) {
+	fs := newFlagSet("trash empty")
+	olderThan := fs.String("older-than", "", "only remove notes deleted longer ago than this (e.g. 30d, 12h)")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil || len(positional) > 0 {
+		if err == nil {
+			err = fmt.Errorf("unexpected arguments: %s", strings.Join(positional, " "))
+		}
+		usageError(err, trashUsage)
+		exit(2)
+	}
+
+	var age time.Duration
+	if *olderThan != "" {
+		age, err = parseAge(*olderThan)
+		if err != nil {
+			usageError(err, trashUsage)
+			exit(2)
+		}
+	}
+
+	removed, err := trash.Empty(age)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error emptying trash: %v\n", err)
+		exit(1)
+	}
+	fmt.Printf("✓ Permanently deleted %d note(s)\n", removed)
+
+	if sync != nil && removed > 0 {
+		if err := sync.CommitAndPush("trash: empty"); err != nil {
+			fmt.Printf("Warning: backup failed: %v\n", err)
+		}
+	}
+}
+
+// parseAge parses durations like "30d", "2w" or anything time.ParseDuration
+// accepts ("12h", "90m").
+func parseAge(s string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	}
+	for suffix, unit := range units {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.Atoi(n)
+			if err != nil || v < 0 {
+				return 0, fmt.Errorf("invalid age: %s", s)
+			}
+			return time.Duration(v) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age: %s", s)
+	}
+	return d, nil
+}
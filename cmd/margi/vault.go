@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gcaixeta/marginalia/internal/config"
+	"github.com/gcaixeta/marginalia/internal/snippet"
+	"github.com/gcaixeta/marginalia/internal/storage"
+)
+
+// extractVaultFlag removes the global --vault flag from args, wherever it
+// appears before a "--" terminator, and returns the remaining arguments
+// along with the vault name.
+func extractVaultFlag(args []string) ([]string, string, error) {
+	rest := make([]string, 0, len(args))
+	name := ""
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return append(rest, args[i:]...), name, nil
+		case arg == "--vault" || arg == "-vault":
+			if i+1 >= len(args) {
+				return nil, "", fmt.Errorf("flag needs an argument: %s", arg)
+			}
+			name = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "--vault="):
+			name = strings.TrimPrefix(arg, "--vault=")
+		case strings.HasPrefix(arg, "-vault="):
+			name = strings.TrimPrefix(arg, "-vault=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return rest, name, nil
+}
+
+// useVault points the data and templates directories at the selected
+// vault: the --vault flag, then $MARGI_VAULT, then the configured default.
+func useVault(cfg *config.Config, name string) (config.VaultConfig, error) {
+	if name == "" {
+		name = os.Getenv(config.VaultEnv)
+	}
+
+	vault, err := cfg.Vault(name)
+	if err != nil {
+		if len(cfg.Vaults) > 0 {
+			err = fmt.Errorf("%w (configured vaults: %s)", err, strings.Join(cfg.VaultNames(), ", "))
+		}
+		return config.VaultConfig{}, err
+	}
+
+	storage.UseDataDir(vault.Root)
+	snippet.UseTemplatesDir(vault.Templates)
+	return vault, nil
+}
+
+func listVaults(cfg *config.Config, current string) {
+	if len(cfg.Vaults) == 0 {
+		dataDir, _ := storage.DataDir()
+		fmt.Printf("No vaults configured; using %s\n", dataDir)
+		return
+	}
+
+	if current == "" {
+		current = os.Getenv(config.VaultEnv)
+	}
+	if current == "" {
+		current = cfg.DefaultVault
+	}
+
+	for _, name := range cfg.VaultNames() {
+		marker := " "
+		if name == current {
+			marker = "*"
+		}
+		vault, err := cfg.Vault(name)
+		if err != nil {
+			fmt.Printf("%s %s\t(%v)\n", marker, name, err)
+			continue
+		}
+		fmt.Printf("%s %s\t%s\n", marker, name, vault.Root)
+	}
+}
@@ -1,10 +1,7 @@
 package collection
 
 import (
-	"io/fs"
-	"os"
 	"path/filepath"
-	"sort"
 
 	"github.com/gcaixeta/marginalia/internal/storage"
 )
@@ -18,97 +15,95 @@ type Collection struct {
 
 // ListCollections returns all available collections with their file counts
 func ListCollections() ([]Collection, error) {
-	dataDir, err := storage.DataDir()
+	store, err := storage.Store()
 	if err != nil {
 		return nil, err
 	}
 
-	var collections []Collection
-
-	entries, err := os.ReadDir(dataDir)
+	names, err := store.Collections()
 	if err != nil {
 		return nil, err
 	}
-
-	for _, entry := range entries {
-		if entry.IsDir() {
-			collectionPath := filepath.Join(dataDir, entry.Name())
-			fileCount, err := countFilesInDir(collectionPath)
-			if err != nil {
-				// If we can't count files, just set to 0
-				fileCount = 0
-			}
-
-			collections = append(collections, Collection{
-				Name:      entry.Name(),
-				FileCount: fileCount,
-				Path:      collectionPath,
-			})
-		}
+	counts, err := countFiles(store)
+	if err != nil {
+		return nil, err
 	}
 
-	// Sort collections alphabetically by name
-	sort.Slice(collections, func(i, j int) bool {
-		return collections[i].Name < collections[j].Name
-	})
+	// Collections are returned sorted by name
+	var collections []Collection
+	for _, name := range names {
+		collections = append(collections, Collection{
+			Name:      name,
+			FileCount: counts[name],
+			Path:      collectionPath(store, name),
+		})
+	}
 
 	return collections, nil
 }
 
 // GetCollectionStats returns the number of files in a specific collection
 func GetCollectionStats(name string) (int, error) {
-	dataDir, err := storage.DataDir()
+	store, err := storage.Store()
 	if err != nil {
 		return 0, err
 	}
 
-	collectionPath := filepath.Join(dataDir, name)
-	return countFilesInDir(collectionPath)
+	counts, err := countFiles(store)
+	if err != nil {
+		return 0, err
+	}
+	return counts[name], nil
 }
 
 // CreateCollection creates a new collection directory
 func CreateCollection(name string) error {
-	dataDir, err := storage.DataDir()
+	store, err := storage.Store()
 	if err != nil {
 		return err
 	}
-
-	collectionPath := filepath.Join(dataDir, name)
-	return storage.EnsureDir(collectionPath)
+	return store.CreateCollection(name)
 }
 
 // CollectionExists checks if a collection already exists
 func CollectionExists(name string) bool {
-	dataDir, err := storage.DataDir()
+	store, err := storage.Store()
 	if err != nil {
 		return false
 	}
 
-	collectionPath := filepath.Join(dataDir, name)
-	info, err := os.Stat(collectionPath)
+	names, err := store.Collections()
 	if err != nil {
 		return false
 	}
-
-	return info.IsDir()
-}
-
-// countFilesInDir counts the number of files (not directories) in a directory
-func countFilesInDir(dirPath string) (int, error) {
-	count := 0
-
-	err := filepath.WalkDir(dirPath, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return nil // Skip entries that cause errors
+	for _, n := range names {
+		if n == name {
+			return true
 		}
+	}
+	return false
+}
 
-		// Only count files, not directories
-		if !d.IsDir() {
-			count++
-		}
+// countFiles counts the notes of every collection in store
+func countFiles(store storage.NoteStore) (map[string]int, error) {
+	files, err := store.List()
+	if err != nil {
+		return nil, err
+	}
 
-		return nil
-	})
+	counts := map[string]int{}
+	for _, file := range files {
+		counts[file.Collection]++
+	}
+	return counts, nil
+}
 
-	return count, err
+// collectionPath returns the directory of a collection, or "" when the
+// store does not keep notes on the local filesystem.
+func collectionPath(store storage.NoteStore, name string) string {
+	local, ok := store.(*storage.LocalStore)
+	if !ok {
+		return ""
+	}
+	return filepath.Join(local.Root(), name)
 }
@@ -8,6 +8,20 @@ import (
 	"github.com/gcaixeta/marginalia/internal/storage"
 )
 
+// TestMain runs the tests against a temporary data dir rather than the
+// user's notes.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "margi-collections")
+	if err != nil {
+		panic(err)
+	}
+	storage.UseDataDir(dir)
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func TestListCollections(t *testing.T) {
 	collections, err := ListCollections()
 	if err != nil {
@@ -93,3 +107,30 @@ func TestGetCollectionStats(t *testing.T) {
 		}
 	}
 }
+
+func TestListCollections_MemStore(t *testing.T) {
+	store := storage.NewMemStore()
+	storage.UseStore(store)
+	defer storage.UseStore(nil)
+
+	store.CreateCollection("empty")
+	store.Create("work", "a.md", nil)
+	store.Create("work", "b.md", nil)
+
+	collections, err := ListCollections()
+	if err != nil {
+		t.Fatalf("ListCollections failed: %v", err)
+	}
+	if len(collections) != 2 {
+		t.Fatalf("Expected 2 collections, got %v", collections)
+	}
+	if collections[0].Name != "empty" || collections[0].FileCount != 0 {
+		t.Errorf("Unexpected first collection: %+v", collections[0])
+	}
+	if collections[1].Name != "work" || collections[1].FileCount != 2 || collections[1].Path != "" {
+		t.Errorf("Unexpected second collection: %+v", collections[1])
+	}
+	if !CollectionExists("work") || CollectionExists("missing") {
+		t.Error("CollectionExists disagrees with the store")
+	}
+}
@@ -1,17 +1,81 @@
 package config
 
 type Config struct {
-	Editor string
-	Backup BackupConfig
+	Editor       string
+	DefaultVault string
+	Vaults       map[string]VaultConfig
+	Backup       BackupConfig
+	Encryption   EncryptionConfig
+	Periodic     PeriodicConfig
+}
+
+// VaultConfig is a named set of notes with its own data directory,
+// templates and backup settings
+type VaultConfig struct {
+	Root       string // Data directory holding the collections
+	Templates  string // Directory holding the collection templates
+	Backup     BackupConfig
+	Encryption EncryptionConfig
+	Periodic   PeriodicConfig
 }
 
 type BackupConfig struct {
-	Provider string
+	Provider string // "git", "dir", "snapshot" or "webdav"
 	Git      GitConfig
+	Dir      DirConfig
+	Snapshot SnapshotConfig
+	WebDAV   WebDAVConfig
 }
 
 type GitConfig struct {
 	Repo   string
 	Remote string
 	Branch string
+	Engine string // "exec" or "go-git"; the git binary is used when installed
+}
+
+// DirConfig mirrors the notes into a directory, e.g. on a mounted drive
+type DirConfig struct {
+	Path string
+}
+
+// SnapshotConfig writes timestamped .tar.gz archives of the notes into a
+// directory. The Keep fields are retention rules; when all are zero every
+// snapshot is kept.
+type SnapshotConfig struct {
+	Path        string
+	KeepLast    int // Most recent snapshots to keep
+	KeepDaily   int // Days to keep the newest snapshot of
+	KeepWeekly  int // Weeks to keep the newest snapshot of
+	KeepMonthly int // Months to keep the newest snapshot of
+}
+
+// WebDAVConfig syncs the notes both ways with a WebDAV folder, e.g. on
+// Nextcloud
+type WebDAVConfig struct {
+	URL      string // Folder holding the collections
+	User     string
+	Password string // Falls back to $MARGI_WEBDAV_PASSWORD
+}
+
+// EncryptionConfig encrypts the notes of a vault at rest and in its
+// backup
+type EncryptionConfig struct {
+	Enabled        bool
+	Identity       string // age X25519 identity file; without it a passphrase is asked for
+	ObfuscateNames bool   // Store notes under opaque file names
+}
+
+// PeriodicConfig sets up the daily, weekly and monthly notes opened by
+// `margi today`, `margi week` and `margi month`
+type PeriodicConfig struct {
+	Daily   PeriodConfig
+	Weekly  PeriodConfig
+	Monthly PeriodConfig
+}
+
+// PeriodConfig says where the notes of a period go and how they are titled
+type PeriodConfig struct {
+	Collection string // Defaults to "journal"
+	Format     string // Go time layout of the title, applied to the first day of the period
 }
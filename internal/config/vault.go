@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// VaultEnv is the environment variable that selects a vault when no
+// --vault flag is given
+const VaultEnv = "MARGI_VAULT"
+
+// Vault returns the vault called name. An empty name selects DefaultVault,
+// and when that is empty too the built-in vault: the default data and
+// templates directories with the top-level backup, encryption and periodic
+// notes settings.
+func (c *Config) Vault(name string) (VaultConfig, error) {
+	if name == "" {
+		name = c.DefaultVault
+	}
+	if name == "" {
+		vault := VaultConfig{Backup: c.Backup, Encryption: c.Encryption, Periodic: c.Periodic}
+		return vault, expandPaths(&vault)
+	}
+
+	vault, ok := c.Vaults[name]
+	if !ok {
+		return VaultConfig{}, fmt.Errorf("unknown vault %q", name)
+	}
+	if vault.Root == "" {
+		return VaultConfig{}, fmt.Errorf("vault %q has no root", name)
+	}
+
+	var err error
+	if vault.Root, err = expandHome(vault.Root); err != nil {
+		return VaultConfig{}, err
+	}
+	if vault.Templates != "" {
+		if vault.Templates, err = expandHome(vault.Templates); err != nil {
+			return VaultConfig{}, err
+		}
+	}
+	if err := expandPaths(&vault); err != nil {
+		return VaultConfig{}, err
+	}
+
+	return vault, nil
+}
+
+// VaultNames returns the names of the configured vaults, sorted
+func (c *Config) VaultNames() []string {
+	names := make([]string, 0, len(c.Vaults))
+	for name := range c.Vaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// expandPaths expands "~" in the paths of the backup and encryption
+// settings
+func expandPaths(vault *VaultConfig) error {
+	backup := &vault.Backup
+	for _, path := range []*string{&backup.Git.Repo, &backup.Dir.Path, &backup.Snapshot.Path, &vault.Encryption.Identity} {
+		var err error
+		if *path, err = expandHome(*path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expandHome replaces a leading "~" with the user's home directory
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+const vaultsConfig = `
+editor = "vim"
+defaultvault = "personal"
+
+[backup]
+provider = "git"
+[backup.git]
+repo = "/legacy"
+
+[vaults.personal]
+root = "~/notes"
+
+[vaults.team]
+root = "/srv/team"
+templates = "/srv/team/templates"
+[vaults.team.backup]
+provider = "git"
+[vaults.team.backup.git]
+repo = "/srv/team"
+branch = "trunk"
+`
+
+func loadTestConfig(t *testing.T, data string) *Config {
+	t.Helper()
+	var cfg Config
+	if err := toml.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	return &cfg
+}
+
+func TestVault_Named(t *testing.T) {
+	cfg := loadTestConfig(t, vaultsConfig)
+
+	vault, err := cfg.Vault("team")
+	if err != nil {
+		t.Fatalf("Vault: %v", err)
+	}
+	if vault.Root != "/srv/team" || vault.Templates != "/srv/team/templates" {
+		t.Errorf("vault = %+v", vault)
+	}
+	if vault.Backup.Provider != "git" || vault.Backup.Git.Branch != "trunk" {
+		t.Errorf("backup = %+v", vault.Backup)
+	}
+}
+
+func TestVault_DefaultExpandsHome(t *testing.T) {
+	cfg := loadTestConfig(t, vaultsConfig)
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+
+	vault, err := cfg.Vault("")
+	if err != nil {
+		t.Fatalf("Vault: %v", err)
+	}
+	if want := filepath.Join(home, "notes"); vault.Root != want {
+		t.Errorf("Root = %q, want %q", vault.Root, want)
+	}
+	if vault.Backup.Provider != "" {
+		t.Errorf("personal vault inherited the top-level backup: %+v", vault.Backup)
+	}
+}
+
+func TestVault_BuiltIn(t *testing.T) {
+	cfg := loadTestConfig(t, "[backup]\nprovider = \"git\"\n")
+
+	vault, err := cfg.Vault("")
+	if err != nil {
+		t.Fatalf("Vault: %v", err)
+	}
+	if vault.Root != "" || vault.Backup.Provider != "git" {
+		t.Errorf("vault = %+v, want default dirs and top-level backup", vault)
+	}
+}
+
+func TestVault_Unknown(t *testing.T) {
+	cfg := loadTestConfig(t, vaultsConfig)
+	if _, err := cfg.Vault("nope"); err == nil {
+		t.Error("expected an error for an unknown vault")
+	}
+}
+
+func TestVault_ExpandsBackupPaths(t *testing.T) {
+	cfg := loadTestConfig(t, "[backup]\nprovider = \"snapshot\"\n[backup.snapshot]\npath = \"~/snapshots\"\nkeepdaily = 7\n")
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+
+	vault, err := cfg.Vault("")
+	if err != nil {
+		t.Fatalf("Vault: %v", err)
+	}
+	if want := filepath.Join(home, "snapshots"); vault.Backup.Snapshot.Path != want || vault.Backup.Snapshot.KeepDaily != 7 {
+		t.Errorf("snapshot = %+v, want path %q", vault.Backup.Snapshot, want)
+	}
+}
+
+func TestVault_Encryption(t *testing.T) {
+	cfg := loadTestConfig(t, "[encryption]\nenabled = true\nidentity = \"~/.age/key.txt\"\n\n[vaults.work]\nroot = \"/srv/work\"\n[vaults.work.encryption]\nenabled = true\nobfuscatenames = true\n")
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+
+	vault, err := cfg.Vault("")
+	if err != nil {
+		t.Fatalf("Vault: %v", err)
+	}
+	if want := filepath.Join(home, ".age", "key.txt"); !vault.Encryption.Enabled || vault.Encryption.Identity != want {
+		t.Errorf("built-in vault encryption = %+v, want identity %q", vault.Encryption, want)
+	}
+
+	work, err := cfg.Vault("work")
+	if err != nil {
+		t.Fatalf("Vault(work): %v", err)
+	}
+	if !work.Encryption.Enabled || !work.Encryption.ObfuscateNames || work.Encryption.Identity != "" {
+		t.Errorf("work vault encryption = %+v", work.Encryption)
+	}
+}
+
+func TestVault_Periodic(t *testing.T) {
+	cfg := loadTestConfig(t, "[periodic.daily]\ncollection = \"diary\"\nformat = \"Monday 2006-01-02\"\n\n[vaults.work]\nroot = \"/srv/work\"\n[vaults.work.periodic.weekly]\ncollection = \"reviews\"\n")
+
+	vault, err := cfg.Vault("")
+	if err != nil {
+		t.Fatalf("Vault: %v", err)
+	}
+	if daily := vault.Periodic.Daily; daily.Collection != "diary" || daily.Format != "Monday 2006-01-02" {
+		t.Errorf("built-in vault daily notes = %+v", daily)
+	}
+
+	work, err := cfg.Vault("work")
+	if err != nil {
+		t.Fatalf("Vault(work): %v", err)
+	}
+	if work.Periodic.Weekly.Collection != "reviews" || work.Periodic.Daily.Collection != "" {
+		t.Errorf("work vault periodic notes = %+v", work.Periodic)
+	}
+}
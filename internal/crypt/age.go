@@ -0,0 +1,113 @@
+package crypt
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// identityPrefix starts the secret key lines of age X25519 identity files
+// written by age-keygen
+const identityPrefix = "AGE-SECRET-KEY-1"
+
+// ReadIdentity returns the 32-byte secret of the first X25519 identity in
+// an age identity file. Comment lines are skipped.
+func ReadIdentity(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, identityPrefix) {
+			continue
+		}
+		hrp, data, err := bech32Decode(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if hrp != "age-secret-key-" || len(data) != 32 {
+			return nil, fmt.Errorf("%s: not an X25519 identity", path)
+		}
+		return data, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%s: no age identity found", path)
+}
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+func bech32Polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := range 5 {
+			if (top>>i)&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	out := make([]byte, 0, len(hrp)*2+1)
+	for i := range len(hrp) {
+		out = append(out, hrp[i]>>5)
+	}
+	out = append(out, 0)
+	for i := range len(hrp) {
+		out = append(out, hrp[i]&31)
+	}
+	return out
+}
+
+// bech32Decode decodes a bech32 string, as used by age for keys, into its
+// human-readable part and data bytes. Unlike BIP 173 it allows strings
+// longer than 90 characters.
+func bech32Decode(s string) (string, []byte, error) {
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, fmt.Errorf("mixed case in key")
+	}
+	s = strings.ToLower(s)
+	sep := strings.LastIndexByte(s, '1')
+	if sep < 1 || sep+7 > len(s) {
+		return "", nil, fmt.Errorf("malformed key")
+	}
+	hrp := s[:sep]
+	var values []byte
+	for _, c := range s[sep+1:] {
+		i := strings.IndexRune(bech32Charset, c)
+		if i < 0 {
+			return "", nil, fmt.Errorf("invalid character %q in key", c)
+		}
+		values = append(values, byte(i))
+	}
+	if bech32Polymod(append(bech32HRPExpand(hrp), values...)) != 1 {
+		return "", nil, fmt.Errorf("invalid key checksum")
+	}
+
+	// Regroup the 5-bit values, minus the checksum, into bytes
+	var data []byte
+	acc, bits := uint32(0), uint(0)
+	for _, v := range values[:len(values)-6] {
+		acc = acc<<5 | uint32(v)
+		bits += 5
+		for bits >= 8 {
+			bits -= 8
+			data = append(data, byte(acc>>bits))
+		}
+	}
+	if bits >= 5 || acc&(1<<bits-1) != 0 {
+		return "", nil, fmt.Errorf("invalid key padding")
+	}
+	return hrp, data, nil
+}
@@ -0,0 +1,246 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+// ParamsFile is the file, in the data dir, recording how the vault key is
+// derived. It holds no secret and must travel with the notes.
+const ParamsFile = ".margi-crypt.json"
+
+// magic starts every encrypted note, so encrypted and plain files can be
+// told apart
+var magic = []byte("margi-encrypted-v1\n")
+
+// checkText is sealed into the params file to tell a wrong passphrase or
+// identity from a right one
+const checkText = "marginalia"
+
+// Key derivation methods recorded in ParamsFile
+const (
+	KDFScrypt = "scrypt" // From a passphrase
+	KDFX25519 = "x25519" // From an age X25519 identity
+)
+
+// ErrWrongKey is returned by Unlock when the passphrase or identity is not
+// the one the vault was encrypted with
+var ErrWrongKey = errors.New("wrong passphrase or identity")
+
+type params struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	Salt    []byte `json:"salt"`
+	N       int    `json:"n,omitempty"` // scrypt cost parameters
+	R       int    `json:"r,omitempty"`
+	P       int    `json:"p,omitempty"`
+	Check   []byte `json:"check"`
+}
+
+// Key encrypts the notes of one vault
+type Key struct {
+	aead  cipher.AEAD
+	names []byte // HMAC key for obfuscated file names
+}
+
+// Unlock returns the key of the vault whose data dir is dir, setting up
+// encryption there on first use. With identity, the path of an age
+// X25519 identity file, the key comes from it; otherwise passphrase is
+// called for one, with confirm set when the vault is new and the
+// passphrase should be asked twice.
+func Unlock(dir, identity string, passphrase func(confirm bool) (string, error)) (*Key, error) {
+	path := filepath.Join(dir, ParamsFile)
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	kdf := KDFScrypt
+	if identity != "" {
+		kdf = KDFX25519
+	}
+
+	if os.IsNotExist(err) {
+		p := params{Version: 1, KDF: kdf, Salt: make([]byte, 16)}
+		if _, err := rand.Read(p.Salt); err != nil {
+			return nil, err
+		}
+		if kdf == KDFScrypt {
+			p.N, p.R, p.P = 1<<15, 8, 1
+		}
+		key, err := deriveKey(p, identity, passphrase, true)
+		if err != nil {
+			return nil, err
+		}
+		p.Check = key.Seal("", []byte(checkText))
+		data, err := json.MarshalIndent(p, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+
+	var p params
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", ParamsFile, err)
+	}
+	if p.KDF != kdf {
+		if p.KDF == KDFScrypt {
+			return nil, fmt.Errorf("the vault is encrypted with a passphrase, not an identity")
+		}
+		return nil, fmt.Errorf("the vault is encrypted with an identity, set encryption.identity")
+	}
+	key, err := deriveKey(p, identity, passphrase, false)
+	if err != nil {
+		return nil, err
+	}
+	if _, check, err := key.Open(p.Check); err != nil || string(check) != checkText {
+		return nil, ErrWrongKey
+	}
+	return key, nil
+}
+
+func deriveKey(p params, identity string, passphrase func(bool) (string, error), confirm bool) (*Key, error) {
+	var master []byte
+	switch p.KDF {
+	case KDFScrypt:
+		pass, err := passphrase(confirm)
+		if err != nil {
+			return nil, err
+		}
+		if pass == "" {
+			return nil, fmt.Errorf("empty passphrase")
+		}
+		master, err = scrypt.Key([]byte(pass), p.Salt, p.N, p.R, p.P, 32)
+		if err != nil {
+			return nil, err
+		}
+	case KDFX25519:
+		secret, err := ReadIdentity(identity)
+		if err != nil {
+			return nil, err
+		}
+		master, err = hkdf.Key(sha256.New, secret, p.Salt, "marginalia vault", 32)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown key derivation %q", p.KDF)
+	}
+	return newKey(master)
+}
+
+// newKey splits a master secret into the note and name keys
+func newKey(master []byte) (*Key, error) {
+	notes, err := hkdf.Key(sha256.New, master, nil, "marginalia notes", chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	names, err := hkdf.Key(sha256.New, master, nil, "marginalia names", 32)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(notes)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{aead: aead, names: names}, nil
+}
+
+// IsSealed reports whether data is an encrypted note
+func IsSealed(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// Seal encrypts a note. The name is encrypted along with the content, so
+// a note stored under an obfuscated name still knows its own.
+func (k *Key) Seal(name string, content []byte) []byte {
+	plain := binary.AppendUvarint(nil, uint64(len(name)))
+	plain = append(plain, name...)
+	plain = append(plain, content...)
+
+	out := make([]byte, len(magic), len(magic)+k.aead.NonceSize()+len(plain)+k.aead.Overhead())
+	copy(out, magic)
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		panic(err) // crypto/rand never fails on supported platforms
+	}
+	out = append(out, nonce...)
+	return k.aead.Seal(out, nonce, plain, magic)
+}
+
+// Open decrypts a note sealed by Seal, returning its name and content
+func (k *Key) Open(data []byte) (string, []byte, error) {
+	if !IsSealed(data) {
+		return "", nil, fmt.Errorf("not an encrypted note")
+	}
+	data = data[len(magic):]
+	if len(data) < k.aead.NonceSize() {
+		return "", nil, fmt.Errorf("truncated encrypted note")
+	}
+	nonce, sealed := data[:k.aead.NonceSize()], data[k.aead.NonceSize():]
+	plain, err := k.aead.Open(nil, nonce, sealed, magic)
+	if err != nil {
+		return "", nil, fmt.Errorf("decrypting note: %w", err)
+	}
+
+	n, size := binary.Uvarint(plain)
+	if size <= 0 || uint64(len(plain)-size) < n {
+		return "", nil, fmt.Errorf("corrupt encrypted note")
+	}
+	plain = plain[size:]
+	return string(plain[:n]), plain[n:], nil
+}
+
+// ObfuscatedName returns the opaque file name a note is stored under when
+// names are obfuscated. The same note always gets the same name, so
+// every machine sharing the vault agrees on it.
+func (k *Key) ObfuscatedName(collection, name string) string {
+	mac := hmac.New(sha256.New, k.names)
+	mac.Write([]byte(collection + "/" + name))
+	return hex.EncodeToString(mac.Sum(nil)[:16]) + ".enc"
+}
+
+// Wipe overwrites a file with zeros before removing it. This is best
+// effort: journaling and copy-on-write filesystems and SSDs may keep the
+// old blocks around.
+func Wipe(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	info, err := f.Stat()
+	if err == nil {
+		_, err = f.Write(make([]byte, info.Size()))
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if rerr := os.Remove(path); err == nil {
+		err = rerr
+	}
+	return err
+}
@@ -0,0 +1,166 @@
+package crypt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func passphrase(pass string) func(bool) (string, error) {
+	return func(bool) (string, error) { return pass, nil }
+}
+
+func TestUnlock_Passphrase(t *testing.T) {
+	dir := t.TempDir()
+	confirmed := false
+	key, err := Unlock(dir, "", func(confirm bool) (string, error) {
+		confirmed = confirm
+		return "correct horse", nil
+	})
+	if err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if !confirmed {
+		t.Error("a new vault did not ask to confirm the passphrase")
+	}
+	sealed := key.Seal("plan.md", []byte("# Plan\n"))
+
+	again, err := Unlock(dir, "", passphrase("correct horse"))
+	if err != nil {
+		t.Fatalf("second Unlock: %v", err)
+	}
+	name, content, err := again.Open(sealed)
+	if err != nil || name != "plan.md" || string(content) != "# Plan\n" {
+		t.Errorf("Open = %q, %q, %v", name, content, err)
+	}
+
+	if _, err := Unlock(dir, "", passphrase("wrong")); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("wrong passphrase: err = %v, want ErrWrongKey", err)
+	}
+	if _, err := Unlock(dir, filepath.Join(dir, "key.txt"), nil); err == nil {
+		t.Error("expected an error unlocking a passphrase vault with an identity")
+	}
+}
+
+func TestUnlock_Identity(t *testing.T) {
+	dir := t.TempDir()
+	secret := bytes.Repeat([]byte{0x42}, 32)
+	identity := filepath.Join(dir, "key.txt")
+	content := "# created: 2026-10-17T10:00:00Z\n# public key: age1...\n" + strings.ToUpper(bech32Encode("age-secret-key-", secret)) + "\n"
+	if err := os.WriteFile(identity, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := Unlock(dir, identity, nil)
+	if err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	again, err := Unlock(dir, identity, nil)
+	if err != nil {
+		t.Fatalf("second Unlock: %v", err)
+	}
+	if _, _, err := again.Open(key.Seal("a.md", []byte("a"))); err != nil {
+		t.Errorf("Open with the same identity: %v", err)
+	}
+
+	other := filepath.Join(dir, "other.txt")
+	os.WriteFile(other, []byte(strings.ToUpper(bech32Encode("age-secret-key-", make([]byte, 32)))), 0600)
+	if _, err := Unlock(dir, other, nil); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("other identity: err = %v, want ErrWrongKey", err)
+	}
+}
+
+func TestKey_OpenRejectsTampering(t *testing.T) {
+	key, err := newKey(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed := key.Seal("plan.md", []byte("secret"))
+	if !IsSealed(sealed) || IsSealed([]byte("# Plan\n")) {
+		t.Error("IsSealed can't tell sealed from plain notes")
+	}
+	if bytes.Contains(sealed, []byte("secret")) || bytes.Contains(sealed, []byte("plan.md")) {
+		t.Error("sealed note contains its plaintext")
+	}
+
+	sealed[len(sealed)-1] ^= 1
+	if _, _, err := key.Open(sealed); err == nil {
+		t.Error("Open accepted a tampered note")
+	}
+}
+
+func TestKey_ObfuscatedName(t *testing.T) {
+	key, _ := newKey(make([]byte, 32))
+	other, _ := newKey(bytes.Repeat([]byte{1}, 32))
+	a := key.ObfuscatedName("work", "plan.md")
+	if a != key.ObfuscatedName("work", "plan.md") {
+		t.Error("ObfuscatedName is not stable")
+	}
+	if a == key.ObfuscatedName("home", "plan.md") || a == other.ObfuscatedName("work", "plan.md") {
+		t.Error("ObfuscatedName collides across collections or keys")
+	}
+	if strings.Contains(a, "plan") || !strings.HasSuffix(a, ".enc") {
+		t.Errorf("ObfuscatedName = %q", a)
+	}
+}
+
+func TestBech32Decode(t *testing.T) {
+	// Test vectors from BIP 173
+	hrp, data, err := bech32Decode("abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw")
+	if err != nil || hrp != "abcdef" || hex.EncodeToString(data) != "00443214c74254b635cf84653a56d7c675be77df" {
+		t.Errorf("bech32Decode = %q, %x, %v", hrp, data, err)
+	}
+	if _, _, err := bech32Decode("a12uel5l"); err != nil {
+		t.Errorf("bech32Decode(empty data): %v", err)
+	}
+	for _, bad := range []string{"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxx", "A12uEl5l", "a1b2"} {
+		if _, _, err := bech32Decode(bad); err == nil {
+			t.Errorf("bech32Decode(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestWipe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "note.md")
+	os.WriteFile(path, []byte("secret"), 0600)
+	if err := Wipe(path); err != nil {
+		t.Fatalf("Wipe: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Wipe left the file behind")
+	}
+	if err := Wipe(path); err != nil {
+		t.Errorf("Wipe of a missing file: %v", err)
+	}
+}
+
+// bech32Encode is the inverse of bech32Decode, to build identities
+func bech32Encode(hrp string, data []byte) string {
+	var values []byte
+	acc, bits := uint32(0), uint(0)
+	for _, b := range data {
+		acc = acc<<8 | uint32(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			values = append(values, byte(acc>>bits)&31)
+		}
+	}
+	if bits > 0 {
+		values = append(values, byte(acc<<(5-bits))&31)
+	}
+	mod := bech32Polymod(append(append(bech32HRPExpand(hrp), values...), 0, 0, 0, 0, 0, 0)) ^ 1
+	for i := range 6 {
+		values = append(values, byte(mod>>(5*(5-i)))&31)
+	}
+	var s strings.Builder
+	s.WriteString(hrp + "1")
+	for _, v := range values {
+		s.WriteByte(bech32Charset[v])
+	}
+	return s.String()
+}
@@ -1,7 +1,7 @@
 package editor
 
 import (
-	"log"
+	"fmt"
 	"os"
 	"os/exec"
 )
@@ -20,12 +20,15 @@ func ResolveEditor(configured string) string {
 	return "vi"
 }
 
-func OpenInEditor(filePath, editorCmd string) {
+// Run opens filePath in the editor and waits for it to exit. It fails when
+// the editor can't be started or exits with an error, e.g. after :cq in vim.
+func Run(filePath, editorCmd string) error {
 	cmd := exec.Command(editorCmd, filePath)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
-		log.Fatalf("Error running %s: %v\n", editorCmd, err)
+		return fmt.Errorf("error running %s: %w", editorCmd, err)
 	}
+	return nil
 }
@@ -0,0 +1,318 @@
+package links
+
+import (
+	"bufio"
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/gcaixeta/marginalia/internal/slug"
+	"github.com/gcaixeta/marginalia/internal/storage"
+)
+
+// Link is a wiki-style [[target]] reference found in a note
+type Link struct {
+	Raw        string // Text between the brackets, e.g. "work/meeting|the meeting"
+	Target     string // Target without label or heading, e.g. "work/meeting"
+	Collection string // Collection part of the target, if any
+	Name       string // Note part of the target
+	Line       int    // 1-based line number
+}
+
+// Reference is a link together with the note that contains it
+type Reference struct {
+	Source storage.FileItem
+	Link   Link
+}
+
+// Parse extracts [[links]] from a Markdown body. "[[target|label]]" and
+// "[[target#heading]]" are supported; links inside code are ignored.
+func Parse(body []byte) []Link {
+	var links []Link
+	inFence := false
+	lineNo := 0
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		for _, raw := range lineLinks(line) {
+			if l, ok := newLink(raw, lineNo); ok {
+				links = append(links, l)
+			}
+		}
+	}
+
+	return links
+}
+
+func lineLinks(line string) []string {
+	var raws []string
+	for _, span := range linkSpans(line) {
+		raws = append(raws, line[span[0]:span[1]])
+	}
+	return raws
+}
+
+// linkSpans returns the byte offsets of the text inside each [[...]] of
+// line, skipping inline code.
+func linkSpans(line string) [][2]int {
+	var spans [][2]int
+	inCode := false
+
+	for i := 0; i < len(line); i++ {
+		if line[i] == '`' {
+			inCode = !inCode
+			continue
+		}
+		if inCode || !strings.HasPrefix(line[i:], "[[") {
+			continue
+		}
+		end := strings.Index(line[i+2:], "]]")
+		if end < 0 {
+			break
+		}
+		spans = append(spans, [2]int{i + 2, i + 2 + end})
+		i += end + 3
+	}
+
+	return spans
+}
+
+// Rewrite replaces the target of every link in body for which target
+// returns a non-empty string, keeping any "#heading" or "|label" suffix.
+// It returns the new body and the number of links rewritten.
+func Rewrite(body []byte, target func(Link) string) ([]byte, int) {
+	var out strings.Builder
+	rewritten := 0
+	inFence := false
+	lineNo := 0
+
+	for _, line := range strings.SplitAfter(string(body), "\n") {
+		lineNo++
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			out.WriteString(line)
+			continue
+		}
+		if inFence {
+			out.WriteString(line)
+			continue
+		}
+
+		last := 0
+		for _, span := range linkSpans(line) {
+			raw := line[span[0]:span[1]]
+			l, ok := newLink(raw, lineNo)
+			if !ok {
+				continue
+			}
+			newTarget := target(l)
+			if newTarget == "" {
+				continue
+			}
+
+			suffix := ""
+			if i := strings.IndexAny(raw, "#|"); i >= 0 {
+				suffix = raw[i:]
+			}
+			out.WriteString(line[last:span[0]])
+			out.WriteString(newTarget + suffix)
+			last = span[1]
+			rewritten++
+		}
+		out.WriteString(line[last:])
+	}
+
+	return []byte(out.String()), rewritten
+}
+
+func newLink(raw string, lineNo int) (Link, bool) {
+	target, _, _ := strings.Cut(raw, "|")
+	target, _, _ = strings.Cut(target, "#")
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return Link{}, false
+	}
+
+	l := Link{Raw: raw, Target: target, Name: target, Line: lineNo}
+	if i := strings.LastIndex(target, "/"); i >= 0 {
+		l.Collection = strings.TrimSpace(target[:i])
+		l.Name = strings.TrimSpace(target[i+1:])
+	}
+	l.Name = strings.TrimSuffix(l.Name, ".md")
+	return l, l.Name != ""
+}
+
+// Resolver resolves link targets against a set of notes
+type Resolver struct {
+	byKey map[string][]storage.FileItem
+}
+
+// NewResolver indexes files by file name (without extension), by the slug
+// after the timestamp prefix and by the slug of that slug's title, so
+// [[20260310-150000-meeting]], [[meeting]] and [[Meeting]] all resolve.
+func NewResolver(files []storage.FileItem) *Resolver {
+	r := &Resolver{byKey: map[string][]storage.FileItem{}}
+
+	sorted := append([]storage.FileItem(nil), files...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Path < sorted[j].Path
+	})
+
+	for _, file := range sorted {
+		for _, key := range Keys(file) {
+			r.byKey[key] = append(r.byKey[key], file)
+		}
+	}
+	return r
+}
+
+// Keys returns the link names that resolve to file
+func Keys(file storage.FileItem) []string {
+	base := strings.TrimSuffix(file.Name, ".md")
+	_, s, _ := slug.SplitName(file.Name)
+
+	keys := []string{base}
+	if s != base {
+		keys = append(keys, s)
+	}
+	return keys
+}
+
+// Resolve returns the note a link points to. from is the note containing
+// the link: when several notes share a slug, one in the same collection wins.
+func (r *Resolver) Resolve(l Link, from storage.FileItem) (storage.FileItem, bool) {
+	candidates := r.candidates(l.Name)
+
+	var fallback *storage.FileItem
+	for i, file := range candidates {
+		switch {
+		case l.Collection != "":
+			if file.Collection == l.Collection {
+				return file, true
+			}
+		case file.Collection == from.Collection:
+			return file, true
+		case fallback == nil:
+			fallback = &candidates[i]
+		}
+	}
+
+	if fallback != nil {
+		return *fallback, true
+	}
+	return storage.FileItem{}, false
+}
+
+// Matches returns every note the link could point to, ignoring which note
+// it is written in: the notes answering to its name, in its collection
+// when it names one
+func (r *Resolver) Matches(l Link) []storage.FileItem {
+	var matches []storage.FileItem
+	for _, file := range r.candidates(l.Name) {
+		if l.Collection == "" || file.Collection == l.Collection {
+			matches = append(matches, file)
+		}
+	}
+	return matches
+}
+
+func (r *Resolver) candidates(name string) []storage.FileItem {
+	if candidates := r.byKey[name]; len(candidates) > 0 {
+		return candidates
+	}
+	return r.byKey[slug.MakeSlug(name)]
+}
+
+// Retarget returns the target a link in from should use once the note it
+// points to has moved from old to moved. A link naming the full file name
+// keeps doing so, as does a slug link when another note in the new
+// collection shares the slug, so the link can't end up on the wrong note.
+// The collection is kept when the link had one or from is elsewhere.
+func (r *Resolver) Retarget(l Link, from, old, moved storage.FileItem) string {
+	_, target, _ := slug.SplitName(moved.Name)
+	if _, _, full := slug.SplitName(l.Name); full || r.shared(target, moved.Collection, old.Path) {
+		target = strings.TrimSuffix(moved.Name, ".md")
+	}
+	if l.Collection != "" || from.Collection != moved.Collection {
+		return moved.Collection + "/" + target
+	}
+	return target
+}
+
+// shared reports whether a note of collection other than the one at path
+// answers to key
+func (r *Resolver) shared(key, collection, path string) bool {
+	for _, file := range r.byKey[key] {
+		if file.Collection == collection && file.Path != path {
+			return true
+		}
+	}
+	return false
+}
+
+// Scan reads every file from store and returns all of their links
+func Scan(store storage.NoteStore, files []storage.FileItem) ([]Reference, error) {
+	var refs []Reference
+	for _, file := range files {
+		content, err := store.Read(file.Collection, file.Name)
+		if err != nil {
+			continue // Skip notes that disappeared or can't be read
+		}
+		for _, l := range Parse(content) {
+			refs = append(refs, Reference{Source: file, Link: l})
+		}
+	}
+	return refs, nil
+}
+
+// Backlinks returns the links in files, read from store, that resolve to
+// target
+func Backlinks(store storage.NoteStore, files []storage.FileItem, target storage.FileItem) ([]Reference, error) {
+	refs, err := Scan(store, files)
+	if err != nil {
+		return nil, err
+	}
+
+	r := NewResolver(files)
+	backlinks := []Reference{}
+	for _, ref := range refs {
+		if ref.Source.Path == target.Path {
+			continue
+		}
+		if resolved, ok := r.Resolve(ref.Link, ref.Source); ok && resolved.Path == target.Path {
+			backlinks = append(backlinks, ref)
+		}
+	}
+	return backlinks, nil
+}
+
+// Broken returns the links in files, read from store, that don't resolve
+// to any note
+func Broken(store storage.NoteStore, files []storage.FileItem) ([]Reference, error) {
+	refs, err := Scan(store, files)
+	if err != nil {
+		return nil, err
+	}
+
+	r := NewResolver(files)
+	broken := []Reference{}
+	for _, ref := range refs {
+		if _, ok := r.Resolve(ref.Link, ref.Source); !ok {
+			broken = append(broken, ref)
+		}
+	}
+	return broken, nil
+}
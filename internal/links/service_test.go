@@ -0,0 +1,206 @@
+package links
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gcaixeta/marginalia/internal/storage"
+)
+
+func writeNote(t *testing.T, dir, collection, name, content string) storage.FileItem {
+	t.Helper()
+	path := filepath.Join(dir, collection, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return storage.FileItem{Path: path, Name: name, Collection: collection}
+}
+
+func TestParse(t *testing.T) {
+	body := []byte("See [[meeting]] and [[work/plan|the plan]].\n" +
+		"Heading link [[roadmap#q3]] and `[[not-a-link]]`.\n" +
+		"```\n[[in-fence]]\n```\n" +
+		"Empty [[ ]] and unterminated [[oops\n")
+
+	got := Parse(body)
+	want := []Link{
+		{Raw: "meeting", Target: "meeting", Name: "meeting", Line: 1},
+		{Raw: "work/plan|the plan", Target: "work/plan", Collection: "work", Name: "plan", Line: 1},
+		{Raw: "roadmap#q3", Target: "roadmap", Name: "roadmap", Line: 2},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Parse() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Parse()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	files := []storage.FileItem{
+		{Path: "/d/work/20260310-15:00:00-meeting.md", Name: "20260310-15:00:00-meeting.md", Collection: "work"},
+		{Path: "/d/journal/20260311-090000-meeting.md", Name: "20260311-090000-meeting.md", Collection: "journal"},
+		{Path: "/d/journal/plain-note.md", Name: "plain-note.md", Collection: "journal"},
+	}
+	r := NewResolver(files)
+	fromJournal := storage.FileItem{Collection: "journal"}
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"meeting", "/d/journal/20260311-090000-meeting.md"},
+		{"work/meeting", "/d/work/20260310-15:00:00-meeting.md"},
+		{"Meeting", "/d/journal/20260311-090000-meeting.md"},
+		{"20260310-15:00:00-meeting", "/d/work/20260310-15:00:00-meeting.md"},
+		{"plain-note.md", "/d/journal/plain-note.md"},
+		{"ideas/meeting", ""},
+		{"missing", ""},
+	}
+
+	for _, tt := range tests {
+		l, _ := newLink(tt.target, 1)
+		got, ok := r.Resolve(l, fromJournal)
+		if tt.want == "" {
+			if ok {
+				t.Errorf("Resolve(%q) = %s, want unresolved", tt.target, got.Path)
+			}
+			continue
+		}
+		if !ok || got.Path != tt.want {
+			t.Errorf("Resolve(%q) = %s (%v), want %s", tt.target, got.Path, ok, tt.want)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	files := []storage.FileItem{
+		{Path: "/d/work/20260310-150000-meeting.md", Name: "20260310-150000-meeting.md", Collection: "work"},
+		{Path: "/d/journal/20260311-090000-meeting.md", Name: "20260311-090000-meeting.md", Collection: "journal"},
+	}
+	r := NewResolver(files)
+
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{"meeting", 2}, // The same slug in two collections
+		{"Meeting", 2},
+		{"work/meeting", 1},
+		{"20260311-090000-meeting", 1},
+		{"ideas/meeting", 0},
+	}
+	for _, tt := range tests {
+		l, _ := newLink(tt.target, 1)
+		if got := r.Matches(l); len(got) != tt.want {
+			t.Errorf("Matches(%q) = %d notes, want %d", tt.target, len(got), tt.want)
+		}
+	}
+}
+
+func TestBacklinksAndBroken(t *testing.T) {
+	dir := t.TempDir()
+	target := writeNote(t, dir, "work", "20260310-150000-meeting.md", "# Meeting\n")
+	a := writeNote(t, dir, "work", "20260311-100000-followup.md", "Follow up on [[meeting]]\n")
+	b := writeNote(t, dir, "journal", "20260312-100000-day.md", "Long day, see [[work/meeting]] and [[nowhere]]\n")
+	files := []storage.FileItem{target, a, b}
+
+	backlinks, err := Backlinks(storage.NewLocalStore(dir), files, target)
+	if err != nil {
+		t.Fatalf("Backlinks() error = %v", err)
+	}
+	if len(backlinks) != 2 {
+		t.Fatalf("Backlinks() returned %d references, want 2: %+v", len(backlinks), backlinks)
+	}
+
+	broken, err := Broken(storage.NewLocalStore(dir), files)
+	if err != nil {
+		t.Fatalf("Broken() error = %v", err)
+	}
+	if len(broken) != 1 || broken[0].Link.Target != "nowhere" || broken[0].Source.Path != b.Path {
+		t.Errorf("Broken() = %+v, want [[nowhere]] in journal note", broken)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	body := []byte("See [[meeting]], [[work/meeting#agenda]] and [[Meeting|the meeting]].\n" +
+		"Keep [[other]] and `[[meeting]]`.\n" +
+		"```\n[[meeting]]\n```\n")
+
+	got, n := Rewrite(body, func(l Link) string {
+		if l.Name == "meeting" || l.Name == "Meeting" {
+			return "ideas/kickoff"
+		}
+		return ""
+	})
+
+	want := "See [[ideas/kickoff]], [[ideas/kickoff#agenda]] and [[ideas/kickoff|the meeting]].\n" +
+		"Keep [[other]] and `[[meeting]]`.\n" +
+		"```\n[[meeting]]\n```\n"
+	if string(got) != want {
+		t.Errorf("Rewrite() =\n%s\nwant\n%s", got, want)
+	}
+	if n != 3 {
+		t.Errorf("Rewrite() rewrote %d links, want 3", n)
+	}
+}
+
+func TestRetarget(t *testing.T) {
+	dir := t.TempDir()
+	old := writeNote(t, dir, "inbox", "20260310-150000-meeting.md", "")
+	other := writeNote(t, dir, "work", "20260301-090000-meeting.md", "")
+	source := writeNote(t, dir, "work", "20260311-100000-notes.md", "")
+	r := NewResolver([]storage.FileItem{old, other, source})
+
+	// inbox/meeting moves to work, where a note already has that slug
+	moved := storage.FileItem{Collection: "work", Name: "20260310-150000-meeting.md"}
+	tests := []struct {
+		link string
+		from storage.FileItem
+		want string
+	}{
+		{"[[20260310-150000-meeting]]", source, "20260310-150000-meeting"},
+		{"[[inbox/meeting]]", source, "work/20260310-150000-meeting"},
+		{"[[meeting|standup]]", old, "work/20260310-150000-meeting"},
+	}
+	for _, tt := range tests {
+		l := Parse([]byte(tt.link))[0]
+		if got := r.Retarget(l, tt.from, old, moved); got != tt.want {
+			t.Errorf("Retarget(%s) = %q, want %q", tt.link, got, tt.want)
+		}
+		// The new target must not resolve to the note already in work
+		files := []storage.FileItem{{Path: "moved", Collection: moved.Collection, Name: moved.Name}, other, source}
+		l = Parse([]byte("[[" + tt.want + "]]"))[0]
+		if resolved, _ := NewResolver(files).Resolve(l, tt.from); resolved.Path != "moved" {
+			t.Errorf("%s resolves to %s after the move", tt.want, resolved.Path)
+		}
+	}
+
+	// With no other note sharing the slug, a slug link stays a slug
+	renamed := storage.FileItem{Collection: "inbox", Name: "20260310-150000-kickoff.md"}
+	l := Parse([]byte("[[meeting]]"))[0]
+	if got := r.Retarget(l, old, old, renamed); got != "kickoff" {
+		t.Errorf("Retarget() = %q, want kickoff", got)
+	}
+}
+
+func TestBacklinks_MemStore(t *testing.T) {
+	store := storage.NewMemStore()
+	store.Write("work", "20260310-150000-meeting.md", []byte("# Meeting\n"))
+	store.Write("journal", "20260312-100000-day.md", []byte("See [[work/meeting]]\n"))
+	files, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backlinks, err := Backlinks(store, files, files[1])
+	if err != nil || len(backlinks) != 1 || backlinks[0].Source.Name != "20260312-100000-day.md" {
+		t.Errorf("Backlinks() = %+v, %v", backlinks, err)
+	}
+}
@@ -0,0 +1,142 @@
+package note
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// SetTitle updates a note's title in its front matter and in its first
+// level-one heading outside fenced code blocks. A note with neither gets
+// a heading at the top of its body.
+func SetTitle(content []byte, title string) []byte {
+	updated := false
+	if HasFrontMatter(content) {
+		content, updated = SetField(content, "title", title)
+	}
+
+	header, body := splitHeader(content)
+	lines := strings.SplitAfter(string(body), "\n")
+	if i := headingLine(lines); i >= 0 {
+		newline := ""
+		if strings.HasSuffix(lines[i], "\n") {
+			newline = "\n"
+		}
+		lines[i] = "# " + title + newline
+		return append(header, strings.Join(lines, "")...)
+	}
+
+	if updated {
+		return content
+	}
+	return append(header, []byte("# "+title+"\n\n"+string(body))...)
+}
+
+// headingLine returns the index of the first level-one heading in lines,
+// skipping fenced code blocks where "# " starts a shell comment, or -1
+func headingLine(lines []string) int {
+	inFence := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if !inFence && strings.HasPrefix(line, "# ") {
+			return i
+		}
+	}
+	return -1
+}
+
+// SetField sets a top-level key of the note's front matter, replacing the
+// existing line or adding one before the closing delimiter. ok is false
+// if the note has no front matter.
+func SetField(content []byte, key string, value any) ([]byte, bool) {
+	content = bytes.TrimPrefix(content, []byte("\ufeff"))
+	format, raw, _, ok := split(content)
+	if !ok {
+		return content, false
+	}
+
+	var line []byte
+	var err error
+	var keyLine *regexp.Regexp
+	switch format {
+	case FormatYAML:
+		line, err = yaml.Marshal(map[string]any{key: value})
+		keyLine = regexp.MustCompile(`^` + regexp.QuoteMeta(key) + `\s*:`)
+	case FormatTOML:
+		line, err = toml.Marshal(map[string]any{key: value})
+		keyLine = regexp.MustCompile(`^` + regexp.QuoteMeta(key) + `\s*=`)
+	}
+	if err != nil {
+		return content, false
+	}
+
+	rawStart := bytes.IndexByte(content, '\n') + 1
+	rawEnd := rawStart + len(raw)
+
+	var out []string
+	replaced := false
+	skipping := false
+	for _, l := range strings.SplitAfter(string(raw), "\n") {
+		if skipping && (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")) {
+			continue // Continuation of the value being replaced
+		}
+		skipping = false
+		if !replaced && keyLine.MatchString(l) {
+			out = append(out, string(line))
+			replaced = true
+			skipping = format == FormatYAML
+			continue
+		}
+		out = append(out, l)
+	}
+	if !replaced {
+		if len(out) > 0 && !strings.HasSuffix(out[len(out)-1], "\n") && out[len(out)-1] != "" {
+			out = append(out, "\n")
+		}
+		out = append(out, string(line))
+	}
+
+	var buf bytes.Buffer
+	buf.Write(content[:rawStart])
+	buf.WriteString(strings.Join(out, ""))
+	buf.Write(content[rawEnd:])
+	return buf.Bytes(), true
+}
+
+// AppendEntry adds text to the end of content as a list item stamped with
+// at, e.g. "- 2026-10-17 09:30 idea". Further lines of text are indented
+// to stay in the item.
+func AppendEntry(content []byte, text string, at time.Time) []byte {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = "  " + lines[i]
+		}
+	}
+
+	content = bytes.Clone(content)
+	if len(content) > 0 && !bytes.HasSuffix(content, []byte("\n")) {
+		content = append(content, '\n')
+	}
+	entry := "- " + at.Format("2006-01-02 15:04") + " " + strings.Join(lines, "\n") + "\n"
+	return append(content, entry...)
+}
+
+// splitHeader splits content into its front matter block, delimiters
+// included, and the body.
+func splitHeader(content []byte) (header, body []byte) {
+	_, _, body, ok := split(content)
+	if !ok {
+		return nil, content
+	}
+	header = append([]byte(nil), content[:len(content)-len(body)]...)
+	return header, body
+}
@@ -0,0 +1,256 @@
+package note
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Front matter formats recognized by Parse
+const (
+	FormatNone = ""
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// timeLayouts are the timestamp formats accepted in front matter strings
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2/1/2006 15:04:05", // Format used by the old `## Created:` header
+}
+
+// Metadata is the front matter of a note
+type Metadata struct {
+	Title   string
+	Tags    []string
+	Created time.Time
+	Updated time.Time
+	Aliases []string
+	Fields  map[string]any // Every other key, as decoded
+	Format  string         // FormatYAML, FormatTOML or FormatNone
+}
+
+// Note is a parsed note file
+type Note struct {
+	Path string
+	Meta Metadata
+	Body []byte // Content after the front matter
+}
+
+// ReadFile reads and parses the note at path. A note with malformed front
+// matter is returned with empty metadata and its whole content as body,
+// along with the parse error.
+func ReadFile(path string) (*Note, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, body, err := Parse(content)
+	return &Note{Path: path, Meta: meta, Body: body}, err
+}
+
+// HasFrontMatter reports whether content starts with a front matter block
+func HasFrontMatter(content []byte) bool {
+	_, _, _, ok := split(content)
+	return ok
+}
+
+// Parse splits content into its front matter and body. Content without
+// front matter is returned unchanged as body with empty metadata.
+func Parse(content []byte) (Metadata, []byte, error) {
+	format, raw, body, ok := split(content)
+	if !ok {
+		return Metadata{Fields: map[string]any{}}, content, nil
+	}
+
+	fields := map[string]any{}
+	var err error
+	switch format {
+	case FormatYAML:
+		err = yaml.Unmarshal(raw, &fields)
+	case FormatTOML:
+		err = toml.Unmarshal(raw, &fields)
+	}
+	if err != nil {
+		return Metadata{Fields: map[string]any{}}, content, fmt.Errorf("invalid %s front matter: %w", format, err)
+	}
+	if fields == nil {
+		fields = map[string]any{}
+	}
+
+	meta := Metadata{Format: format}
+	meta.Title = asString(take(fields, "title"))
+	meta.Tags = asStrings(take(fields, "tags"), ", ")
+	// Aliases are titles, so a single one may hold spaces
+	meta.Aliases = asStrings(take(fields, "aliases"), ",")
+	meta.Created = asTime(take(fields, "created"))
+	meta.Updated = asTime(take(fields, "updated"))
+	if meta.Created.IsZero() {
+		meta.Created = asTime(take(fields, "date"))
+	}
+	meta.Fields = fields
+
+	return meta, body, nil
+}
+
+// Title returns the front matter title of a note, or else the text of the
+// first level-one heading of its body outside code blocks; empty when it
+// has neither
+func Title(meta Metadata, body []byte) string {
+	if meta.Title != "" {
+		return meta.Title
+	}
+	lines := strings.Split(string(body), "\n")
+	if i := headingLine(lines); i >= 0 {
+		return strings.TrimSpace(strings.TrimPrefix(lines[i], "# "))
+	}
+	return ""
+}
+
+// FrontMatter renders a YAML front matter block for a new note
+func FrontMatter(title, collection string, created time.Time) string {
+	header := struct {
+		Title      string   `yaml:"title"`
+		Collection string   `yaml:"collection"`
+		Created    string   `yaml:"created"`
+		Tags       []string `yaml:"tags"`
+	}{
+		Title:      title,
+		Collection: collection,
+		Created:    created.Format(time.RFC3339),
+		Tags:       []string{},
+	}
+
+	data, err := yaml.Marshal(header)
+	if err != nil {
+		// Marshalling a struct of strings can't fail
+		panic(err)
+	}
+
+	return "---\n" + string(data) + "---\n"
+}
+
+// split detects a front matter block delimited by "---" (YAML) or "+++"
+// (TOML) lines at the very start of content.
+func split(content []byte) (format string, raw, body []byte, ok bool) {
+	content = bytes.TrimPrefix(content, []byte("\ufeff"))
+
+	var delim string
+	switch {
+	case hasDelimLine(content, "---"):
+		format, delim = FormatYAML, "---"
+	case hasDelimLine(content, "+++"):
+		format, delim = FormatTOML, "+++"
+	default:
+		return FormatNone, nil, content, false
+	}
+
+	nl := bytes.IndexByte(content, '\n')
+	if nl < 0 {
+		return FormatNone, nil, content, false
+	}
+
+	rest := content[nl+1:]
+	offset := 0
+	for offset <= len(rest) {
+		end := bytes.IndexByte(rest[offset:], '\n')
+		line := rest[offset:]
+		next := len(rest)
+		if end >= 0 {
+			line = rest[offset : offset+end]
+			next = offset + end + 1
+		}
+
+		trimmed := strings.TrimRight(string(line), " \t\r")
+		if trimmed == delim || (format == FormatYAML && trimmed == "...") {
+			return format, rest[:offset], rest[next:], true
+		}
+
+		if end < 0 {
+			break
+		}
+		offset = next
+	}
+
+	return FormatNone, nil, content, false
+}
+
+func hasDelimLine(content []byte, delim string) bool {
+	line, _, _ := bytes.Cut(content, []byte("\n"))
+	return strings.TrimRight(string(line), " \t\r") == delim
+}
+
+// take removes key from fields and returns its value
+func take(fields map[string]any, key string) any {
+	v, ok := fields[key]
+	if !ok {
+		return nil
+	}
+	delete(fields, key)
+	return v
+}
+
+func asString(v any) string {
+	if v == nil {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprint(v))
+}
+
+// asStrings accepts a list or a string separated by any of the runes in
+// seps
+func asStrings(v any, seps string) []string {
+	var out []string
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case []any:
+		for _, item := range v {
+			if s := asString(item); s != "" {
+				out = append(out, s)
+			}
+		}
+	case []string:
+		for _, item := range v {
+			if s := strings.TrimSpace(item); s != "" {
+				out = append(out, s)
+			}
+		}
+	default:
+		for _, s := range strings.FieldsFunc(asString(v), func(r rune) bool {
+			return strings.ContainsRune(seps, r)
+		}) {
+			if s = strings.TrimSpace(s); s != "" {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+func asTime(v any) time.Time {
+	switch v := v.(type) {
+	case nil:
+		return time.Time{}
+	case time.Time:
+		return v
+	default:
+		s := asString(v)
+		for _, layout := range timeLayouts {
+			if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+				return t
+			}
+		}
+		return time.Time{}
+	}
+}
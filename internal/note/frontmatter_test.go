@@ -0,0 +1,278 @@
+package note
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseYAML(t *testing.T) {
+	content := []byte(`---
+title: "Weekly sync"
+tags: [work, meetings]
+aliases:
+  - standup
+created: 2026-03-10T15:00:00Z
+updated: 2026-03-11
+project: apollo
+---
+
+# Weekly sync
+`)
+
+	meta, body, err := Parse(content)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if meta.Format != FormatYAML {
+		t.Errorf("Format = %q, want yaml", meta.Format)
+	}
+	if meta.Title != "Weekly sync" {
+		t.Errorf("Title = %q", meta.Title)
+	}
+	if len(meta.Tags) != 2 || meta.Tags[0] != "work" || meta.Tags[1] != "meetings" {
+		t.Errorf("Tags = %v", meta.Tags)
+	}
+	if len(meta.Aliases) != 1 || meta.Aliases[0] != "standup" {
+		t.Errorf("Aliases = %v", meta.Aliases)
+	}
+	if !meta.Created.Equal(time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC)) {
+		t.Errorf("Created = %v", meta.Created)
+	}
+	if meta.Updated.Year() != 2026 || meta.Updated.Day() != 11 {
+		t.Errorf("Updated = %v", meta.Updated)
+	}
+	if meta.Fields["project"] != "apollo" {
+		t.Errorf("Fields = %v", meta.Fields)
+	}
+	if _, ok := meta.Fields["title"]; ok {
+		t.Error("known keys should not be repeated in Fields")
+	}
+	if string(body) != "\n# Weekly sync\n" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestParseTOML(t *testing.T) {
+	content := []byte(`+++
+title = "Plan"
+tags = "work, q3"
+created = 2026-01-02T10:00:00Z
++++
+Body
+`)
+
+	meta, body, err := Parse(content)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if meta.Format != FormatTOML || meta.Title != "Plan" {
+		t.Errorf("unexpected metadata %+v", meta)
+	}
+	if len(meta.Tags) != 2 || meta.Tags[1] != "q3" {
+		t.Errorf("Tags = %v", meta.Tags)
+	}
+	if meta.Created.IsZero() {
+		t.Error("Created should be parsed")
+	}
+	if string(body) != "Body\n" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestParseScalarAliases(t *testing.T) {
+	tests := []struct {
+		aliases string
+		want    []string
+	}{
+		{"Old Title", []string{"Old Title"}},
+		{"Old Title, Kickoff meeting", []string{"Old Title", "Kickoff meeting"}},
+		{"[Old Title, standup]", []string{"Old Title", "standup"}},
+	}
+	for _, tt := range tests {
+		meta, _, err := Parse([]byte("---\naliases: " + tt.aliases + "\ntags: work q3\n---\n"))
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.aliases, err)
+		}
+		if !slices.Equal(meta.Aliases, tt.want) {
+			t.Errorf("aliases: %s gives %q, want %q", tt.aliases, meta.Aliases, tt.want)
+		}
+		// Tags still split on spaces
+		if !slices.Equal(meta.Tags, []string{"work", "q3"}) {
+			t.Errorf("Tags = %q", meta.Tags)
+		}
+	}
+}
+
+func TestTitle(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"---\ntitle: Weekly sync\n---\n# Heading\n", "Weekly sync"},
+		{"---\ntags: [work]\n---\nIntro\n# Heading\n", "Heading"},
+		{"## Not a title\nBody\n", ""},
+		{"```sh\n# install\n```\n# Setup\n", "Setup"},
+	}
+	for _, tt := range tests {
+		meta, body, _ := Parse([]byte(tt.content))
+		if got := Title(meta, body); got != tt.want {
+			t.Errorf("Title(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestParseWithoutFrontMatter(t *testing.T) {
+	content := []byte("# Title\n\n---\n\ntext\n")
+
+	meta, body, err := Parse(content)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if meta.Format != FormatNone || meta.Title != "" {
+		t.Errorf("unexpected metadata %+v", meta)
+	}
+	if string(body) != string(content) {
+		t.Errorf("body should be the whole content, got %q", body)
+	}
+	if HasFrontMatter(content) {
+		t.Error("HasFrontMatter() = true, want false")
+	}
+}
+
+func TestParseUnterminated(t *testing.T) {
+	content := []byte("---\ntitle: x\n\nno closing delimiter\n")
+	if HasFrontMatter(content) {
+		t.Error("HasFrontMatter() = true for unterminated block")
+	}
+}
+
+func TestParseInvalidYAML(t *testing.T) {
+	content := []byte("---\ntitle: [unclosed\n---\nbody\n")
+	_, body, err := Parse(content)
+	if err == nil {
+		t.Fatal("Parse() expected error for invalid YAML")
+	}
+	if string(body) != string(content) {
+		t.Errorf("body should be the whole content on error, got %q", body)
+	}
+}
+
+func TestFrontMatterRoundTrip(t *testing.T) {
+	created := time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+	fm := FrontMatter(`Quotes: "and" colons`, "journal", created)
+
+	meta, body, err := Parse([]byte(fm + "\n# body\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if meta.Title != `Quotes: "and" colons` {
+		t.Errorf("Title = %q", meta.Title)
+	}
+	if !meta.Created.Equal(created) {
+		t.Errorf("Created = %v, want %v", meta.Created, created)
+	}
+	if meta.Fields["collection"] != "journal" {
+		t.Errorf("collection = %v", meta.Fields["collection"])
+	}
+	if len(meta.Tags) != 0 {
+		t.Errorf("Tags = %v, want none", meta.Tags)
+	}
+	if string(body) != "\n# body\n" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestSetTitle(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "front matter and heading",
+			content: "---\ntitle: Old\ntags: []\n---\n\n# Old\n\ntext\n",
+			want:    "---\ntitle: 'New: title'\ntags: []\n---\n\n# New: title\n\ntext\n",
+		},
+		{
+			name:    "heading only",
+			content: "intro\n# Old\n",
+			want:    "intro\n# New: title\n",
+		},
+		{
+			name:    "comment in a code block",
+			content: "```sh\n# install\nmake\n```\n# Old\n",
+			want:    "```sh\n# install\nmake\n```\n# New: title\n",
+		},
+		{
+			name:    "toml without title key",
+			content: "+++\ndraft = true\n+++\nbody\n",
+			want:    "+++\ndraft = true\ntitle = 'New: title'\n+++\nbody\n",
+		},
+		{
+			name:    "nothing to update",
+			content: "just text\n",
+			want:    "# New: title\n\njust text\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(SetTitle([]byte(tt.content), "New: title"))
+			if got != tt.want {
+				t.Errorf("SetTitle() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetFieldReplacesMultilineValue(t *testing.T) {
+	content := "---\ncollection: >\n  old\n  value\ntitle: x\n---\nbody\n"
+	got, ok := SetField([]byte(content), "collection", "work")
+	if !ok {
+		t.Fatal("SetField() ok = false")
+	}
+	want := "---\ncollection: work\ntitle: x\n---\nbody\n"
+	if string(got) != want {
+		t.Errorf("SetField() = %q, want %q", got, want)
+	}
+}
+
+func TestAppendEntry(t *testing.T) {
+	at := time.Date(2026, 10, 17, 9, 30, 0, 0, time.Local)
+	tests := []struct {
+		name    string
+		content string
+		text    string
+		want    string
+	}{
+		{
+			name:    "one line",
+			content: "# Inbox\n\n",
+			text:    "idea\n",
+			want:    "# Inbox\n\n- 2026-10-17 09:30 idea\n",
+		},
+		{
+			name:    "no trailing newline",
+			content: "# Inbox\n- older",
+			text:    "idea",
+			want:    "# Inbox\n- older\n- 2026-10-17 09:30 idea\n",
+		},
+		{
+			name:    "several lines",
+			content: "",
+			text:    "idea\n\ndetails\n",
+			want:    "- 2026-10-17 09:30 idea\n\n  details\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(AppendEntry([]byte(tt.content), tt.text, at))
+			if got != tt.want {
+				t.Errorf("AppendEntry() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
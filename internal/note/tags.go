@@ -0,0 +1,110 @@
+package note
+
+import (
+	"bufio"
+	"bytes"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Tags returns the note's front matter tags merged with the inline
+// #hashtags found in its body, normalized, deduplicated and sorted.
+func (n *Note) Tags() []string {
+	return MergeTags(n.Meta.Tags, InlineTags(n.Body))
+}
+
+// NormalizeTag lowercases a tag and strips its leading '#'
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+// MatchTag reports whether tag satisfies want. Tags are hierarchical, so
+// "work" also matches "work/meetings".
+func MatchTag(tag, want string) bool {
+	tag, want = NormalizeTag(tag), NormalizeTag(want)
+	return tag == want || strings.HasPrefix(tag, want+"/")
+}
+
+// MergeTags normalizes and deduplicates several tag lists into one sorted list
+func MergeTags(lists ...[]string) []string {
+	seen := map[string]bool{}
+	tags := []string{}
+	for _, list := range lists {
+		for _, tag := range list {
+			tag = NormalizeTag(tag)
+			if tag != "" && !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// InlineTags extracts #hashtags from a Markdown body. Headings ("# Title"),
+// fenced code blocks and inline code spans are ignored, as are tokens
+// without a letter such as "#1".
+func InlineTags(body []byte) []string {
+	var tags []string
+	inFence := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		tags = append(tags, lineTags(line)...)
+	}
+
+	return MergeTags(tags)
+}
+
+func lineTags(line string) []string {
+	var tags []string
+	runes := []rune(line)
+	inCode := false
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r == '`' {
+			inCode = !inCode
+			continue
+		}
+		if inCode || r != '#' {
+			continue
+		}
+		if i > 0 && !unicode.IsSpace(runes[i-1]) && runes[i-1] != '(' {
+			continue // Part of a word or URL fragment, e.g. "C#" or "page#anchor"
+		}
+
+		j := i + 1
+		hasLetter := false
+		for j < len(runes) && isTagRune(runes[j]) {
+			if unicode.IsLetter(runes[j]) {
+				hasLetter = true
+			}
+			j++
+		}
+
+		tag := strings.TrimRight(string(runes[i+1:j]), "/-")
+		if hasLetter && tag != "" {
+			tags = append(tags, tag)
+		}
+		i = j - 1
+	}
+
+	return tags
+}
+
+func isTagRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' || r == '/'
+}
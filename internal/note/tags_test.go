@@ -0,0 +1,52 @@
+package note
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInlineTags(t *testing.T) {
+	body := []byte("# Heading\n\n" +
+		"Planning for #Work and #project/apollo.\n" +
+		"Issue #12 is not a tag, nor is C# or page#anchor.\n" +
+		"Inline `#code` is skipped (#paren works).\n" +
+		"```\n#insidefence\n```\n" +
+		"## Sub heading #work\n")
+
+	got := InlineTags(body)
+	want := []string{"paren", "project/apollo", "work"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("InlineTags() = %v, want %v", got, want)
+	}
+}
+
+func TestNoteTagsMergesFrontMatter(t *testing.T) {
+	meta, body, err := Parse([]byte("---\ntags: [Work, '#ideas']\n---\nSee #ideas and #later\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := &Note{Meta: meta, Body: body}
+
+	want := []string{"ideas", "later", "work"}
+	if got := n.Tags(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Tags() = %v, want %v", got, want)
+	}
+}
+
+func TestMatchTag(t *testing.T) {
+	tests := []struct {
+		tag, want string
+		match     bool
+	}{
+		{"work", "work", true},
+		{"work/meetings", "work", true},
+		{"work", "#Work", true},
+		{"workshop", "work", false},
+		{"work", "work/meetings", false},
+	}
+	for _, tt := range tests {
+		if got := MatchTag(tt.tag, tt.want); got != tt.match {
+			t.Errorf("MatchTag(%q, %q) = %v, want %v", tt.tag, tt.want, got, tt.match)
+		}
+	}
+}
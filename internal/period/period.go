@@ -0,0 +1,80 @@
+package period
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gcaixeta/marginalia/internal/slug"
+	"github.com/gcaixeta/marginalia/internal/storage"
+)
+
+// Kind is the length of the period a periodic note covers
+type Kind string
+
+const (
+	Daily   Kind = "daily"
+	Weekly  Kind = "weekly"
+	Monthly Kind = "monthly"
+)
+
+// Start returns the first day of the period of k containing t, at
+// midnight. Weeks start on Monday, as ISO weeks do.
+func (k Kind) Start(t time.Time) time.Time {
+	y, m, d := t.Date()
+	switch k {
+	case Weekly:
+		// Monday is 0 days into the week, Sunday 6
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, m, d-offset, 0, 0, 0, 0, t.Location())
+	case Monthly:
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// Shift moves t by n periods of k, backwards when n is negative
+func (k Kind) Shift(t time.Time, n int) time.Time {
+	switch k {
+	case Weekly:
+		return t.AddDate(0, 0, 7*n)
+	case Monthly:
+		return t.AddDate(0, n, 0)
+	default:
+		return t.AddDate(0, 0, n)
+	}
+}
+
+// Title returns the title of the note for the period starting at start:
+// start formatted with the Go layout format, or by default 2026-10-17 for
+// days, 2026-W42 for weeks and 2026-10 for months
+func (k Kind) Title(start time.Time, format string) string {
+	if format != "" {
+		return start.Format(format)
+	}
+	switch k {
+	case Weekly:
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case Monthly:
+		return start.Format("2006-01")
+	default:
+		return start.Format("2006-01-02")
+	}
+}
+
+// Find returns the note of collection titled title, matched by the slug in
+// its file name, or nil when the period has no note yet
+func Find(collection, title string) (*storage.FileItem, error) {
+	files, err := storage.ListAllFiles()
+	if err != nil {
+		return nil, err
+	}
+	want := slug.MakeSlug(title)
+	for _, file := range storage.FilterByCollection(files, collection) {
+		if _, s, ok := slug.SplitName(file.Name); ok && s == want {
+			return &file, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,75 @@
+package period
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gcaixeta/marginalia/internal/storage"
+)
+
+func TestStartAndTitle(t *testing.T) {
+	// A Saturday, so the week starts on the 12th
+	now := time.Date(2026, 10, 17, 9, 30, 0, 0, time.Local)
+	tests := []struct {
+		kind  Kind
+		start time.Time
+		title string
+	}{
+		{Daily, time.Date(2026, 10, 17, 0, 0, 0, 0, time.Local), "2026-10-17"},
+		{Weekly, time.Date(2026, 10, 12, 0, 0, 0, 0, time.Local), "2026-W42"},
+		{Monthly, time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local), "2026-10"},
+	}
+	for _, tt := range tests {
+		start := tt.kind.Start(now)
+		if !start.Equal(tt.start) {
+			t.Errorf("%s: Start = %v, want %v", tt.kind, start, tt.start)
+		}
+		if got := tt.kind.Title(start, ""); got != tt.title {
+			t.Errorf("%s: Title = %q, want %q", tt.kind, got, tt.title)
+		}
+	}
+
+	if got := Daily.Title(now, "Monday 2 Jan"); got != "Saturday 17 Oct" {
+		t.Errorf("Title with a format = %q", got)
+	}
+	// Sunday belongs to the week that started the Monday before
+	sunday := time.Date(2026, 10, 18, 23, 0, 0, 0, time.Local)
+	if got := Weekly.Title(Weekly.Start(sunday), ""); got != "2026-W42" {
+		t.Errorf("Sunday is in %s, want 2026-W42", got)
+	}
+}
+
+func TestShift(t *testing.T) {
+	jan31 := time.Date(2026, 1, 31, 0, 0, 0, 0, time.Local)
+	if got := Daily.Shift(jan31, 1); got.Month() != time.February || got.Day() != 1 {
+		t.Errorf("Daily.Shift = %v", got)
+	}
+	if got := Weekly.Shift(jan31, -1); got.Day() != 24 {
+		t.Errorf("Weekly.Shift = %v", got)
+	}
+	// Months are shifted from their first day, so none is skipped
+	if got := Monthly.Start(Monthly.Shift(Monthly.Start(jan31), 1)); got.Month() != time.February {
+		t.Errorf("Monthly.Shift = %v", got)
+	}
+}
+
+func TestFind(t *testing.T) {
+	dataDir := t.TempDir()
+	storage.UseDataDir(dataDir)
+	t.Cleanup(func() { storage.UseDataDir("") })
+	os.MkdirAll(filepath.Join(dataDir, "journal"), 0755)
+	os.MkdirAll(filepath.Join(dataDir, "work"), 0755)
+	os.WriteFile(filepath.Join(dataDir, "journal", "20261017-08:00:00-2026-10-17.md"), []byte("# today\n"), 0644)
+	os.WriteFile(filepath.Join(dataDir, "journal", "20261017-08:00:00-2026-10-17-notes.md"), []byte("# notes\n"), 0644)
+	os.WriteFile(filepath.Join(dataDir, "work", "20261016-08:00:00-2026-10-16.md"), []byte("# work\n"), 0644)
+
+	file, err := Find("journal", "2026-10-17")
+	if err != nil || file == nil || file.Name != "20261017-08:00:00-2026-10-17.md" {
+		t.Errorf("Find = %+v, %v", file, err)
+	}
+	if file, err := Find("journal", "2026-10-16"); err != nil || file != nil {
+		t.Errorf("Find in another collection = %+v, %v", file, err)
+	}
+}
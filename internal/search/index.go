@@ -0,0 +1,337 @@
+package search
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gcaixeta/marginalia/internal/note"
+	"github.com/gcaixeta/marginalia/internal/storage"
+)
+
+// IndexDir is the directory, relative to the data dir, where the index is
+// stored. It starts with a dot so ListAllFiles and FindFilePath skip it.
+const IndexDir = ".index"
+
+const (
+	indexFile    = "search.json"
+	indexVersion = 2
+)
+
+// docEntry is what the index remembers about a single note
+type docEntry struct {
+	Collection string    `json:"collection"`
+	Name       string    `json:"name"`
+	ModTime    time.Time `json:"mtime"`
+	Size       int64     `json:"size"`
+	Terms      []string  `json:"terms"`
+	Tags       []string  `json:"tags"`
+}
+
+// Index is an inverted index of note contents persisted under IndexDir.
+// Documents are keyed by their path relative to the data dir.
+type Index struct {
+	Version  int                       `json:"version"`
+	Docs     map[string]*docEntry      `json:"docs"`
+	Postings map[string]map[string]int `json:"postings"` // term -> doc -> frequency
+
+	root string
+}
+
+// OpenIndex loads the index stored in dataDir, or returns an empty one if
+// it doesn't exist yet or was written by an incompatible version.
+func OpenIndex(dataDir string) (*Index, error) {
+	ix := newIndex(dataDir)
+
+	data, err := os.ReadFile(filepath.Join(dataDir, IndexDir, indexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return ix, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var loaded Index
+	if err := json.Unmarshal(data, &loaded); err != nil || loaded.Version != indexVersion {
+		return ix, nil // Corrupt or outdated: start over, Update will refill it
+	}
+	if loaded.Docs != nil {
+		ix.Docs = loaded.Docs
+	}
+	if loaded.Postings != nil {
+		ix.Postings = loaded.Postings
+	}
+	return ix, nil
+}
+
+func newIndex(dataDir string) *Index {
+	return &Index{
+		Version:  indexVersion,
+		Docs:     map[string]*docEntry{},
+		Postings: map[string]map[string]int{},
+		root:     dataDir,
+	}
+}
+
+// Refresh opens the index in dataDir, updates it with files read from
+// store and saves it if anything changed.
+func Refresh(dataDir string, store storage.NoteStore, files []storage.FileItem) (*Index, error) {
+	ix, err := OpenIndex(dataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	changed, err := ix.Update(store, files)
+	if err != nil {
+		return nil, err
+	}
+	if changed > 0 {
+		if err := ix.Save(); err != nil {
+			return nil, err
+		}
+	}
+	return ix, nil
+}
+
+// Update brings the index in line with files, re-reading from store only
+// the notes whose mtime or size changed and dropping notes that no longer
+// exist. It returns the number of documents added, updated or removed.
+func (ix *Index) Update(store storage.NoteStore, files []storage.FileItem) (int, error) {
+	changed := 0
+	seen := make(map[string]bool, len(files))
+
+	for _, file := range files {
+		key := ix.key(file)
+		seen[key] = true
+
+		if doc, ok := ix.Docs[key]; ok && doc.ModTime.Equal(file.ModTime) && doc.Size == file.Size {
+			continue
+		}
+
+		content, err := store.Read(file.Collection, file.Name)
+		if err != nil {
+			continue // Skip unreadable notes, they'll be retried next time
+		}
+		ix.remove(key)
+		ix.add(key, file, content)
+		changed++
+	}
+
+	for key := range ix.Docs {
+		if !seen[key] {
+			ix.remove(key)
+			changed++
+		}
+	}
+
+	return changed, nil
+}
+
+// Rebuild discards the index contents and indexes files from scratch
+func (ix *Index) Rebuild(store storage.NoteStore, files []storage.FileItem) (int, error) {
+	ix.Docs = map[string]*docEntry{}
+	ix.Postings = map[string]map[string]int{}
+	return ix.Update(store, files)
+}
+
+// Save writes the index to disk atomically. The index directory gets its
+// own .gitignore so the git backup never commits it.
+func (ix *Index) Save() error {
+	dir := filepath.Join(ix.root, IndexDir)
+	if err := storage.EnsureDir(dir); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("*\n"), 0644); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(ix)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, indexFile+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, indexFile))
+}
+
+// Len returns the number of indexed documents
+func (ix *Index) Len() int {
+	return len(ix.Docs)
+}
+
+// Candidates returns the keys of documents that contain every query term.
+// Like Search, a term matches any indexed word it is a substring of.
+func (ix *Index) Candidates(terms []string) map[string]bool {
+	var result map[string]bool
+
+	for _, term := range terms {
+		docs := map[string]bool{}
+		for word, postings := range ix.Postings {
+			if !strings.Contains(word, term) {
+				continue
+			}
+			for key := range postings {
+				if result == nil || result[key] {
+					docs[key] = true
+				}
+			}
+		}
+		result = docs
+		if len(result) == 0 {
+			break
+		}
+	}
+
+	if result == nil {
+		result = map[string]bool{}
+	}
+	return result
+}
+
+// Filter returns the files whose indexed content contains every term of query
+func (ix *Index) Filter(files []storage.FileItem, query string) []storage.FileItem {
+	candidates := ix.Candidates(Terms(query))
+
+	filtered := []storage.FileItem{}
+	for _, file := range files {
+		if candidates[ix.key(file)] {
+			filtered = append(filtered, file)
+		}
+	}
+	return filtered
+}
+
+// FilterTags returns the files tagged with every one of tags
+func (ix *Index) FilterTags(files []storage.FileItem, tags []string) []storage.FileItem {
+	if len(tags) == 0 {
+		return files
+	}
+
+	filtered := []storage.FileItem{}
+	for _, file := range files {
+		if ix.hasTags(ix.key(file), tags) {
+			filtered = append(filtered, file)
+		}
+	}
+	return filtered
+}
+
+// TagCounts returns how many notes carry each tag
+func (ix *Index) TagCounts() map[string]int {
+	counts := map[string]int{}
+	for _, doc := range ix.Docs {
+		for _, tag := range doc.Tags {
+			counts[tag]++
+		}
+	}
+	return counts
+}
+
+// Tags returns the tags of an indexed file
+func (ix *Index) Tags(file storage.FileItem) []string {
+	if doc, ok := ix.Docs[ix.key(file)]; ok {
+		return doc.Tags
+	}
+	return nil
+}
+
+func (ix *Index) hasTags(key string, tags []string) bool {
+	doc, ok := ix.Docs[key]
+	if !ok {
+		return false
+	}
+	for _, want := range tags {
+		found := false
+		for _, tag := range doc.Tags {
+			if note.MatchTag(tag, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Search runs a content search restricted to the notes the index says can
+// match, so only those files are read from store.
+func (ix *Index) Search(store storage.NoteStore, files []storage.FileItem, query string, opts Options) ([]Result, error) {
+	return Search(store, ix.Filter(files, query), query, opts)
+}
+
+func (ix *Index) key(file storage.FileItem) string {
+	rel, err := filepath.Rel(ix.root, file.Path)
+	if err != nil {
+		return file.Path
+	}
+	return filepath.ToSlash(rel)
+}
+
+func (ix *Index) add(key string, file storage.FileItem, content []byte) {
+	freqs := tokenize(string(content))
+	for word, n := range tokenize(strings.TrimSuffix(file.Name, filepath.Ext(file.Name))) {
+		freqs[word] += n
+	}
+
+	terms := make([]string, 0, len(freqs))
+	for word, n := range freqs {
+		postings, ok := ix.Postings[word]
+		if !ok {
+			postings = map[string]int{}
+			ix.Postings[word] = postings
+		}
+		postings[key] = n
+		terms = append(terms, word)
+	}
+
+	meta, body, _ := note.Parse(content)
+
+	ix.Docs[key] = &docEntry{
+		Collection: file.Collection,
+		Name:       file.Name,
+		ModTime:    file.ModTime,
+		Size:       file.Size,
+		Terms:      terms,
+		Tags:       note.MergeTags(meta.Tags, note.InlineTags(body)),
+	}
+}
+
+func (ix *Index) remove(key string) {
+	doc, ok := ix.Docs[key]
+	if !ok {
+		return
+	}
+	for _, word := range doc.Terms {
+		delete(ix.Postings[word], key)
+		if len(ix.Postings[word]) == 0 {
+			delete(ix.Postings, word)
+		}
+	}
+	delete(ix.Docs, key)
+}
+
+// tokenize returns the frequency of each normalized word in s
+func tokenize(s string) map[string]int {
+	freqs := map[string]int{}
+	for _, w := range words(s) {
+		freqs[w]++
+	}
+	return freqs
+}
@@ -0,0 +1,129 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gcaixeta/marginalia/internal/storage"
+)
+
+func TestIndexUpdateIsIncremental(t *testing.T) {
+	dir := t.TempDir()
+	a := writeNote(t, dir, "work", "a.md", "# Alpha\n\nquarterly budget\n")
+	b := writeNote(t, dir, "work", "b.md", "# Beta\n\nroadmap\n")
+
+	ix, err := OpenIndex(dir)
+	if err != nil {
+		t.Fatalf("OpenIndex() error = %v", err)
+	}
+	if n, _ := ix.Update(storage.NewLocalStore(dir), []storage.FileItem{a, b}); n != 2 {
+		t.Fatalf("first Update() changed %d docs, want 2", n)
+	}
+	if n, _ := ix.Update(storage.NewLocalStore(dir), []storage.FileItem{a, b}); n != 0 {
+		t.Fatalf("second Update() changed %d docs, want 0", n)
+	}
+
+	// Rewrite b with new content and a different mtime.
+	b = writeNote(t, dir, "work", "b.md", "# Beta\n\nbudget roadmap\n")
+	b.ModTime = b.ModTime.Add(time.Second)
+	if n, _ := ix.Update(storage.NewLocalStore(dir), []storage.FileItem{a, b}); n != 1 {
+		t.Fatalf("Update() after edit changed %d docs, want 1", n)
+	}
+	if got := ix.Candidates(Terms("budget")); len(got) != 2 {
+		t.Errorf("Candidates(budget) = %v, want both notes", got)
+	}
+
+	// Dropping a note from the file list removes it from the index.
+	if n, _ := ix.Update(storage.NewLocalStore(dir), []storage.FileItem{b}); n != 1 {
+		t.Fatalf("Update() after delete changed %d docs, want 1", n)
+	}
+	if got := ix.Candidates(Terms("quarterly")); len(got) != 0 {
+		t.Errorf("Candidates(quarterly) = %v, want none", got)
+	}
+	if ix.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", ix.Len())
+	}
+}
+
+func TestIndexSaveAndReopen(t *testing.T) {
+	dir := t.TempDir()
+	a := writeNote(t, dir, "work", "meeting-notes.md", "Discussed the launch.\n")
+
+	ix, err := Refresh(dir, storage.NewLocalStore(dir), []storage.FileItem{a})
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if ix.Len() != 1 {
+		t.Fatalf("Len() = %d, want 1", ix.Len())
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, IndexDir, ".gitignore")); err != nil {
+		t.Errorf("expected .gitignore in index dir: %v", err)
+	}
+
+	reopened, err := OpenIndex(dir)
+	if err != nil {
+		t.Fatalf("OpenIndex() error = %v", err)
+	}
+	if n, _ := reopened.Update(storage.NewLocalStore(dir), []storage.FileItem{a}); n != 0 {
+		t.Errorf("Update() on reopened index changed %d docs, want 0", n)
+	}
+
+	// File name words are indexed too, so name-only hits are candidates.
+	results, err := reopened.Search(storage.NewLocalStore(dir), []storage.FileItem{a}, "meeting launch", Options{})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Search() returned %d results, want 1", len(results))
+	}
+}
+
+func TestIndexCandidatesMatchSubstrings(t *testing.T) {
+	dir := t.TempDir()
+	a := writeNote(t, dir, "work", "a.md", "Reunião de planejamento\n")
+
+	ix, err := OpenIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ix.Update(storage.NewLocalStore(dir), []storage.FileItem{a})
+
+	if got := ix.Candidates(Terms("reuni plan")); !got["work/a.md"] {
+		t.Errorf("Candidates() = %v, want work/a.md", got)
+	}
+	if got := ix.Candidates(Terms("reuni missing")); len(got) != 0 {
+		t.Errorf("Candidates() = %v, want none", got)
+	}
+}
+
+func TestIndexTags(t *testing.T) {
+	dir := t.TempDir()
+	a := writeNote(t, dir, "work", "a.md", "---\ntags: [work]\n---\nPlanning #work/meetings\n")
+	b := writeNote(t, dir, "journal", "b.md", "Went for a run #health #work\n")
+	c := writeNote(t, dir, "journal", "c.md", "Nothing tagged\n")
+	files := []storage.FileItem{a, b, c}
+
+	ix, err := OpenIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ix.Update(storage.NewLocalStore(dir), files)
+
+	counts := ix.TagCounts()
+	if counts["work"] != 2 || counts["work/meetings"] != 1 || counts["health"] != 1 {
+		t.Errorf("TagCounts() = %v", counts)
+	}
+
+	if got := ix.FilterTags(files, []string{"work"}); len(got) != 2 {
+		t.Errorf("FilterTags(work) returned %d files, want 2", len(got))
+	}
+	if got := ix.FilterTags(files, []string{"#work", "health"}); len(got) != 1 || got[0].Name != "b.md" {
+		t.Errorf("FilterTags(work, health) = %v, want b.md", got)
+	}
+	if got := ix.FilterTags(files, nil); len(got) != 3 {
+		t.Errorf("FilterTags(nil) returned %d files, want all", len(got))
+	}
+}
@@ -0,0 +1,180 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gcaixeta/marginalia/internal/note"
+	"github.com/gcaixeta/marginalia/internal/storage"
+	"golang.org/x/text/unicode/norm"
+)
+
+// titleWeight is how much a term found in the title counts compared to
+// a single occurrence in the body.
+const titleWeight = 5
+
+// Options controls how results are collected
+type Options struct {
+	Context int // Lines of context around each matching line
+	Limit   int // Maximum number of results (0 means no limit)
+}
+
+// Match is a line of a note that contains at least one query term
+type Match struct {
+	Line   int      // 1-based line number
+	Text   string   // The matching line
+	Before []string // Context lines before the match
+	After  []string // Context lines after the match
+}
+
+// Result is a note that matched every query term
+type Result struct {
+	File    storage.FileItem
+	Title   string
+	Score   int
+	Matches []Match
+}
+
+// Terms splits a query into normalized search terms
+func Terms(query string) []string {
+	seen := map[string]bool{}
+	terms := []string{}
+	for _, f := range words(query) {
+		if !seen[f] {
+			seen[f] = true
+			terms = append(terms, f)
+		}
+	}
+	return terms
+}
+
+// words splits s into normalized words
+func words(s string) []string {
+	return strings.FieldsFunc(Normalize(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Normalize lowercases s and strips diacritics so "Reunião" matches "reuniao"
+func Normalize(s string) string {
+	t := norm.NFD.String(strings.ToLower(s))
+
+	var b strings.Builder
+	for _, r := range t {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Search reads files from store and returns the notes containing every
+// term of query, best matches first.
+func Search(store storage.NoteStore, files []storage.FileItem, query string, opts Options) ([]Result, error) {
+	terms := Terms(query)
+	results := []Result{}
+	if len(terms) == 0 {
+		return results, nil
+	}
+
+	for _, file := range files {
+		content, err := store.Read(file.Collection, file.Name)
+		if err != nil {
+			continue // Skip notes that disappeared or can't be read
+		}
+
+		if result, ok := searchContent(file, content, terms, opts.Context); ok {
+			results = append(results, result)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].File.ModTime.After(results[j].File.ModTime)
+	})
+
+	if opts.Limit > 0 && len(results) > opts.Limit {
+		results = results[:opts.Limit]
+	}
+
+	return results, nil
+}
+
+// searchContent scores a single note. ok is false when any term is missing
+// from both the title and the body.
+func searchContent(file storage.FileItem, content []byte, terms []string, context int) (Result, bool) {
+	context = max(context, 0)
+	lines := splitLines(content)
+	meta, body, _ := note.Parse(content)
+	title := Title(file, meta, body)
+	normTitle := Normalize(title)
+
+	counts := make([]int, len(terms))
+	score := 0
+	for i, term := range terms {
+		if strings.Contains(normTitle, term) {
+			counts[i]++
+			score += titleWeight
+		}
+	}
+
+	var matches []Match
+	for n, line := range lines {
+		normLine := Normalize(line)
+		hit := false
+		for i, term := range terms {
+			if c := strings.Count(normLine, term); c > 0 {
+				counts[i] += c
+				score += c
+				hit = true
+			}
+		}
+		if hit {
+			matches = append(matches, Match{
+				Line:   n + 1,
+				Text:   line,
+				Before: lines[max(0, n-context):n],
+				After:  lines[n+1 : min(len(lines), n+1+context)],
+			})
+		}
+	}
+
+	for _, c := range counts {
+		if c == 0 {
+			return Result{}, false
+		}
+	}
+
+	return Result{
+		File:    file,
+		Title:   title,
+		Score:   score,
+		Matches: matches,
+	}, true
+}
+
+// Title returns the front matter title of a note, or its first level-one
+// heading, falling back to its file name without extension.
+func Title(file storage.FileItem, meta note.Metadata, body []byte) string {
+	if title := note.Title(meta, body); title != "" {
+		return title
+	}
+	return strings.TrimSuffix(file.Name, filepath.Ext(file.Name))
+}
+
+func splitLines(content []byte) []string {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
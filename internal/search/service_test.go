@@ -0,0 +1,139 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gcaixeta/marginalia/internal/storage"
+)
+
+func writeNote(t *testing.T, dir, collection, name, content string) storage.FileItem {
+	t.Helper()
+	path := filepath.Join(dir, collection, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return storage.FileItem{
+		Path:       path,
+		Name:       name,
+		Collection: collection,
+		ModTime:    info.ModTime(),
+		Size:       info.Size(),
+	}
+}
+
+func TestTerms(t *testing.T) {
+	got := Terms("Reunião de  PLANEJAMENTO, reunião")
+	want := []string{"reuniao", "de", "planejamento"}
+	if len(got) != len(want) {
+		t.Fatalf("Terms() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Terms()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSearchRanksTitleAndFrequency(t *testing.T) {
+	dir := t.TempDir()
+	files := []storage.FileItem{
+		writeNote(t, dir, "work", "a.md", "# Weekly sync\n\nWe talked about the budget.\n"),
+		writeNote(t, dir, "work", "b.md", "# Budget review\n\nBudget for Q3.\nMore budget talk.\n"),
+		writeNote(t, dir, "journal", "c.md", "# Today\n\nNothing relevant.\n"),
+	}
+
+	results, err := Search(storage.NewLocalStore(dir), files, "budget", Options{})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Search() returned %d results, want 2", len(results))
+	}
+	if results[0].File.Name != "b.md" {
+		t.Errorf("expected b.md to rank first, got %s", results[0].File.Name)
+	}
+	if results[0].Title != "Budget review" {
+		t.Errorf("unexpected title %q", results[0].Title)
+	}
+}
+
+func TestSearchRequiresAllTerms(t *testing.T) {
+	dir := t.TempDir()
+	files := []storage.FileItem{
+		writeNote(t, dir, "work", "a.md", "alpha beta\n"),
+		writeNote(t, dir, "work", "b.md", "alpha only\n"),
+	}
+
+	results, err := Search(storage.NewLocalStore(dir), files, "alpha beta", Options{})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 1 || results[0].File.Name != "a.md" {
+		t.Fatalf("Search() = %v, want only a.md", results)
+	}
+}
+
+func TestSearchContext(t *testing.T) {
+	dir := t.TempDir()
+	files := []storage.FileItem{
+		writeNote(t, dir, "work", "a.md", "one\ntwo\nneedle\nfour\nfive\n"),
+	}
+
+	results, err := Search(storage.NewLocalStore(dir), files, "needle", Options{Context: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 1 || len(results[0].Matches) != 1 {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+
+	m := results[0].Matches[0]
+	if m.Line != 3 || m.Text != "needle" {
+		t.Errorf("unexpected match %+v", m)
+	}
+	if len(m.Before) != 1 || m.Before[0] != "two" {
+		t.Errorf("unexpected before context %v", m.Before)
+	}
+	if len(m.After) != 1 || m.After[0] != "four" {
+		t.Errorf("unexpected after context %v", m.After)
+	}
+}
+
+func TestSearchNegativeContext(t *testing.T) {
+	dir := t.TempDir()
+	files := []storage.FileItem{
+		writeNote(t, dir, "work", "a.md", "one\nneedle\nthree\n"),
+	}
+
+	results, err := Search(storage.NewLocalStore(dir), files, "needle", Options{Context: -1})
+	if err != nil || len(results) != 1 || len(results[0].Matches) != 1 {
+		t.Fatalf("Search() = %+v, %v", results, err)
+	}
+	if m := results[0].Matches[0]; len(m.Before) != 0 || len(m.After) != 0 {
+		t.Errorf("negative context returned %v / %v", m.Before, m.After)
+	}
+}
+
+func TestSearchMemStore(t *testing.T) {
+	store := storage.NewMemStore()
+	store.Write("work", "a.md", []byte("# Plan\n\nThe budget for Q3.\n"))
+	store.Write("work", "b.md", []byte("# Other\n\nNothing here.\n"))
+	files, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := Search(store, files, "budget", Options{})
+	if err != nil || len(results) != 1 || results[0].File.Name != "a.md" {
+		t.Fatalf("Search() = %+v, %v, want a.md", results, err)
+	}
+}
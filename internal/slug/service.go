@@ -41,3 +41,40 @@ func MdSlugWithTime(title string) string {
 		MakeSlug(title),
 	)
 }
+
+// timestampLayouts are the file name prefixes written by MdSlugWithTime,
+// current format first.
+var timestampLayouts = []string{
+	"20060102-15:04:05",
+	"20060102-150405",
+}
+
+// SplitName splits a note file name such as "20260310-150000-meeting.md"
+// into its creation time and slug. ok is false when the name has no
+// timestamp prefix, in which case slug is the name without extension.
+func SplitName(name string) (created time.Time, slug string, ok bool) {
+	base := strings.TrimSuffix(name, ".md")
+
+	for _, layout := range timestampLayouts {
+		if len(base) <= len(layout) || base[len(layout)] != '-' {
+			continue
+		}
+		t, err := time.ParseInLocation(layout, base[:len(layout)], time.Local)
+		if err != nil {
+			continue
+		}
+		return t, base[len(layout)+1:], true
+	}
+
+	return time.Time{}, base, false
+}
+
+// Retitle returns the file name of a note renamed to title, keeping the
+// timestamp prefix of name so its creation time is preserved.
+func Retitle(name, title string) string {
+	base := strings.TrimSuffix(name, ".md")
+	if _, s, ok := SplitName(name); ok {
+		return base[:len(base)-len(s)] + MakeSlug(title) + ".md"
+	}
+	return MakeSlug(title) + ".md"
+}
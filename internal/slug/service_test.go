@@ -0,0 +1,50 @@
+package slug
+
+import "testing"
+
+func TestSplitName(t *testing.T) {
+	tests := []struct {
+		name   string
+		slug   string
+		ok     bool
+		minute int
+	}{
+		{"20260310-15:04:05-meeting-notes.md", "meeting-notes", true, 4},
+		{"20260310-150405-meeting-notes.md", "meeting-notes", true, 4},
+		{"meeting-notes.md", "meeting-notes", false, 0},
+		{"20260310-meeting.md", "20260310-meeting", false, 0},
+	}
+
+	for _, tt := range tests {
+		created, slug, ok := SplitName(tt.name)
+		if slug != tt.slug || ok != tt.ok {
+			t.Errorf("SplitName(%q) = %q, %v; want %q, %v", tt.name, slug, ok, tt.slug, tt.ok)
+		}
+		if ok && created.Minute() != tt.minute {
+			t.Errorf("SplitName(%q) time = %v", tt.name, created)
+		}
+	}
+}
+
+func TestSplitNameRoundTrip(t *testing.T) {
+	name := MdSlugWithTime("Reunião de Planejamento")
+	_, slug, ok := SplitName(name)
+	if !ok || slug != "reuniao-de-planejamento" {
+		t.Errorf("SplitName(%q) = %q, %v", name, slug, ok)
+	}
+}
+
+func TestRetitle(t *testing.T) {
+	tests := []struct {
+		name, title, want string
+	}{
+		{"20260310-15:04:05-old-title.md", "New Título", "20260310-15:04:05-new-titulo.md"},
+		{"20260310-150405-old.md", "Renamed", "20260310-150405-renamed.md"},
+		{"plain.md", "Renamed", "renamed.md"},
+	}
+	for _, tt := range tests {
+		if got := Retitle(tt.name, tt.title); got != tt.want {
+			t.Errorf("Retitle(%q, %q) = %q, want %q", tt.name, tt.title, got, tt.want)
+		}
+	}
+}
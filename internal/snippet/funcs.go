@@ -0,0 +1,80 @@
+package snippet
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gcaixeta/marginalia/internal/slug"
+	"gopkg.in/yaml.v3"
+)
+
+// funcs returns the functions templates can call. now is the time the
+// note is created at, so every function agrees on it.
+func funcs(now time.Time) template.FuncMap {
+	return template.FuncMap{
+		"now": func() time.Time { return now },
+		"today": func() time.Time {
+			y, m, d := now.Date()
+			return time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+		},
+		"date":    formatDate,
+		"addDays": addDays,
+		"slug":    slug.MakeSlug,
+		"env":     os.Getenv,
+		"uuid":    newUUID,
+		"yaml":    yamlScalar,
+	}
+}
+
+// yamlScalar quotes s when it needs it to be read back as the same YAML
+// string: title: {{ .Title | yaml }}
+func yamlScalar(s string) (string, error) {
+	out, err := yaml.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// formatDate formats t, a time or an RFC3339 string such as .Date, with a
+// Go layout: {{ now | date "2006-01-02" }}
+func formatDate(layout string, t any) (string, error) {
+	tt, err := toTime(t)
+	if err != nil {
+		return "", err
+	}
+	return tt.Format(layout), nil
+}
+
+// addDays moves t by n days: {{ today | addDays -1 | date "Monday" }}
+func addDays(n int, t any) (time.Time, error) {
+	tt, err := toTime(t)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return tt.AddDate(0, 0, n), nil
+}
+
+func toTime(t any) (time.Time, error) {
+	switch v := t.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		return time.Parse(time.RFC3339, v)
+	default:
+		return time.Time{}, fmt.Errorf("expected a time, got %T", t)
+	}
+}
+
+// newUUID returns a random (version 4) UUID
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,96 @@
+package snippet
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Prompt is a value a template asks for before the note is created,
+// exposed to the template as {{ .Values.<name> }}
+type Prompt struct {
+	Name    string   `yaml:"name"`
+	Label   string   `yaml:"label"`   // Shown in the form, defaults to Name
+	Default string   `yaml:"default"` // Used when nothing is entered
+	Choices []string `yaml:"choices"` // When set, the value must be one of them
+}
+
+// Check reports whether value is acceptable for p
+func (p Prompt) Check(value string) error {
+	if len(p.Choices) > 0 && !slices.Contains(p.Choices, value) {
+		return fmt.Errorf("%s must be one of %s, got %q", p.Name, strings.Join(p.Choices, ", "), value)
+	}
+	return nil
+}
+
+// Initial returns the value p starts with: its default, or the first
+// choice when it has none
+func (p Prompt) Initial() string {
+	if p.Default == "" && len(p.Choices) > 0 {
+		return p.Choices[0]
+	}
+	return p.Default
+}
+
+// promptsHeader matches the block declaring the prompts at the top of a
+// template. It is a template comment, so the file stays a valid template:
+//
+//	{{/* prompts
+//	- name: attendees
+//	  label: Who attended?
+//	*/}}
+var promptsHeader = regexp.MustCompile(`^\s*\{\{-?\s*/\*\s*prompts\s*\n((?s:.*?))\*/\s*-?\}\}[ \t]*\r?\n?`)
+
+// splitPrompts separates the prompts header of a template from its body
+func splitPrompts(content string) ([]Prompt, string, error) {
+	m := promptsHeader.FindStringSubmatchIndex(content)
+	if m == nil {
+		return nil, content, nil
+	}
+
+	var prompts []Prompt
+	if err := yaml.Unmarshal([]byte(content[m[2]:m[3]]), &prompts); err != nil {
+		return nil, "", fmt.Errorf("error parsing template prompts: %w", err)
+	}
+	seen := make(map[string]bool)
+	for i, p := range prompts {
+		if p.Name == "" {
+			return nil, "", fmt.Errorf("template prompt %d has no name", i+1)
+		}
+		if seen[p.Name] {
+			return nil, "", fmt.Errorf("template prompt %s is declared twice", p.Name)
+		}
+		seen[p.Name] = true
+		if p.Label == "" {
+			prompts[i].Label = p.Name
+		}
+		if p.Default != "" {
+			if err := p.Check(p.Default); err != nil {
+				return nil, "", fmt.Errorf("template prompt default: %w", err)
+			}
+		}
+	}
+	return prompts, content[m[1]:], nil
+}
+
+// Prompts returns the prompts declared by the template of collection, none
+// when the collection has no template
+func Prompts(collection string) ([]Prompt, error) {
+	path, err := Path(collection)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	prompts, _, err := splitPrompts(string(content))
+	return prompts, err
+}
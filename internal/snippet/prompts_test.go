@@ -0,0 +1,76 @@
+package snippet
+
+import (
+	"testing"
+	"time"
+)
+
+const meetingTemplate = `{{/* prompts
+- name: attendees
+  label: Who attended?
+  default: the team
+- name: kind
+  choices: [standup, retro]
+*/}}
+---
+title: {{ .Title }}
+---
+Attendees: {{ .Values.attendees }}
+Kind: {{ .Values.kind }}
+Project: {{ .Values.project }}
+`
+
+func TestPrompts(t *testing.T) {
+	useDirs(t, map[string]string{"meetings.md": meetingTemplate})
+
+	prompts, err := Prompts("meetings")
+	if err != nil {
+		t.Fatalf("Prompts: %v", err)
+	}
+	if len(prompts) != 2 {
+		t.Fatalf("got %d prompts, want 2: %+v", len(prompts), prompts)
+	}
+	if p := prompts[0]; p.Name != "attendees" || p.Label != "Who attended?" || p.Initial() != "the team" {
+		t.Errorf("first prompt = %+v", p)
+	}
+	if p := prompts[1]; p.Label != "kind" || p.Initial() != "standup" {
+		t.Errorf("second prompt = %+v, want its name as label and the first choice", p)
+	}
+	if err := prompts[1].Check("planning"); err == nil {
+		t.Error("Check accepted a value outside the choices")
+	}
+
+	if prompts, err := Prompts("none"); err != nil || prompts != nil {
+		t.Errorf("Prompts without a template = %v, %v", prompts, err)
+	}
+}
+
+func TestPrompts_Invalid(t *testing.T) {
+	for name, header := range map[string]string{
+		"no name":   "- label: Who?\n",
+		"twice":     "- name: a\n- name: a\n",
+		"bad yaml":  "- name: [a\n",
+		"bad value": "- name: kind\n  default: x\n  choices: [a, b]\n",
+	} {
+		if _, _, err := splitPrompts("{{/* prompts\n" + header + "*/}}\nbody"); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestRender_Values(t *testing.T) {
+	useDirs(t, map[string]string{"meetings.md": meetingTemplate})
+
+	now := time.Now()
+	data := NewData("Sync", "meetings", now)
+	data.Values = map[string]string{"kind": "retro", "project": "apollo"}
+	got, err := render("meetings", data, now)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	// The header is dropped so the template's own front matter comes first
+	want := "---\ntitle: Sync\n---\nAttendees: the team\nKind: retro\nProject: apollo\n"
+	if got != want {
+		t.Errorf("render = %q, want %q", got, want)
+	}
+}
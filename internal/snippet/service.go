@@ -3,48 +3,127 @@ package snippet
 import (
 	"bytes"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"text/template"
 	"time"
+
+	"github.com/gcaixeta/marginalia/internal/note"
+	"github.com/gcaixeta/marginalia/internal/slug"
+	"github.com/gcaixeta/marginalia/internal/storage"
 )
 
+// Default returns the content of a new note when its collection has no
+// template: front matter followed by the title as a heading.
 func Default(title, collection string) string {
-	return fmt.Sprintf(`# %s
+	return fmt.Sprintf("%s\n# %s\n\n", note.FrontMatter(title, collection, time.Now()), title)
+}
 
-## Created: %s
-## Collection: %s
+// templatesDirOverride replaces the default templates dir when set by
+// UseTemplatesDir
+var templatesDirOverride string
 
-`, title, time.Now().Format("2/1/2006 15:04:05"), collection)
+// UseTemplatesDir makes TemplatesDir return dir instead of the default
+// location. An empty dir restores the default.
+func UseTemplatesDir(dir string) {
+	templatesDirOverride = dir
 }
 
-func ReadSnippet(title, collection string) (string, error) {
+// TemplatesDir returns the directory holding one <collection>.md template
+// per collection: the one set by UseTemplatesDir or
+// <user config dir>/marginalia/collections.
+func TemplatesDir() (string, error) {
+	if templatesDirOverride != "" {
+		return templatesDirOverride, nil
+	}
+
 	configDir, err := os.UserConfigDir()
 	if err != nil {
-		log.Fatalf("Error while getting default user config dir: %v", err)
+		return "", fmt.Errorf("error while getting default user config dir: %w", err)
 	}
+	return filepath.Join(configDir, "marginalia", "collections"), nil
+}
+
+// partialsDir is the directory, inside the templates dir, holding the
+// partial templates every collection template can include
+const partialsDir = "partials"
+
+// Data is what a collection template is executed with
+type Data struct {
+	Title      string
+	Collection string
+	Date       string // Creation time in RFC3339 format
+	Previous   *Note  // Latest note of the collection, nil if it has none
 
-	snippetPath := filepath.Join(configDir, "marginalia", "collections", collection+".md")
+	// Period is the first day of the period of a daily, weekly or monthly
+	// note, and the zero time for other notes
+	Period time.Time
+
+	// Values holds the answers to the template's prompts along with any
+	// value given with --set
+	Values map[string]string
+}
+
+// Note is an existing note a template refers to
+type Note struct {
+	Title   string
+	Name    string // File name
+	Link    string // Wiki link to the note, e.g. [[20261016-09:00:00-standup]]
+	Created time.Time
+}
+
+// NewData returns the template data of a note about to be created
+func NewData(title, collection string, now time.Time) Data {
+	return Data{
+		Title:      title,
+		Collection: collection,
+		Date:       now.Format(time.RFC3339),
+		Previous:   previousNote(collection),
+	}
+}
+
+// ReadSnippet renders the template of data.Collection for a new note.
+// Prompts without a value in data.Values take their initial value. It
+// fails with an error matching fs.ErrNotExist when the collection has no
+// template.
+func ReadSnippet(data Data) (string, error) {
+	return render(data.Collection, data, time.Now())
+}
+
+func render(collection string, data Data, now time.Time) (string, error) {
+	snippetPath, err := Path(collection)
+	if err != nil {
+		return "", err
+	}
+	templatesDir := filepath.Dir(snippetPath)
 
 	content, err := os.ReadFile(snippetPath)
 	if err != nil {
 		return "", err
 	}
 
-	tmpl, err := template.New("snippet").Parse(string(content))
+	prompts, body, err := splitPrompts(string(content))
 	if err != nil {
-		return "", fmt.Errorf("error parsing snippet template: %w", err)
+		return "", err
+	}
+	values := make(map[string]string, len(prompts)+len(data.Values))
+	for _, p := range prompts {
+		values[p.Name] = p.Initial()
 	}
+	for k, v := range data.Values {
+		values[k] = v
+	}
+	data.Values = values
 
-	data := struct {
-		Title      string
-		Collection string
-		Date       string
-	}{
-		Title:      title,
-		Collection: collection,
-		Date:       time.Now().Format("2/1/2006 15:04:05"),
+	// Named after the file so a partial can't share the collection's name
+	tmpl := template.New(collection + ".md").Funcs(funcs(now))
+	tmpl.Funcs(template.FuncMap{"include": include(tmpl)})
+	if err := parsePartials(tmpl, filepath.Join(templatesDir, partialsDir)); err != nil {
+		return "", err
+	}
+	if _, err := tmpl.Parse(body); err != nil {
+		return "", fmt.Errorf("error parsing snippet template: %w", err)
 	}
 
 	var buf bytes.Buffer
@@ -52,5 +131,80 @@ func ReadSnippet(title, collection string) (string, error) {
 		return "", fmt.Errorf("error executing snippet template: %w", err)
 	}
 
+	// Templates may define their own front matter; otherwise add the
+	// default one so every note carries readable metadata.
+	if !note.HasFrontMatter(buf.Bytes()) {
+		return note.FrontMatter(data.Title, collection, now) + "\n" + buf.String(), nil
+	}
+
 	return buf.String(), nil
 }
+
+// parsePartials adds the templates in dir to tmpl, each named after its
+// file without extension: partials/header.md is {{ template "header" . }}
+func parsePartials(tmpl *template.Template, dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.md"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(filepath.Base(path), ".md")
+		if _, err := tmpl.New(name).Parse(string(content)); err != nil {
+			return fmt.Errorf("error parsing partial %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// include returns a function rendering a partial to a string, so unlike
+// {{ template }} its output can be piped to other functions
+func include(tmpl *template.Template) func(name string, data any) (string, error) {
+	return func(name string, data any) (string, error) {
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+}
+
+// previousNote returns the most recently created note of collection
+func previousNote(collection string) *Note {
+	store, err := storage.Store()
+	if err != nil {
+		return nil
+	}
+	files, err := store.List()
+	if err != nil {
+		return nil
+	}
+
+	var latest *Note
+	for _, file := range storage.FilterByCollection(files, collection) {
+		created, s, ok := slug.SplitName(file.Name)
+		if !ok {
+			created = file.ModTime
+		}
+		if latest != nil && !created.After(latest.Created) {
+			continue
+		}
+		title := s
+		if content, err := store.Read(file.Collection, file.Name); err == nil {
+			meta, body, _ := note.Parse(content)
+			if t := note.Title(meta, body); t != "" {
+				title = t
+			}
+		}
+		latest = &Note{
+			Title:   title,
+			Name:    file.Name,
+			Link:    "[[" + strings.TrimSuffix(file.Name, ".md") + "]]",
+			Created: created,
+		}
+	}
+	return latest
+}
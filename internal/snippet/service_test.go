@@ -0,0 +1,127 @@
+package snippet
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gcaixeta/marginalia/internal/storage"
+)
+
+// useDirs points the data and templates dirs at new temporary dirs and
+// writes the given templates, keyed by path relative to the templates dir
+func useDirs(t *testing.T, templates map[string]string) string {
+	t.Helper()
+	dataDir, templatesDir := t.TempDir(), t.TempDir()
+	storage.UseDataDir(dataDir)
+	UseTemplatesDir(templatesDir)
+	t.Cleanup(func() {
+		storage.UseDataDir("")
+		UseTemplatesDir("")
+	})
+	for rel, content := range templates {
+		path := filepath.Join(templatesDir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dataDir
+}
+
+func TestRender_Functions(t *testing.T) {
+	useDirs(t, map[string]string{
+		"journal.md": `---
+title: {{ .Title }}
+---
+today: {{ today | date "2006-01-02" }}
+yesterday: {{ (today.AddDate 0 0 -1) | date "2006-01-02" }}
+tomorrow: {{ today | addDays 1 | date "Monday" }}
+created: {{ .Date | date "15:04" }}
+slug: {{ slug .Title }}
+home: {{ env "MARGI_TEST_HOME" }}
+id: {{ uuid }}
+`,
+	})
+	t.Setenv("MARGI_TEST_HOME", "/home/me")
+
+	now := time.Date(2026, 10, 17, 9, 30, 0, 0, time.Local)
+	got, err := render("journal", NewData("Café da Manhã", "journal", now), now)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	for _, want := range []string{
+		"title: Café da Manhã",
+		"today: 2026-10-17",
+		"yesterday: 2026-10-16",
+		"tomorrow: Sunday",
+		"created: 09:30",
+		"slug: cafe-da-manha",
+		"home: /home/me",
+	} {
+		if !strings.Contains(got, want+"\n") {
+			t.Errorf("rendered template lacks %q:\n%s", want, got)
+		}
+	}
+	if !regexp.MustCompile(`id: [0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}\n`).MatchString(got) {
+		t.Errorf("no UUID in:\n%s", got)
+	}
+}
+
+func TestRender_Partials(t *testing.T) {
+	useDirs(t, map[string]string{
+		"meetings.md":          "{{ template \"header\" . }}\n## Notes\n{{ include \"meetings\" . | slug }}\n",
+		"partials/header.md":   "# {{ .Title }} ({{ .Collection }})",
+		"partials/meetings.md": "Shared Agenda",
+	})
+
+	now := time.Now()
+	got, err := render("meetings", NewData("Sync", "meetings", now), now)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if !strings.Contains(got, "# Sync (meetings)\n## Notes\nshared-agenda\n") {
+		t.Errorf("partials not included:\n%s", got)
+	}
+}
+
+func TestRender_Previous(t *testing.T) {
+	dataDir := useDirs(t, map[string]string{
+		"journal.md": "{{ with .Previous }}Previous: {{ .Link }} ({{ .Title }}){{ else }}First entry{{ end }}\n",
+	})
+
+	now := time.Now()
+	got, err := render("journal", NewData("Day 1", "journal", now), now)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if !strings.Contains(got, "First entry") {
+		t.Errorf("expected no previous note:\n%s", got)
+	}
+
+	os.MkdirAll(filepath.Join(dataDir, "journal"), 0755)
+	os.WriteFile(filepath.Join(dataDir, "journal", "20261015-090000-day-1.md"), []byte("---\ntitle: Day one\n---\n"), 0644)
+	os.WriteFile(filepath.Join(dataDir, "journal", "20261016-090000-day-2.md"), []byte("# Day 2\n"), 0644)
+	os.MkdirAll(filepath.Join(dataDir, "work"), 0755)
+	os.WriteFile(filepath.Join(dataDir, "work", "20261017-090000-plan.md"), []byte("# Plan\n"), 0644)
+
+	got, err = render("journal", NewData("Day 3", "journal", now), now)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if !strings.Contains(got, "Previous: [[20261016-090000-day-2]] (Day 2)") {
+		t.Errorf("wrong previous note:\n%s", got)
+	}
+}
+
+func TestReadSnippet_MissingTemplate(t *testing.T) {
+	useDirs(t, nil)
+	if _, err := ReadSnippet(NewData("Title", "none", time.Now())); !os.IsNotExist(err) {
+		t.Errorf("err = %v, want a not-exist error", err)
+	}
+}
@@ -0,0 +1,97 @@
+package snippet
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gcaixeta/marginalia/internal/storage"
+)
+
+// Scaffold is the template `margi template new` starts from: the same
+// note Default creates, written as a template
+const Scaffold = `---
+title: {{ .Title | yaml }}
+collection: {{ .Collection | yaml }}
+created: {{ .Date }}
+tags: []
+---
+
+# {{ .Title }}
+
+`
+
+// SampleTitle is the title templates are previewed with
+const SampleTitle = "Sample note"
+
+// Path returns where the template of collection lives, whether or not it
+// exists. It fails for a name that isn't a valid collection, so a template
+// can't be read or written outside the templates directory.
+func Path(collection string) (string, error) {
+	if err := storage.CheckCollection(collection); err != nil {
+		return "", err
+	}
+	templatesDir, err := TemplatesDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(templatesDir, collection+".md"), nil
+}
+
+// List returns the collections that have a template, sorted by name
+func List() ([]string, error) {
+	return listTemplates("")
+}
+
+// Partials returns the names of the partials templates can include
+func Partials() ([]string, error) {
+	return listTemplates(partialsDir)
+}
+
+func listTemplates(sub string) ([]string, error) {
+	templatesDir, err := TemplatesDir()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(templatesDir, sub, "*.md"))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(paths))
+	for _, path := range paths {
+		names = append(names, strings.TrimSuffix(filepath.Base(path), ".md"))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Create writes Scaffold as the template of collection and returns its
+// path. It fails with an error matching fs.ErrExist when the collection
+// already has a template.
+func Create(collection string) (string, error) {
+	path, err := Path(collection)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.WriteString(Scaffold); err != nil {
+		f.Close()
+		return "", err
+	}
+	return path, f.Close()
+}
+
+// Preview renders the template of collection for a note titled
+// SampleTitle, with every prompt at its initial value
+func Preview(collection string) (string, error) {
+	now := time.Now()
+	return render(collection, NewData(SampleTitle, collection, now), now)
+}
@@ -0,0 +1,97 @@
+package snippet
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gcaixeta/marginalia/internal/note"
+)
+
+func TestList(t *testing.T) {
+	useDirs(t, map[string]string{
+		"work.md":            "# Work",
+		"journal.md":         "# Journal",
+		"partials/header.md": "# Header",
+		"notes.txt":          "not a template",
+	})
+
+	names, err := List()
+	if err != nil || !reflect.DeepEqual(names, []string{"journal", "work"}) {
+		t.Errorf("List = %v, %v", names, err)
+	}
+	partials, err := Partials()
+	if err != nil || !reflect.DeepEqual(partials, []string{"header"}) {
+		t.Errorf("Partials = %v, %v", partials, err)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	useDirs(t, nil)
+
+	if _, err := Preview("work"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Preview without a template: err = %v", err)
+	}
+	if _, err := Create("work"); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := Create("work"); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("second Create: err = %v, want fs.ErrExist", err)
+	}
+
+	// The scaffold renders the same note as the default
+	got, err := Preview("work")
+	if err != nil {
+		t.Fatalf("Preview: %v", err)
+	}
+	want := Default(SampleTitle, "work")
+	if got[strings.Index(got, "tags:"):] != want[strings.Index(want, "tags:"):] {
+		t.Errorf("Preview = %q, want %q", got, want)
+	}
+}
+
+func TestScaffold_QuotedTitle(t *testing.T) {
+	useDirs(t, nil)
+	if _, err := Create("work"); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	for _, title := range []string{"Re: budget", "Q3 #plan", "plain"} {
+		content, err := ReadSnippet(NewData(title, "work", time.Now()))
+		if err != nil {
+			t.Fatalf("ReadSnippet(%q): %v", title, err)
+		}
+		meta, _, err := note.Parse([]byte(content))
+		if err != nil || meta.Title != title {
+			t.Errorf("title %q parsed back as %q, %v", title, meta.Title, err)
+		}
+	}
+}
+
+func TestCreate_InvalidName(t *testing.T) {
+	useDirs(t, nil)
+
+	for _, name := range []string{"", "..", "../../x", "a/b", `a\b`, ".hidden"} {
+		if path, err := Create(name); err == nil {
+			t.Errorf("Create(%q) wrote %s", name, path)
+		}
+		if _, err := Preview(name); err == nil || errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Preview(%q): err = %v, want an invalid name", name, err)
+		}
+	}
+	templatesDir, _ := TemplatesDir()
+	if entries, _ := os.ReadDir(templatesDir); len(entries) != 0 {
+		t.Errorf("templates dir has %d entries, want none", len(entries))
+	}
+}
+
+func TestPreview_Broken(t *testing.T) {
+	useDirs(t, map[string]string{"work.md": "{{ .Title }"})
+	if _, err := Preview("work"); err == nil || errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Preview of a broken template: err = %v", err)
+	}
+}
@@ -1,11 +1,11 @@
 package storage
 
 import (
-	"bytes"
 	"fmt"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
 
 	"github.com/gcaixeta/marginalia/internal/config"
 )
@@ -17,6 +17,8 @@ type GitSync struct {
 	branch  string
 	pullWg  sync.WaitGroup
 	pullErr error
+	pending atomic.Int64 // Commits not pushed yet, see Pending
+	engine  gitEngine
 }
 
 func NewGitSync(cfg *config.BackupConfig) (*GitSync, error) {
@@ -39,76 +41,156 @@ func NewGitSync(cfg *config.BackupConfig) (*GitSync, error) {
 		branch = "main"
 	}
 
-	return &GitSync{
+	engine, err := newGitEngine(cfg.Git.Engine, dataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &GitSync{
 		dataDir: dataDir,
 		repo:    cfg.Git.Repo,
 		remote:  remote,
 		branch:  branch,
-	}, nil
+		engine:  engine,
+	}
+	g.pending.Store(int64(g.loadState().PendingCommits))
+	return g, nil
 }
 
-func (g *GitSync) run(args ...string) error {
-	cmdArgs := append([]string{"-C", g.dataDir}, args...)
-	cmd := exec.Command("git", cmdArgs...)
-	return cmd.Run()
+// git returns the engine running the repository operations. GitSyncs
+// built without one use the git binary.
+func (g *GitSync) git() gitEngine {
+	if g.engine == nil {
+		return &execGit{dir: g.dataDir}
+	}
+	return g.engine
 }
 
-func (g *GitSync) runSilent(args ...string) error {
-	cmdArgs := append([]string{"-C", g.dataDir}, args...)
-	cmd := exec.Command("git", cmdArgs...)
-	cmd.Stdin = nil
-	cmd.Env = append(os.Environ(),
-		"GIT_TERMINAL_PROMPT=0",
-		"GIT_SSH_COMMAND=ssh -o BatchMode=yes",
-	)
-	return cmd.Run()
+// rel returns path relative to the data dir, in the form engines expect
+func (g *GitSync) rel(path string) (string, error) {
+	rel, err := filepath.Rel(g.dataDir, path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
 }
 
 func (g *GitSync) Synchronize() error {
 	g.pullWg.Go(func() {
 		if _, err := os.Stat(g.dataDir + "/.git"); os.IsNotExist(err) {
-			if err := g.runSilent("init"); err != nil {
-				g.pullErr = fmt.Errorf("git init: %w", err)
+			if err := g.git().Init(g.branch, g.remote, g.repo); err != nil {
+				g.pullErr = err
 				return
 			}
-			if err := g.runSilent("remote", "add", g.remote, g.repo); err != nil {
-				g.pullErr = fmt.Errorf("git remote add: %w", err)
+		}
+
+		if err := g.git().Pull(g.remote, g.branch); err != nil {
+			conflicts, cerr := g.Conflicts()
+			if cerr == nil && len(conflicts) > 0 {
+				g.pullErr = &ConflictError{Conflicts: conflicts}
 				return
 			}
+			g.pullErr = fmt.Errorf("git pull: %w", err)
 		}
+		g.recordPull(g.pullErr)
 
-		_ = g.runSilent("pull", g.remote, g.branch)
+		// Flush commits a failed push left behind, now that the remote is
+		// reachable again.
+		if g.pullErr == nil {
+			g.retryPending(false)
+		}
 	})
 	return nil
 }
 
+// Wait blocks until the pull started by Synchronize finishes and returns
+// its error, a *ConflictError when notes could not be merged.
+func (g *GitSync) Wait() error {
+	g.pullWg.Wait()
+	return g.pullErr
+}
+
 func (g *GitSync) CommitAndPush(message string) error {
 	g.pullWg.Wait()
-	if g.pullErr != nil {
+	if g.pullErr != nil && !IsConflict(g.pullErr) {
 		fmt.Printf("Warning: git pull failed: %v\n", g.pullErr)
 	}
 
-	if err := g.run("add", "-A"); err != nil {
+	// `git add` would mark conflicted notes as resolved, so check first.
+	conflicts, err := g.Conflicts()
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return &ConflictError{Conflicts: conflicts}
+	}
+	marked, err := g.markedFiles()
+	if err != nil {
+		return err
+	}
+	if len(marked) > 0 {
+		return &ConflictError{Conflicts: marked}
+	}
+
+	// Notes must be encrypted before git sees them
+	if err := sealPlaintext(g.dataDir); err != nil {
+		return err
+	}
+	if err := g.git().AddAll(); err != nil {
 		return fmt.Errorf("git add: %w", err)
 	}
 
-	cmd := exec.Command("git", "-C", g.dataDir, "status", "--porcelain")
-	out, err := cmd.Output()
+	changes, err := g.git().Changes()
 	if err != nil {
 		return fmt.Errorf("git status: %w", err)
 	}
-	if len(bytes.TrimSpace(out)) == 0 {
+	// A merge whose resolution matches HEAD leaves nothing to show, but
+	// still needs its commit.
+	if len(changes) == 0 && !g.Merging() {
 		return nil
 	}
 
-	if err := g.run("commit", "-m", message); err != nil {
+	if err := g.git().Commit(message); err != nil {
 		return fmt.Errorf("git commit: %w", err)
 	}
 
-	if err := g.run("push", g.remote, g.branch); err != nil {
-		return fmt.Errorf("git push: %w", err)
+	if err := g.git().Push(g.remote, g.branch); err != nil {
+		err = fmt.Errorf("git push: %w", err)
+		g.recordPush(err)
+		return err
 	}
+	g.recordPush(nil)
 
 	fmt.Println("↑ synced")
 	return nil
 }
+
+// Merging reports whether a pulled merge is waiting for its commit
+func (g *GitSync) Merging() bool {
+	return g.git().Merging()
+}
+
+// Move renames a note with `git mv` so its history follows it. Files git
+// doesn't track yet are renamed directly.
+func (g *GitSync) Move(from, to string) error {
+	g.pullWg.Wait()
+
+	if err := EnsureDir(filepath.Dir(to)); err != nil {
+		return err
+	}
+	relFrom, err := g.rel(from)
+	if err != nil {
+		return err
+	}
+	relTo, err := g.rel(to)
+	if err != nil {
+		return err
+	}
+	if !g.git().Tracked(relFrom) {
+		return os.Rename(from, to)
+	}
+	if err := g.git().Move(relFrom, relTo); err != nil {
+		return fmt.Errorf("git mv: %w", err)
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/gcaixeta/marginalia/internal/config"
@@ -44,6 +45,22 @@ func newBareRepo(t *testing.T) string {
 	return dir
 }
 
+// forEachEngine runs test once with every git engine
+func forEachEngine(t *testing.T, test func(t *testing.T, engine string)) {
+	for _, engine := range []string{EngineExec, EngineGoGit} {
+		t.Run(engine, func(t *testing.T) { test(t, engine) })
+	}
+}
+
+func newTestSync(t *testing.T, engine, dataDir, repo string) *GitSync {
+	t.Helper()
+	git, err := newGitEngine(engine, dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &GitSync{dataDir: dataDir, repo: repo, remote: "origin", branch: "main", engine: git}
+}
+
 func TestNewGitSync_ReturnsNilForNonGitProvider(t *testing.T) {
 	cfg := &config.BackupConfig{
 		Provider: "s3",
@@ -73,18 +90,19 @@ func TestNewGitSync_ReturnsNilForEmptyRepo(t *testing.T) {
 }
 
 func TestNewGitSync_SetsDefaultRemoteAndBranch(t *testing.T) {
-	// We can't call NewGitSync (it calls DataDir), so construct directly.
-	g := &GitSync{
-		dataDir: t.TempDir(),
-		repo:    "somewhere",
-		remote:  "",
-		branch:  "",
-	}
-	if g.remote == "" {
-		g.remote = "origin"
+	dataDir := t.TempDir()
+	UseDataDir(dataDir)
+	defer UseDataDir("")
+
+	g, err := NewGitSync(&config.BackupConfig{
+		Provider: "git",
+		Git:      config.GitConfig{Repo: "somewhere"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
-	if g.branch == "" {
-		g.branch = "main"
+	if g.dataDir != dataDir {
+		t.Errorf("expected dataDir=%q, got %q", dataDir, g.dataDir)
 	}
 	if g.remote != "origin" {
 		t.Errorf("expected remote=origin, got %q", g.remote)
@@ -96,96 +114,241 @@ func TestNewGitSync_SetsDefaultRemoteAndBranch(t *testing.T) {
 
 func TestCommitAndPush_NothingToCommit(t *testing.T) {
 	requireGit(t)
-	local := newLocalRepo(t)
-	bare := newBareRepo(t)
+	forEachEngine(t, func(t *testing.T, engine string) {
+		local := newLocalRepo(t)
+		bare := newBareRepo(t)
 
-	gitCmd(t, local, "remote", "add", "origin", bare)
+		gitCmd(t, local, "remote", "add", "origin", bare)
 
-	// Create an initial commit so push has a valid history.
-	placeholder := filepath.Join(local, ".keep")
-	if err := os.WriteFile(placeholder, []byte(""), 0644); err != nil {
-		t.Fatal(err)
-	}
-	gitCmd(t, local, "add", "-A")
-	gitCmd(t, local, "commit", "-m", "init")
-	gitCmd(t, local, "push", "origin", "main")
+		// Create an initial commit so push has a valid history.
+		placeholder := filepath.Join(local, ".keep")
+		if err := os.WriteFile(placeholder, []byte(""), 0644); err != nil {
+			t.Fatal(err)
+		}
+		gitCmd(t, local, "add", "-A")
+		gitCmd(t, local, "commit", "-m", "init")
+		gitCmd(t, local, "push", "origin", "main")
 
-	g := &GitSync{dataDir: local, repo: bare, remote: "origin", branch: "main"}
+		g := newTestSync(t, engine, local, bare)
 
-	if err := g.CommitAndPush("nothing"); err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
+		if err := g.CommitAndPush("nothing"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
 }
 
 func TestCommitAndPush_CommitsAndPushes(t *testing.T) {
 	requireGit(t)
-	local := newLocalRepo(t)
-	bare := newBareRepo(t)
+	forEachEngine(t, func(t *testing.T, engine string) {
+		local := newLocalRepo(t)
+		bare := newBareRepo(t)
 
-	gitCmd(t, local, "remote", "add", "origin", bare)
+		gitCmd(t, local, "remote", "add", "origin", bare)
 
-	// Initial commit so we can push.
-	placeholder := filepath.Join(local, ".keep")
-	if err := os.WriteFile(placeholder, []byte(""), 0644); err != nil {
-		t.Fatal(err)
-	}
-	gitCmd(t, local, "add", "-A")
-	gitCmd(t, local, "commit", "-m", "init")
-	gitCmd(t, local, "push", "origin", "main")
+		// Initial commit so we can push.
+		placeholder := filepath.Join(local, ".keep")
+		if err := os.WriteFile(placeholder, []byte(""), 0644); err != nil {
+			t.Fatal(err)
+		}
+		gitCmd(t, local, "add", "-A")
+		gitCmd(t, local, "commit", "-m", "init")
+		gitCmd(t, local, "push", "origin", "main")
 
-	// Add a new file that needs committing.
-	newNote := filepath.Join(local, "note.md")
-	if err := os.WriteFile(newNote, []byte("# Hello\n"), 0644); err != nil {
-		t.Fatal(err)
-	}
+		// Add a new file that needs committing.
+		newNote := filepath.Join(local, "note.md")
+		if err := os.WriteFile(newNote, []byte("# Hello\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
 
-	g := &GitSync{dataDir: local, repo: bare, remote: "origin", branch: "main"}
+		g := newTestSync(t, engine, local, bare)
 
-	if err := g.CommitAndPush("add note"); err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
+		if err := g.CommitAndPush("add note"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
 
-	// Verify the commit exists.
-	cmd := exec.Command("git", "-C", local, "log", "--oneline")
-	out, err := cmd.Output()
-	if err != nil {
-		t.Fatalf("git log: %v", err)
-	}
-	if len(out) == 0 {
-		t.Fatal("expected at least one commit")
-	}
+		// Verify the commit exists.
+		cmd := exec.Command("git", "-C", local, "log", "--oneline")
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("git log: %v", err)
+		}
+		if len(out) == 0 {
+			t.Fatal("expected at least one commit")
+		}
+	})
 }
 
 func TestSynchronize_PullsFromRemote(t *testing.T) {
 	requireGit(t)
+	forEachEngine(t, func(t *testing.T, engine string) {
+		// Set up a "remote" repo (a second local repo acting as remote).
+		remote := newLocalRepo(t)
+		local := newLocalRepo(t)
 
-	// Set up a "remote" repo (a second local repo acting as remote).
-	remote := newLocalRepo(t)
-	local := newLocalRepo(t)
+		// Seed the remote with a commit.
+		remoteFile := filepath.Join(remote, "seed.md")
+		if err := os.WriteFile(remoteFile, []byte("# Seed\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		gitCmd(t, remote, "add", "-A")
+		gitCmd(t, remote, "commit", "-m", "seed")
 
-	// Seed the remote with a commit.
-	remoteFile := filepath.Join(remote, "seed.md")
-	if err := os.WriteFile(remoteFile, []byte("# Seed\n"), 0644); err != nil {
-		t.Fatal(err)
-	}
-	gitCmd(t, remote, "add", "-A")
-	gitCmd(t, remote, "commit", "-m", "seed")
+		// Wire local → remote as origin.
+		gitCmd(t, local, "remote", "add", "origin", remote)
 
-	// Wire local → remote as origin.
-	gitCmd(t, local, "remote", "add", "origin", remote)
+		g := newTestSync(t, engine, local, remote)
 
-	g := &GitSync{dataDir: local, repo: remote, remote: "origin", branch: "main"}
+		if err := g.Synchronize(); err != nil {
+			t.Fatalf("Synchronize error: %v", err)
+		}
+		// CommitAndPush waits for the pull goroutine.
+		if err := g.CommitAndPush("sync"); err != nil {
+			t.Fatalf("CommitAndPush error: %v", err)
+		}
 
-	if err := g.Synchronize(); err != nil {
-		t.Fatalf("Synchronize error: %v", err)
-	}
-	// CommitAndPush waits for the pull goroutine.
-	if err := g.CommitAndPush("sync"); err != nil {
-		t.Fatalf("CommitAndPush error: %v", err)
-	}
+		// The file seeded in the remote should now exist locally.
+		if _, err := os.Stat(filepath.Join(local, "seed.md")); os.IsNotExist(err) {
+			t.Fatal("expected seed.md to be pulled into local repo")
+		}
+	})
+}
 
-	// The file seeded in the remote should now exist locally.
-	if _, err := os.Stat(filepath.Join(local, "seed.md")); os.IsNotExist(err) {
-		t.Fatal("expected seed.md to be pulled into local repo")
-	}
+func TestMove_UsesGitMv(t *testing.T) {
+	requireGit(t)
+	forEachEngine(t, func(t *testing.T, engine string) {
+		local := newLocalRepo(t)
+
+		if err := os.MkdirAll(filepath.Join(local, "work"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		from := filepath.Join(local, "work", "note.md")
+		if err := os.WriteFile(from, []byte("# Note\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		gitCmd(t, local, "add", "-A")
+		gitCmd(t, local, "commit", "-m", "init")
+
+		if err := os.MkdirAll(filepath.Join(local, "ideas"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		to := filepath.Join(local, "ideas", "renamed.md")
+
+		g := newTestSync(t, engine, local, "")
+		if err := g.Move(from, to); err != nil {
+			t.Fatalf("Move error: %v", err)
+		}
+
+		out, err := exec.Command("git", "-C", local, "status", "--porcelain").Output()
+		if err != nil {
+			t.Fatalf("git status: %v", err)
+		}
+		if string(out) != "R  work/note.md -> ideas/renamed.md\n" {
+			t.Errorf("expected staged rename, got %q", out)
+		}
+	})
+}
+
+func TestMove_UntrackedFile(t *testing.T) {
+	requireGit(t)
+	forEachEngine(t, func(t *testing.T, engine string) {
+		local := newLocalRepo(t)
+
+		from := filepath.Join(local, "draft.md")
+		if err := os.WriteFile(from, []byte("draft\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		to := filepath.Join(local, "final.md")
+
+		g := newTestSync(t, engine, local, "")
+		if err := g.Move(from, to); err != nil {
+			t.Fatalf("Move error: %v", err)
+		}
+		if _, err := os.Stat(to); err != nil {
+			t.Errorf("expected %s to exist: %v", to, err)
+		}
+	})
+}
+
+func TestSynchronize_MergesDivergedHistory(t *testing.T) {
+	requireGit(t)
+	forEachEngine(t, func(t *testing.T, engine string) {
+		bare := newBareRepo(t)
+		local := newLocalRepo(t)
+		gitCmd(t, local, "remote", "add", "origin", bare)
+		writeRepoFile(t, local, "work/plan.md", "# Plan\n\nfirst\nmiddle\nlast\n")
+		gitCmd(t, local, "add", "-A")
+		gitCmd(t, local, "commit", "-q", "-m", "base")
+		gitCmd(t, local, "push", "-q", "origin", "main")
+
+		other := cloneRepo(t, bare)
+		writeRepoFile(t, other, "work/plan.md", "# Plan\n\nfirst, edited\nmiddle\nlast\n")
+		writeRepoFile(t, other, "work/todo.md", "todo\n")
+		gitCmd(t, other, "add", "-A")
+		gitCmd(t, other, "commit", "-q", "-m", "theirs")
+		gitCmd(t, other, "push", "-q", "origin", "main")
+
+		writeRepoFile(t, local, "work/plan.md", "# Plan\n\nfirst\nmiddle\nlast, edited\n")
+		gitCmd(t, local, "commit", "-q", "-am", "mine")
+
+		g := newTestSync(t, engine, local, bare)
+		g.Synchronize()
+		if err := g.Wait(); err != nil {
+			t.Fatalf("pull: %v", err)
+		}
+
+		content, _ := os.ReadFile(filepath.Join(local, "work", "plan.md"))
+		if string(content) != "# Plan\n\nfirst, edited\nmiddle\nlast, edited\n" {
+			t.Errorf("merged note = %q", content)
+		}
+		if _, err := os.Stat(filepath.Join(local, "work", "todo.md")); err != nil {
+			t.Errorf("expected todo.md to be pulled: %v", err)
+		}
+
+		out, err := exec.Command("git", "-C", local, "rev-list", "--parents", "-n1", "HEAD").Output()
+		if err != nil {
+			t.Fatalf("git rev-list: %v", err)
+		}
+		if parents := len(strings.Fields(string(out))) - 1; parents != 2 {
+			t.Errorf("expected a merge commit, HEAD has %d parent(s)", parents)
+		}
+		if out, _ := exec.Command("git", "-C", local, "status", "--porcelain").Output(); len(out) != 0 {
+			t.Errorf("expected a clean working tree, got %q", out)
+		}
+	})
+}
+
+func TestSynchronize_InitsRepository(t *testing.T) {
+	requireGit(t)
+	forEachEngine(t, func(t *testing.T, engine string) {
+		bare := newBareRepo(t)
+		seed := cloneRepo(t, bare)
+		writeRepoFile(t, seed, "work/seed.md", "# Seed\n")
+		gitCmd(t, seed, "add", "-A")
+		gitCmd(t, seed, "commit", "-q", "-m", "seed")
+		gitCmd(t, seed, "push", "-q", "origin", "main")
+
+		local := t.TempDir()
+		g := newTestSync(t, engine, local, bare)
+		g.Synchronize()
+		if err := g.Wait(); err != nil {
+			t.Fatalf("pull: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(local, "work", "seed.md")); err != nil {
+			t.Fatalf("expected seed.md to be pulled: %v", err)
+		}
+
+		gitCmd(t, local, "config", "user.email", "test@test.com")
+		gitCmd(t, local, "config", "user.name", "Test")
+		writeRepoFile(t, local, "work/note.md", "# Note\n")
+		if err := g.CommitAndPush("add note"); err != nil {
+			t.Fatalf("CommitAndPush: %v", err)
+		}
+		out, err := exec.Command("git", "-C", bare, "log", "--format=%s", "main").Output()
+		if err != nil {
+			t.Fatalf("git log: %v", err)
+		}
+		if string(out) != "add note\nseed\n" {
+			t.Errorf("remote log = %q", out)
+		}
+	})
 }
@@ -0,0 +1,309 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Conflict is a note git could not merge during a pull
+type Conflict struct {
+	Path       string // Full path of the note in the working tree
+	Collection string
+	Name       string
+}
+
+// Rel returns the note as "collection/name"
+func (c Conflict) Rel() string {
+	return c.Collection + "/" + c.Name
+}
+
+// ConflictError reports notes with unresolved merge conflicts. Commits are
+// refused while it is returned.
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	notes := make([]string, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		notes[i] = "  " + c.Rel()
+	}
+	return fmt.Sprintf("unresolved merge conflicts in %d note(s):\n%s\nrun `margi resolve` to fix them",
+		len(e.Conflicts), strings.Join(notes, "\n"))
+}
+
+// IsConflict reports whether err is a ConflictError
+func IsConflict(err error) bool {
+	var conflictErr *ConflictError
+	return errors.As(err, &conflictErr)
+}
+
+// Resolution is how a conflicted note is resolved
+type Resolution int
+
+const (
+	ResolveOurs   Resolution = iota // Keep the local version
+	ResolveTheirs                   // Keep the remote version
+	ResolveBoth                     // Keep local in place and remote as a separate note
+)
+
+// theirsSuffix is appended to the name of the note holding the remote
+// version when both versions are kept
+const theirsSuffix = "-theirs"
+
+// Conflicts returns the notes left unmerged by the last pull
+func (g *GitSync) Conflicts() ([]Conflict, error) {
+	paths, err := g.git().Conflicts()
+	if err != nil {
+		return nil, fmt.Errorf("git diff: %w", err)
+	}
+
+	conflicts := []Conflict{}
+	for _, rel := range paths {
+		conflicts = append(conflicts, g.conflict(rel))
+	}
+	return conflicts, nil
+}
+
+func (g *GitSync) conflict(rel string) Conflict {
+	rel = filepath.FromSlash(rel)
+	return Conflict{
+		Path:       filepath.Join(g.dataDir, rel),
+		Collection: filepath.Base(filepath.Dir(rel)),
+		Name:       filepath.Base(rel),
+	}
+}
+
+// Resolve settles a conflicted note and stages the result. It returns the
+// paths it wrote, which include the extra note created by ResolveBoth.
+func (g *GitSync) Resolve(c Conflict, how Resolution) ([]string, error) {
+	g.pullWg.Wait()
+
+	ours, hasOurs := g.stage(c, 2)
+	theirs, hasTheirs := g.stage(c, 3)
+
+	var written []string
+	keep := func(path string, content []byte, ok bool) error {
+		if !ok {
+			// Deleted on that side
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			return nil
+		}
+		written = append(written, path)
+		return os.WriteFile(path, content, 0644)
+	}
+
+	var err error
+	switch how {
+	case ResolveOurs:
+		err = keep(c.Path, ours, hasOurs)
+	case ResolveTheirs:
+		err = keep(c.Path, theirs, hasTheirs)
+	case ResolveBoth:
+		switch {
+		case hasOurs && hasTheirs:
+			if err = keep(c.Path, ours, true); err == nil {
+				err = keep(theirsPath(c.Path), theirs, true)
+			}
+		case hasOurs:
+			err = keep(c.Path, ours, true)
+		default:
+			err = keep(c.Path, theirs, hasTheirs)
+		}
+	default:
+		return nil, fmt.Errorf("unknown resolution %d", how)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return written, g.stageResolved(append([]string{c.Path}, written...)...)
+}
+
+// MarkResolved stages a conflicted note the user merged by hand. It fails
+// while the note still contains conflict markers.
+func (g *GitSync) MarkResolved(c Conflict) error {
+	g.pullWg.Wait()
+
+	content, err := os.ReadFile(c.Path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if plain, err := Decrypt(content); err == nil {
+		content = plain
+	}
+	if HasConflictMarkers(content) {
+		return fmt.Errorf("%s still contains conflict markers", c.Rel())
+	}
+	return g.stageResolved(c.Path)
+}
+
+// MergeByHand has the user merge a conflicted note with edit, which gets
+// the path of the file to fix, and stages the result unless edit fails. An encrypted note is
+// merged in plain text: both versions are decrypted into a temporary file,
+// between conflict markers, and the merge is encrypted back into place.
+func (g *GitSync) MergeByHand(c Conflict, edit func(path string) error) error {
+	store := Encrypted()
+	if store == nil {
+		if err := edit(c.Path); err != nil {
+			return err
+		}
+		return g.MarkResolved(c)
+	}
+	g.pullWg.Wait()
+
+	name := c.Name
+	side := func(n int) []byte {
+		data, ok := g.stage(c, n)
+		if !ok {
+			return nil
+		}
+		sealedName, content, err := store.key.Open(data)
+		if err != nil {
+			// Not encrypted yet, e.g. committed before encryption was on
+			return data
+		}
+		name = sealedName
+		return content
+	}
+	ours, theirs := side(2), side(3)
+
+	merged, err := store.editTemp(name, conflictBlock(ours, theirs), edit)
+	if err != nil {
+		return err
+	}
+	if HasConflictMarkers(merged) {
+		return fmt.Errorf("%s still contains conflict markers", c.Rel())
+	}
+	if err := writeFileAtomic(c.Path, store.key.Seal(name, merged)); err != nil {
+		return err
+	}
+	return g.stageResolved(c.Path)
+}
+
+// conflictBlock returns both versions of a note between git-style
+// conflict markers
+func conflictBlock(ours, theirs []byte) []byte {
+	var b bytes.Buffer
+	side := func(content []byte) {
+		b.Write(content)
+		if len(content) > 0 && !bytes.HasSuffix(content, []byte("\n")) {
+			b.WriteByte('\n')
+		}
+	}
+	b.WriteString("<<<<<<< ours\n")
+	side(ours)
+	b.WriteString("=======\n")
+	side(theirs)
+	b.WriteString(">>>>>>> theirs\n")
+	return b.Bytes()
+}
+
+// stage returns the content of a note at one stage of the index: 2 is
+// ours and 3 is theirs. ok is false if the note was deleted on that side.
+func (g *GitSync) stage(c Conflict, n int) ([]byte, bool) {
+	rel, err := g.rel(c.Path)
+	if err != nil {
+		return nil, false
+	}
+	return g.git().Stage(rel, n)
+}
+
+// stageResolved records paths as resolved, whether they exist or not
+func (g *GitSync) stageResolved(paths ...string) error {
+	rels := make([]string, len(paths))
+	for i, path := range paths {
+		rel, err := g.rel(path)
+		if err != nil {
+			return err
+		}
+		rels[i] = rel
+	}
+	if err := g.git().Add(rels...); err != nil {
+		return fmt.Errorf("git add: %w", err)
+	}
+	return nil
+}
+
+// markedFiles returns the changed files that still contain conflict
+// markers, so they are never committed. Markers in fenced code blocks are
+// taken as quoted, so notes can show them.
+func (g *GitSync) markedFiles() ([]Conflict, error) {
+	changes, err := g.git().Changes()
+	if err != nil {
+		return nil, fmt.Errorf("git status: %w", err)
+	}
+
+	var marked []Conflict
+	for _, entry := range changes {
+		rel := entry[3:]
+		content, err := os.ReadFile(filepath.Join(g.dataDir, rel))
+		if err != nil {
+			continue
+		}
+		if plain, err := Decrypt(content); err == nil {
+			content = plain
+		}
+		if conflictMarkers(content, true) {
+			marked = append(marked, g.conflict(rel))
+		}
+	}
+	return marked, nil
+}
+
+// HasConflictMarkers reports whether content holds a complete git conflict
+// block: "<<<<<<<", "=======" and ">>>>>>>" lines, in that order.
+func HasConflictMarkers(content []byte) bool {
+	return conflictMarkers(content, false)
+}
+
+// conflictMarkers is HasConflictMarkers, ignoring the lines of fenced code
+// blocks when skipFences is set
+func conflictMarkers(content []byte, skipFences bool) bool {
+	state := 0
+	inFence := false
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if skipFences {
+			trimmed := strings.TrimSpace(line)
+			if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+				inFence = !inFence
+				state = 0
+				continue
+			}
+			if inFence {
+				continue
+			}
+		}
+		switch {
+		case state == 0 && strings.HasPrefix(line, "<<<<<<< "):
+			state = 1
+		case state == 1 && strings.TrimRight(line, " \r") == "=======":
+			state = 2
+		case state == 2 && strings.HasPrefix(line, ">>>>>>> "):
+			return true
+		}
+	}
+	return false
+}
+
+// theirsPath returns a free path next to path for the remote version of a
+// note, e.g. "20260101-120000-plan-theirs.md".
+func theirsPath(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext) + theirsSuffix
+	target := base + ext
+	for i := 2; exists(target); i++ {
+		target = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+	return target
+}
@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeRepoFile(t *testing.T, dir, rel, content string) {
+	t.Helper()
+	path := filepath.Join(dir, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func cloneRepo(t *testing.T, bare string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "clone")
+	if out, err := exec.Command("git", "clone", "-q", bare, dir).CombinedOutput(); err != nil {
+		t.Fatalf("git clone: %v\n%s", err, out)
+	}
+	gitCmd(t, dir, "config", "user.email", "test@test.com")
+	gitCmd(t, dir, "config", "user.name", "Test")
+	return dir
+}
+
+// newConflictedSync returns a GitSync whose pull left work/plan.md
+// conflicted: both machines edited it after a common commit.
+func newConflictedSync(t *testing.T, engine string) (*GitSync, Conflict) {
+	t.Helper()
+	requireGit(t)
+
+	bare := newBareRepo(t)
+	mine := newLocalRepo(t)
+	gitCmd(t, mine, "remote", "add", "origin", bare)
+	writeRepoFile(t, mine, "work/plan.md", "# Plan\n\nbase\n")
+	gitCmd(t, mine, "add", "-A")
+	gitCmd(t, mine, "commit", "-q", "-m", "base")
+	gitCmd(t, mine, "push", "-q", "origin", "main")
+
+	theirs := cloneRepo(t, bare)
+	writeRepoFile(t, theirs, "work/plan.md", "# Plan\n\ntheirs\n")
+	gitCmd(t, theirs, "commit", "-q", "-am", "theirs")
+	gitCmd(t, theirs, "push", "-q", "origin", "main")
+
+	writeRepoFile(t, mine, "work/plan.md", "# Plan\n\nmine\n")
+	gitCmd(t, mine, "commit", "-q", "-am", "mine")
+
+	g := newTestSync(t, engine, mine, bare)
+	g.Synchronize()
+	err := g.Wait()
+	if !IsConflict(err) {
+		t.Fatalf("Wait() = %v, want a conflict error", err)
+	}
+
+	conflicts := err.(*ConflictError).Conflicts
+	if len(conflicts) != 1 || conflicts[0].Rel() != "work/plan.md" {
+		t.Fatalf("conflicts = %v, want work/plan.md", conflicts)
+	}
+	return g, conflicts[0]
+}
+
+func TestSynchronize_DetectsConflicts(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine string) {
+		g, c := newConflictedSync(t, engine)
+
+		if c.Path != filepath.Join(g.dataDir, "work", "plan.md") || c.Collection != "work" || c.Name != "plan.md" {
+			t.Errorf("conflict = %+v", c)
+		}
+
+		// The next commit must not go through while the note is unmerged.
+		err := g.CommitAndPush("edit")
+		if !IsConflict(err) {
+			t.Fatalf("CommitAndPush() = %v, want a conflict error", err)
+		}
+		if !strings.Contains(err.Error(), "work/plan.md") {
+			t.Errorf("error %q does not name the note", err)
+		}
+	})
+}
+
+func TestResolve(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine string) {
+		tests := []struct {
+			how     Resolution
+			content string
+			extra   string // Content of the separate remote note, if any
+		}{
+			{ResolveOurs, "mine", ""},
+			{ResolveTheirs, "theirs", ""},
+			{ResolveBoth, "mine", "theirs"},
+		}
+
+		for _, tt := range tests {
+			g, c := newConflictedSync(t, engine)
+
+			written, err := g.Resolve(c, tt.how)
+			if err != nil {
+				t.Fatalf("Resolve(%d) error: %v", tt.how, err)
+			}
+
+			content, _ := os.ReadFile(c.Path)
+			if !strings.Contains(string(content), tt.content) || HasConflictMarkers(content) {
+				t.Errorf("Resolve(%d): note = %q, want %q", tt.how, content, tt.content)
+			}
+
+			extra := filepath.Join(g.dataDir, "work", "plan-theirs.md")
+			if tt.extra != "" {
+				if len(written) != 2 || written[1] != extra {
+					t.Errorf("Resolve(%d): written = %v, want the theirs note too", tt.how, written)
+				}
+				if content, _ := os.ReadFile(extra); !strings.Contains(string(content), tt.extra) {
+					t.Errorf("Resolve(%d): theirs note = %q", tt.how, content)
+				}
+			} else if exists(extra) {
+				t.Errorf("Resolve(%d) created %s", tt.how, extra)
+			}
+
+			if conflicts, _ := g.Conflicts(); len(conflicts) != 0 {
+				t.Errorf("Resolve(%d): still conflicted: %v", tt.how, conflicts)
+			}
+			if err := g.CommitAndPush("merge"); err != nil {
+				t.Errorf("Resolve(%d): CommitAndPush error: %v", tt.how, err)
+			}
+			if g.Merging() {
+				t.Errorf("Resolve(%d): merge was not concluded", tt.how)
+			}
+		}
+	})
+}
+
+func TestMarkResolved(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine string) {
+		g, c := newConflictedSync(t, engine)
+
+		if err := g.MarkResolved(c); err == nil {
+			t.Fatal("MarkResolved accepted a note with conflict markers")
+		}
+
+		writeRepoFile(t, g.dataDir, "work/plan.md", "# Plan\n\nmine and theirs\n")
+		if err := g.MarkResolved(c); err != nil {
+			t.Fatalf("MarkResolved error: %v", err)
+		}
+		if err := g.CommitAndPush("merge"); err != nil {
+			t.Fatalf("CommitAndPush error: %v", err)
+		}
+	})
+}
+
+func TestCommitAndPush_RefusesConflictMarkers(t *testing.T) {
+	requireGit(t)
+	local := newLocalRepo(t)
+	writeRepoFile(t, local, "work/plan.md", "# Plan\n")
+	gitCmd(t, local, "add", "-A")
+	gitCmd(t, local, "commit", "-q", "-m", "init")
+
+	writeRepoFile(t, local, "work/plan.md", "<<<<<<< HEAD\nmine\n=======\ntheirs\n>>>>>>> origin/main\n")
+
+	g := &GitSync{dataDir: local, remote: "origin", branch: "main"}
+	if err := g.CommitAndPush("edit"); !IsConflict(err) {
+		t.Fatalf("CommitAndPush() = %v, want a conflict error", err)
+	}
+}
+
+func TestHasConflictMarkers(t *testing.T) {
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{"<<<<<<< HEAD\na\n=======\nb\n>>>>>>> main\n", true},
+		{"Title\n=======\n\ntext\n", false},
+		{"<<<<<<< HEAD\na\n", false},
+		{"plain note\n", false},
+	}
+	for _, tt := range tests {
+		if got := HasConflictMarkers([]byte(tt.content)); got != tt.want {
+			t.Errorf("HasConflictMarkers(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestMarkedFiles(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine string) {
+		requireGit(t)
+		local := newLocalRepo(t)
+		writeRepoFile(t, local, "work/plan.md", "# Plan\n\nbase\n")
+		writeRepoFile(t, local, "work/git.md", "# Git\n")
+		gitCmd(t, local, "add", "-A")
+		gitCmd(t, local, "commit", "-q", "-m", "init")
+
+		// A renamed note still holding markers is reported by its new path
+		gitCmd(t, local, "mv", "work/plan.md", "work/kickoff.md")
+		writeRepoFile(t, local, "work/kickoff.md", "# Plan\n\n<<<<<<< ours\nmine\n=======\ntheirs\n>>>>>>> theirs\n")
+		gitCmd(t, local, "add", "-A")
+		// A note quoting markers in a code block is not
+		writeRepoFile(t, local, "work/git.md", "# Git\n\n```\n<<<<<<< HEAD\na\n=======\nb\n>>>>>>> main\n```\n")
+
+		g := newTestSync(t, engine, local, "")
+		marked, err := g.markedFiles()
+		if err != nil {
+			t.Fatalf("markedFiles: %v", err)
+		}
+		if len(marked) != 1 || marked[0].Rel() != "work/kickoff.md" {
+			t.Errorf("markedFiles = %+v, want work/kickoff.md", marked)
+		}
+	})
+}
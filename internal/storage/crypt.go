@@ -0,0 +1,439 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gcaixeta/marginalia/internal/crypt"
+)
+
+// cacheManifest is the file, in the cache dir, recording which stored
+// file each cached note was decrypted from
+const cacheManifest = ".manifest.json"
+
+// cachedNote is what the cache remembers about a decrypted note
+type cachedNote struct {
+	Collection string    `json:"collection"`
+	Name       string    `json:"name"`
+	ModTime    time.Time `json:"mtime"` // Of the stored file
+	Size       int64     `json:"size"`  // Of the stored file
+}
+
+// CryptStore keeps notes encrypted in the data dir, optionally under
+// obfuscated file names. Collection names stay readable.
+//
+// Listed notes are decrypted into a private cache, outside the data dir,
+// and FileItem.Path points there: search, links and everything else that
+// reads notes by path keep working. Writes go through the store, which
+// encrypts them; the cache is only ever read.
+type CryptStore struct {
+	root      string
+	cache     string
+	key       *crypt.Key
+	obfuscate bool
+
+	mu       sync.Mutex
+	manifest map[string]cachedNote // By stored path relative to root
+}
+
+// NewCryptStore returns a store of notes encrypted with key in dataDir,
+// caching their plaintext in cacheDir
+func NewCryptStore(dataDir, cacheDir string, key *crypt.Key, obfuscate bool) (*CryptStore, error) {
+	if err := os.MkdirAll(cacheDir, 0700); err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(cacheDir, 0700); err != nil {
+		return nil, err
+	}
+	return &CryptStore{root: dataDir, cache: cacheDir, key: key, obfuscate: obfuscate}, nil
+}
+
+// CacheDir creates a new directory, only the user can read, for a command
+// to cache decrypted notes in: in $XDG_RUNTIME_DIR, which is usually in
+// memory, or else in the system temp directory. Each command gets its own
+// and must Wipe it before exiting, so no plaintext outlives the command.
+func CacheDir() (string, error) {
+	return os.MkdirTemp(os.Getenv("XDG_RUNTIME_DIR"), "marginalia-*")
+}
+
+// Encrypted returns the store in use when it is encrypted, or nil
+func Encrypted() *CryptStore {
+	c, _ := storeOverride.(*CryptStore)
+	return c
+}
+
+// PlainDir returns the directory holding the notes as plain files: the
+// decrypted cache of an encrypted store, or else DataDir
+func PlainDir() (string, error) {
+	if c := Encrypted(); c != nil {
+		return c.cache, nil
+	}
+	return DataDir()
+}
+
+// NotePath returns where a note is stored in the data dir, which differs
+// from file.Path for encrypted notes
+func NotePath(file FileItem) string {
+	if c := Encrypted(); c != nil {
+		return c.path(file.Collection, file.Name)
+	}
+	return file.Path
+}
+
+// Decrypt returns the content of a note as read from the data dir or its
+// history, decrypting it when it is encrypted
+func Decrypt(data []byte) ([]byte, error) {
+	c := Encrypted()
+	if c == nil || !crypt.IsSealed(data) {
+		return data, nil
+	}
+	_, content, err := c.key.Open(data)
+	return content, err
+}
+
+// sealPlaintext encrypts the plain notes left in dataDir, so backups only
+// ever see ciphertext
+func sealPlaintext(dataDir string) error {
+	c := Encrypted()
+	if c == nil || c.root != dataDir {
+		return nil
+	}
+	_, err := c.SealAll()
+	return err
+}
+
+// storedName returns the file name a note is stored under
+func (c *CryptStore) storedName(collection, name string) string {
+	if c.obfuscate {
+		return c.key.ObfuscatedName(collection, name)
+	}
+	return name
+}
+
+func (c *CryptStore) path(collection, name string) string {
+	return filepath.Join(c.root, collection, c.storedName(collection, name))
+}
+
+func (c *CryptStore) cachePath(collection, name string) string {
+	return filepath.Join(c.cache, collection, name)
+}
+
+func (c *CryptStore) Collections() ([]string, error) {
+	return NewLocalStore(c.root).Collections()
+}
+
+func (c *CryptStore) CreateCollection(name string) error {
+	return NewLocalStore(c.root).CreateCollection(name)
+}
+
+// List decrypts the notes changed since the last call into the cache and
+// returns them with their cached paths
+func (c *CryptStore) List() ([]FileItem, error) {
+	stored, err := NewLocalStore(c.root).List()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	manifest := c.loadManifest()
+	fresh := map[string]cachedNote{}
+	files := []FileItem{}
+	for _, file := range stored {
+		rel := file.Collection + "/" + file.Name
+		note, ok := manifest[rel]
+		if !ok || !note.ModTime.Equal(file.ModTime) || note.Size != file.Size || !exists(c.cachePath(note.Collection, note.Name)) {
+			if note, err = c.decryptToCache(file); err != nil {
+				continue // Unreadable, e.g. sealed with another key
+			}
+		}
+		fresh[rel] = note
+
+		path := c.cachePath(note.Collection, note.Name)
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		files = append(files, FileItem{
+			Path:       path,
+			Name:       note.Name,
+			Collection: note.Collection,
+			ModTime:    file.ModTime,
+			Size:       info.Size(),
+		})
+	}
+
+	// Drop what was deleted, moved or renamed since
+	kept := map[string]bool{}
+	for _, note := range fresh {
+		kept[c.cachePath(note.Collection, note.Name)] = true
+	}
+	for _, note := range manifest {
+		if path := c.cachePath(note.Collection, note.Name); !kept[path] {
+			crypt.Wipe(path)
+		}
+	}
+	c.manifest = fresh
+	return files, c.saveManifest()
+}
+
+// decryptToCache writes the plaintext of a stored note to the cache. A
+// note stored under a name that is not its own, such as a conflict copy
+// made by a backup, is named after its file.
+func (c *CryptStore) decryptToCache(file FileItem) (cachedNote, error) {
+	data, err := os.ReadFile(file.Path)
+	if err != nil {
+		return cachedNote{}, err
+	}
+	name, content := file.Name, data
+	if crypt.IsSealed(data) {
+		var sealedName string
+		if sealedName, content, err = c.key.Open(data); err != nil {
+			return cachedNote{}, err
+		}
+		if c.storedName(file.Collection, sealedName) == file.Name {
+			name = sealedName
+		}
+	}
+
+	note := cachedNote{Collection: file.Collection, Name: name, ModTime: file.ModTime, Size: file.Size}
+	path := c.cachePath(note.Collection, note.Name)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return cachedNote{}, err
+	}
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		return cachedNote{}, err
+	}
+	return note, os.Chtimes(path, file.ModTime, file.ModTime)
+}
+
+func (c *CryptStore) loadManifest() map[string]cachedNote {
+	if c.manifest != nil {
+		return c.manifest
+	}
+	manifest := map[string]cachedNote{}
+	if data, err := os.ReadFile(filepath.Join(c.cache, cacheManifest)); err == nil {
+		json.Unmarshal(data, &manifest)
+	}
+	return manifest
+}
+
+func (c *CryptStore) saveManifest() error {
+	data, err := json.Marshal(c.manifest)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(c.cache, cacheManifest), data, 0600)
+}
+
+func (c *CryptStore) Read(collection, name string) ([]byte, error) {
+	if err := checkNames(collection, name); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(c.path(collection, name))
+	if err != nil {
+		return nil, err
+	}
+	if !crypt.IsSealed(data) {
+		return data, nil
+	}
+	_, content, err := c.key.Open(data)
+	return content, err
+}
+
+func (c *CryptStore) Write(collection, name string, content []byte) error {
+	if err := checkNames(collection, name); err != nil {
+		return err
+	}
+	return writeFileAtomic(c.path(collection, name), c.key.Seal(name, content))
+}
+
+func (c *CryptStore) Create(collection, name string, content []byte) (FileItem, error) {
+	if err := checkNames(collection, name); err != nil {
+		return FileItem{}, err
+	}
+	path := c.path(collection, name)
+	if exists(path) {
+		return FileItem{}, fmt.Errorf("%s/%s: %w", collection, name, fs.ErrExist)
+	}
+	if err := writeFileAtomic(path, c.key.Seal(name, content)); err != nil {
+		return FileItem{}, err
+	}
+	return c.item(collection, name, content)
+}
+
+// Move re-encrypts the note under its new name, since the name is part of
+// the ciphertext
+func (c *CryptStore) Move(collection, name, toCollection, toName string) (FileItem, error) {
+	if err := checkNames(collection, name); err != nil {
+		return FileItem{}, err
+	}
+	if err := checkNames(toCollection, toName); err != nil {
+		return FileItem{}, err
+	}
+
+	content, err := c.Read(collection, name)
+	if err != nil {
+		return FileItem{}, err
+	}
+	from, to := c.path(collection, name), c.path(toCollection, toName)
+	if from != to {
+		if exists(to) {
+			return FileItem{}, fmt.Errorf("%s/%s: %w", toCollection, toName, fs.ErrExist)
+		}
+		if err := writeFileAtomic(to, c.key.Seal(toName, content)); err != nil {
+			return FileItem{}, err
+		}
+		if err := os.Remove(from); err != nil {
+			return FileItem{}, err
+		}
+	}
+	return c.item(toCollection, toName, content)
+}
+
+func (c *CryptStore) Delete(collection, name string) error {
+	if err := checkNames(collection, name); err != nil {
+		return err
+	}
+	if err := os.Remove(c.path(collection, name)); err != nil {
+		return err
+	}
+	crypt.Wipe(c.cachePath(collection, name))
+	return nil
+}
+
+// item refreshes the cached copy of a note just written and describes it
+func (c *CryptStore) item(collection, name string, content []byte) (FileItem, error) {
+	info, err := os.Stat(c.path(collection, name))
+	if err != nil {
+		return FileItem{}, err
+	}
+	file := FileItem{
+		Path:       c.cachePath(collection, name),
+		Name:       name,
+		Collection: collection,
+		ModTime:    info.ModTime(),
+		Size:       int64(len(content)),
+	}
+	if err := os.MkdirAll(filepath.Dir(file.Path), 0700); err != nil {
+		return FileItem{}, err
+	}
+	return file, os.WriteFile(file.Path, content, 0600)
+}
+
+// Edit decrypts the note cached at path into a temporary file, calls edit
+// with it and encrypts back what changed, unless edit fails. The
+// temporary file, and
+// anything the editor left next to it, is wiped afterwards.
+func (c *CryptStore) Edit(path string, edit func(tmp string) error) error {
+	rel, err := filepath.Rel(c.cache, path)
+	if err != nil {
+		return err
+	}
+	collection, name, ok := strings.Cut(filepath.ToSlash(rel), "/")
+	if !ok {
+		return fmt.Errorf("%s is not an encrypted note", path)
+	}
+	content, err := c.Read(collection, name)
+	if err != nil {
+		return err
+	}
+
+	updated, err := c.editTemp(name, content, edit)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(updated, content) {
+		return nil
+	}
+	if err := c.Write(collection, name, updated); err != nil {
+		return err
+	}
+	_, err = c.item(collection, name, updated)
+	return err
+}
+
+// Wipe overwrites and deletes every decrypted note in the cache, then
+// removes the cache itself
+func (c *CryptStore) Wipe() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err := filepath.WalkDir(c.cache, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			crypt.Wipe(path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	c.manifest = nil
+	return os.RemoveAll(c.cache)
+}
+
+// editTemp writes content to a temporary file called name in the cache,
+// calls edit on it and returns what it then holds, unless edit fails. The
+// file is wiped afterwards, along with anything the editor left next to it.
+func (c *CryptStore) editTemp(name string, content []byte, edit func(tmp string) error) ([]byte, error) {
+	dir, err := os.MkdirTemp(c.cache, ".edit-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			crypt.Wipe(filepath.Join(dir, entry.Name()))
+		}
+		os.RemoveAll(dir)
+	}()
+
+	tmp := filepath.Join(dir, name)
+	if err := os.WriteFile(tmp, content, 0600); err != nil {
+		return nil, err
+	}
+	if err := edit(tmp); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(tmp)
+}
+
+// SealAll encrypts the notes still stored in plain text, such as those
+// written before encryption was enabled, and moves them under obfuscated
+// names when those are on. It returns how many notes it encrypted.
+func (c *CryptStore) SealAll() (int, error) {
+	stored, err := NewLocalStore(c.root).List()
+	if err != nil {
+		return 0, err
+	}
+	sealed := 0
+	for _, file := range stored {
+		data, err := os.ReadFile(file.Path)
+		if err != nil {
+			return sealed, err
+		}
+		if crypt.IsSealed(data) {
+			continue
+		}
+		to := c.path(file.Collection, file.Name)
+		if to != file.Path && exists(to) {
+			return sealed, fmt.Errorf("%s/%s: %w", file.Collection, file.Name, fs.ErrExist)
+		}
+		if err := writeFileAtomic(to, c.key.Seal(file.Name, data)); err != nil {
+			return sealed, err
+		}
+		if to != file.Path {
+			if err := os.Remove(file.Path); err != nil {
+				return sealed, err
+			}
+		}
+		sealed++
+	}
+	return sealed, nil
+}
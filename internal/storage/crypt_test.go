@@ -0,0 +1,339 @@
+package storage
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gcaixeta/marginalia/internal/crypt"
+)
+
+// newTestCryptStore returns an encrypted store over a new data dir,
+// installed with UseStore for the duration of the test
+func newTestCryptStore(t *testing.T, obfuscate bool) *CryptStore {
+	t.Helper()
+	dataDir := t.TempDir()
+	key, err := crypt.Unlock(dataDir, "", func(bool) (string, error) { return "secret", nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewCryptStore(dataDir, filepath.Join(t.TempDir(), "cache"), key, obfuscate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	UseStore(c)
+	t.Cleanup(func() { UseStore(nil) })
+	return c
+}
+
+func TestCryptStore(t *testing.T) {
+	for _, obfuscate := range []bool{false, true} {
+		c := newTestCryptStore(t, obfuscate)
+
+		file, err := c.Create("work", "plan.md", []byte("# Plan\n"))
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		stored := c.path("work", "plan.md")
+		data, err := os.ReadFile(stored)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !crypt.IsSealed(data) || bytes.Contains(data, []byte("Plan")) {
+			t.Errorf("obfuscate=%v: the note is stored in plain text", obfuscate)
+		}
+		if got := filepath.Base(stored) == "plan.md"; got == obfuscate {
+			t.Errorf("obfuscate=%v: stored as %s", obfuscate, filepath.Base(stored))
+		}
+
+		// Listed notes are read from the decrypted cache
+		files, err := c.List()
+		if err != nil || len(files) != 1 {
+			t.Fatalf("List = %+v, %v", files, err)
+		}
+		if files[0].Name != "plan.md" || files[0].Path != file.Path || !strings.HasPrefix(files[0].Path, c.cache) {
+			t.Errorf("listed %+v, want plan.md in the cache", files[0])
+		}
+		if got := readFile(t, files[0].Path); got != "# Plan\n" {
+			t.Errorf("cached content = %q", got)
+		}
+
+		if err := c.Write("work", "plan.md", []byte("# Plan\n\nmore\n")); err != nil {
+			t.Fatal(err)
+		}
+		content, err := c.Read("work", "plan.md")
+		if err != nil || string(content) != "# Plan\n\nmore\n" {
+			t.Errorf("Read = %q, %v", content, err)
+		}
+
+		moved, err := c.Move("work", "plan.md", "home", "todo.md")
+		if err != nil {
+			t.Fatalf("Move: %v", err)
+		}
+		if exists(stored) {
+			t.Error("Move left the old note behind")
+		}
+		files, _ = c.List()
+		if len(files) != 1 || files[0].Path != moved.Path || exists(file.Path) {
+			t.Errorf("after Move, List = %+v and old cache entry exists = %v", files, exists(file.Path))
+		}
+
+		if err := c.Delete("home", "todo.md"); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := c.Read("home", "todo.md"); !IsNotExist(err) {
+			t.Errorf("Read after Delete: err = %v", err)
+		}
+		if exists(moved.Path) {
+			t.Error("Delete left the decrypted copy in the cache")
+		}
+	}
+}
+
+func TestCryptStore_SealAll(t *testing.T) {
+	c := newTestCryptStore(t, true)
+	writeRepoFile(t, c.root, "work/plan.md", "# Plan\n")
+
+	sealed, err := c.SealAll()
+	if err != nil || sealed != 1 {
+		t.Fatalf("SealAll = %d, %v, want 1", sealed, err)
+	}
+	if exists(filepath.Join(c.root, "work", "plan.md")) {
+		t.Error("the plain note is still there")
+	}
+	content, err := c.Read("work", "plan.md")
+	if err != nil || string(content) != "# Plan\n" {
+		t.Errorf("Read = %q, %v", content, err)
+	}
+	if sealed, _ := c.SealAll(); sealed != 0 {
+		t.Errorf("second SealAll encrypted %d notes again", sealed)
+	}
+}
+
+func TestCryptStore_Edit(t *testing.T) {
+	c := newTestCryptStore(t, false)
+	file, err := c.Create("work", "plan.md", []byte("# Plan\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tmp string
+	err = c.Edit(file.Path, func(path string) error {
+		tmp = path
+		if got := readFile(t, path); got != "# Plan\n" {
+			t.Errorf("editor got %q", got)
+		}
+		os.WriteFile(path, []byte("# Plan\n\nedited\n"), 0600)
+		os.WriteFile(path+".swp", []byte("swap"), 0600)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Edit: %v", err)
+	}
+	if exists(tmp) || exists(filepath.Dir(tmp)) {
+		t.Error("Edit left the temporary file behind")
+	}
+	content, _ := c.Read("work", "plan.md")
+	if string(content) != "# Plan\n\nedited\n" {
+		t.Errorf("after Edit, Read = %q", content)
+	}
+	if got := readFile(t, file.Path); got != "# Plan\n\nedited\n" {
+		t.Errorf("after Edit, cached content = %q", got)
+	}
+
+	// A failed editor, e.g. :cq in vim, discards the edit
+	err = c.Edit(file.Path, func(path string) error {
+		os.WriteFile(path, []byte("aborted\n"), 0600)
+		return os.ErrInvalid
+	})
+	if err == nil {
+		t.Error("Edit hid the editor's error")
+	}
+	if content, _ := c.Read("work", "plan.md"); string(content) != "# Plan\n\nedited\n" {
+		t.Errorf("after a failed edit, Read = %q", content)
+	}
+}
+
+func TestTrash_Encrypted(t *testing.T) {
+	c := newTestCryptStore(t, true)
+	file, err := c.Create("work", "plan.md", []byte("# Plan\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trash := NewTrash(c.root)
+	if _, err := trash.Put(file); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if exists(file.Path) {
+		t.Error("the decrypted copy survived the trash")
+	}
+	infos, _ := filepath.Glob(filepath.Join(c.root, TrashDir, "work", "*"+trashInfoExt))
+	if len(infos) != 1 || strings.Contains(readFile(t, infos[0]), "plan.md") {
+		t.Errorf("trash records = %v, want one without the note name", infos)
+	}
+
+	items, err := trash.List()
+	if err != nil || len(items) != 1 || items[0].Name != "plan.md" {
+		t.Fatalf("List = %+v, %v", items, err)
+	}
+	if _, err := trash.Restore(items[0]); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if content, err := c.Read("work", "plan.md"); err != nil || string(content) != "# Plan\n" {
+		t.Errorf("restored note = %q, %v", content, err)
+	}
+}
+
+func TestCommitAndPush_EncryptsNotes(t *testing.T) {
+	requireGit(t)
+	c := newTestCryptStore(t, false)
+	bare := newBareRepo(t)
+	gitCmd(t, c.root, "init", "-b", "main")
+	gitCmd(t, c.root, "config", "user.email", "test@example.com")
+	gitCmd(t, c.root, "config", "user.name", "Test")
+	gitCmd(t, c.root, "remote", "add", "origin", bare)
+
+	// Written behind the store's back, e.g. by another tool
+	writeRepoFile(t, c.root, "work/plan.md", "# Plan\n")
+	g := newTestSync(t, EngineExec, c.root, bare)
+	if err := g.CommitAndPush("add: plan"); err != nil {
+		t.Fatalf("CommitAndPush: %v", err)
+	}
+
+	out, err := exec.Command("git", "-C", bare, "show", "main:work/plan.md").Output()
+	if err != nil {
+		t.Fatalf("git show: %v", err)
+	}
+	if !crypt.IsSealed(out) {
+		t.Errorf("pushed note is not encrypted: %q", out)
+	}
+	out, _ = exec.Command("git", "-C", bare, "ls-tree", "--name-only", "main").Output()
+	if !strings.Contains(string(out), crypt.ParamsFile) {
+		t.Errorf("the encryption parameters were not committed: %s", out)
+	}
+}
+
+func TestLog_Encrypted(t *testing.T) {
+	requireGit(t)
+	c := newTestCryptStore(t, true)
+	gitCmd(t, c.root, "init", "-q", "-b", "main")
+	gitCmd(t, c.root, "config", "user.email", "test@example.com")
+	gitCmd(t, c.root, "config", "user.name", "Test")
+	file, err := c.Create("work", "plan.md", []byte("# Plan\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gitCmd(t, c.root, "add", "-A")
+	gitCmd(t, c.root, "commit", "-q", "-m", "add plan")
+
+	forEachEngine(t, func(t *testing.T, engine string) {
+		g := newTestSync(t, engine, c.root, "")
+		// The listed path is the decrypted copy in the cache, outside the repo
+		revisions, err := g.Log(NotePath(file), 0)
+		if err != nil || len(revisions) != 1 || revisions[0].Subject != "add plan" {
+			t.Errorf("Log = %+v, %v", revisions, err)
+		}
+	})
+}
+
+func TestMergeByHand_Encrypted(t *testing.T) {
+	requireGit(t)
+	c := newTestCryptStore(t, true)
+	gitCmd(t, c.root, "init", "-q", "-b", "main")
+	gitCmd(t, c.root, "config", "user.email", "test@example.com")
+	gitCmd(t, c.root, "config", "user.name", "Test")
+	commit := func(content, msg string) {
+		if err := c.Write("work", "plan.md", []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+		gitCmd(t, c.root, "add", "-A")
+		gitCmd(t, c.root, "commit", "-q", "-m", msg)
+	}
+	commit("# Plan\n\nbase\n", "base")
+	gitCmd(t, c.root, "checkout", "-q", "-b", "other")
+	commit("# Plan\n\ntheirs\n", "theirs")
+	gitCmd(t, c.root, "checkout", "-q", "main")
+	commit("# Plan\n\nmine\n", "mine")
+	exec.Command("git", "-C", c.root, "merge", "-q", "other").Run()
+
+	g := newTestSync(t, EngineExec, c.root, "")
+	conflicts, err := g.Conflicts()
+	if err != nil || len(conflicts) != 1 {
+		t.Fatalf("Conflicts = %+v, %v", conflicts, err)
+	}
+
+	var seen string
+	err = g.MergeByHand(conflicts[0], func(path string) error {
+		seen = readFile(t, path)
+		return nil
+	})
+	if err == nil {
+		t.Error("MergeByHand accepted a merge with conflict markers")
+	}
+	if want := "<<<<<<< ours\n# Plan\n\nmine\n=======\n# Plan\n\ntheirs\n>>>>>>> theirs\n"; seen != want {
+		t.Errorf("editor got %q, want %q", seen, want)
+	}
+
+	err = g.MergeByHand(conflicts[0], func(path string) error {
+		return os.WriteFile(path, []byte("# Plan\n\nmine and theirs\n"), 0600)
+	})
+	if err != nil {
+		t.Fatalf("MergeByHand: %v", err)
+	}
+	if content, err := c.Read("work", "plan.md"); err != nil || string(content) != "# Plan\n\nmine and theirs\n" {
+		t.Errorf("merged note = %q, %v", content, err)
+	}
+	if conflicts, _ := g.Conflicts(); len(conflicts) != 0 {
+		t.Errorf("still conflicted: %+v", conflicts)
+	}
+	if marked, err := g.markedFiles(); err != nil || len(marked) != 0 {
+		t.Errorf("markedFiles = %+v, %v", marked, err)
+	}
+}
+
+func TestCryptStore_Wipe(t *testing.T) {
+	c := newTestCryptStore(t, false)
+	file, err := c.Create("work", "plan.md", []byte("# Plan\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exists(file.Path) {
+		t.Fatal("the note was not cached")
+	}
+
+	if err := c.Wipe(); err != nil {
+		t.Fatalf("Wipe: %v", err)
+	}
+	if exists(c.cache) {
+		t.Error("Wipe left the cache behind")
+	}
+	if content, err := c.Read("work", "plan.md"); err != nil || string(content) != "# Plan\n" {
+		t.Errorf("stored note after Wipe = %q, %v", content, err)
+	}
+}
+
+func TestCacheDir(t *testing.T) {
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	dir, err := CacheDir()
+	if err != nil || filepath.Dir(dir) != runtimeDir {
+		t.Errorf("CacheDir = %q, %v, want a directory in %s", dir, err, runtimeDir)
+	}
+	if other, _ := CacheDir(); other == dir {
+		t.Error("caches are shared between commands")
+	}
+
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	dir, err = CacheDir()
+	if err != nil {
+		t.Fatalf("CacheDir without a runtime dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if info, err := os.Stat(dir); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("cache mode = %v, %v, want 0700", info.Mode().Perm(), err)
+	}
+}
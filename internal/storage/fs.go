@@ -1,9 +1,10 @@
 package storage
 
 import (
-	"io/fs"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 )
@@ -17,32 +18,19 @@ type FileItem struct {
 	Size           int64     // File size in bytes
 }
 
+// FindFilePath returns the paths of the notes whose file name contains
+// fileName, case-insensitively.
 func FindFilePath(fileName string) ([]string, error) {
-	var foundFiles []string
-	dataDir, err := DataDir()
+	files, err := ListAllFiles()
 	if err != nil {
 		return nil, err
 	}
 
-	// filepath.WalkDir traverses the directory tree rooted at rootDir, calling the walkFn for each file/directory.
-	err = filepath.WalkDir(dataDir, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return nil // returning nil allows walking to continue in other branches
-		}
-
-		if d.IsDir() && strings.HasPrefix(d.Name(), ".") {
-			return fs.SkipDir
-		}
-
-		// Check if the current entry is a regular file and its name contains the search term (case-insensitive)
-		if !d.IsDir() && strings.Contains(strings.ToLower(d.Name()), strings.ToLower(fileName)) {
-			foundFiles = append(foundFiles, path)
+	var foundFiles []string
+	for _, file := range files {
+		if strings.Contains(strings.ToLower(file.Name), strings.ToLower(fileName)) {
+			foundFiles = append(foundFiles, file.Path)
 		}
-		return nil
-	})
-
-	if err != nil {
-		return nil, err
 	}
 
 	return foundFiles, nil
@@ -52,7 +40,24 @@ func EnsureDir(path string) error {
 	return os.MkdirAll(path, 0755)
 }
 
+// dataDirOverride replaces the default data dir when set by UseDataDir
+var dataDirOverride string
+
+// UseDataDir makes DataDir return dir instead of the default location.
+// An empty dir restores the default.
+func UseDataDir(dir string) {
+	dataDirOverride = dir
+}
+
+// DataDir returns the directory holding the collections, creating it if
+// needed: the one set by UseDataDir or ~/.local/share/marginalia/collections.
 func DataDir() (string, error) {
+	if dataDirOverride != "" {
+		if err := os.MkdirAll(dataDirOverride, 0755); err != nil {
+			return "", err
+		}
+		return dataDirOverride, nil
+	}
 
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -70,50 +75,11 @@ func DataDir() (string, error) {
 
 // ListAllFiles returns all files in all collections with their metadata
 func ListAllFiles() ([]FileItem, error) {
-	dataDir, err := DataDir()
+	store, err := Store()
 	if err != nil {
 		return nil, err
 	}
-
-	var files []FileItem
-
-	err = filepath.WalkDir(dataDir, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return nil // Skip entries with errors
-		}
-
-		if d.IsDir() && strings.HasPrefix(d.Name(), ".") {
-			return fs.SkipDir
-		}
-
-		// Only process regular files (not directories)
-		if !d.IsDir() {
-			info, err := d.Info()
-			if err != nil {
-				return nil // Skip if we can't get info
-			}
-
-			// Get the collection name (parent directory name)
-			parentDir := filepath.Dir(path)
-			collectionName := filepath.Base(parentDir)
-
-			files = append(files, FileItem{
-				Path:       path,
-				Name:       d.Name(),
-				Collection: collectionName,
-				ModTime:    info.ModTime(),
-				Size:       info.Size(),
-			})
-		}
-
-		return nil
-	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	return files, nil
+	return store.List()
 }
 
 // FindFiles returns files matching a search term with their metadata
@@ -139,3 +105,65 @@ func FindFiles(searchTerm string) ([]FileItem, error) {
 
 	return matchingFiles, nil
 }
+
+// FilterByCollection returns the files that belong to the given collection.
+// An empty collection name returns all files.
+func FilterByCollection(files []FileItem, collection string) []FileItem {
+	if collection == "" {
+		return files
+	}
+
+	filtered := []FileItem{}
+	for _, file := range files {
+		if file.Collection == collection {
+			filtered = append(filtered, file)
+		}
+	}
+
+	return filtered
+}
+
+// SortFiles sorts files in place by "name", "mtime" or "size".
+// Ties are broken by collection and name so the output is stable.
+func SortFiles(files []FileItem, by string, reverse bool) error {
+	var less func(a, b FileItem) bool
+
+	switch by {
+	case "", "name":
+		less = func(a, b FileItem) bool {
+			if a.Collection != b.Collection {
+				return a.Collection < b.Collection
+			}
+			return a.Name < b.Name
+		}
+	case "mtime":
+		less = func(a, b FileItem) bool {
+			return a.ModTime.Before(b.ModTime)
+		}
+	case "size":
+		less = func(a, b FileItem) bool {
+			return a.Size < b.Size
+		}
+	default:
+		return fmt.Errorf("unknown sort key %q (expected name, mtime or size)", by)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		a, b := files[i], files[j]
+		if reverse {
+			a, b = b, a
+		}
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		if a.Collection != b.Collection {
+			return a.Collection < b.Collection
+		}
+		return a.Name < b.Name
+	})
+
+	return nil
+}
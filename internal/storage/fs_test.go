@@ -1,9 +1,43 @@
 package storage
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
+func TestUseDataDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "vault")
+	UseDataDir(dir)
+	defer UseDataDir("")
+
+	got, err := DataDir()
+	if err != nil {
+		t.Fatalf("DataDir() error = %v", err)
+	}
+	if got != dir {
+		t.Errorf("DataDir() = %q, want %q", got, dir)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("DataDir() did not create %s: %v", dir, err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "work"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "work", "a.md"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := ListAllFiles()
+	if err != nil {
+		t.Fatalf("ListAllFiles() error = %v", err)
+	}
+	if len(files) != 1 || files[0].Collection != "work" {
+		t.Errorf("ListAllFiles() = %v, want the vault's single note", files)
+	}
+}
+
 func TestListAllFiles(t *testing.T) {
 	files, err := ListAllFiles()
 	if err != nil {
@@ -60,3 +94,61 @@ func TestFindFiles(t *testing.T) {
 		})
 	}
 }
+
+func TestSortFiles(t *testing.T) {
+	now := time.Now()
+	files := []FileItem{
+		{Name: "b.md", Collection: "work", ModTime: now.Add(-time.Hour), Size: 30},
+		{Name: "a.md", Collection: "work", ModTime: now, Size: 10},
+		{Name: "c.md", Collection: "journal", ModTime: now.Add(-2 * time.Hour), Size: 20},
+	}
+
+	tests := []struct {
+		by      string
+		reverse bool
+		want    []string
+	}{
+		{by: "name", want: []string{"c.md", "a.md", "b.md"}},
+		{by: "mtime", want: []string{"c.md", "b.md", "a.md"}},
+		{by: "mtime", reverse: true, want: []string{"a.md", "b.md", "c.md"}},
+		{by: "size", want: []string{"a.md", "c.md", "b.md"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			sorted := append([]FileItem(nil), files...)
+			if err := SortFiles(sorted, tt.by, tt.reverse); err != nil {
+				t.Fatalf("SortFiles() error = %v", err)
+			}
+			for i, name := range tt.want {
+				if sorted[i].Name != name {
+					t.Errorf("position %d: got %s, want %s", i, sorted[i].Name, name)
+				}
+			}
+		})
+	}
+
+	if err := SortFiles(files, "color", false); err == nil {
+		t.Error("SortFiles() expected error for unknown sort key")
+	}
+}
+
+func TestFilterByCollection(t *testing.T) {
+	files := []FileItem{
+		{Name: "a.md", Collection: "work"},
+		{Name: "b.md", Collection: "journal"},
+	}
+
+	if got := FilterByCollection(files, ""); len(got) != 2 {
+		t.Errorf("empty collection: got %d files, want 2", len(got))
+	}
+
+	got := FilterByCollection(files, "journal")
+	if len(got) != 1 || got[0].Name != "b.md" {
+		t.Errorf("journal: got %v", got)
+	}
+
+	if got := FilterByCollection(files, "missing"); got == nil || len(got) != 0 {
+		t.Errorf("missing collection: got %v, want empty slice", got)
+	}
+}
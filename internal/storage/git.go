@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// Git engines, selected with backup.git.engine
+const (
+	EngineExec  = "exec"   // Shells out to the git binary
+	EngineGoGit = "go-git" // Pure Go, for machines without git
+)
+
+// gitEngine carries out the repository operations GitSync needs. Paths
+// are relative to the data dir and use forward slashes.
+type gitEngine interface {
+	// Init creates the repository on branch with remote pointing at url
+	Init(branch, remote, url string) error
+	// Pull fetches branch and merges it, leaving conflicts in place
+	Pull(remote, branch string) error
+	Push(remote, branch string) error
+
+	// AddAll stages every change, including deletions
+	AddAll() error
+	// Add stages paths whether they exist or not, marking conflicts resolved
+	Add(paths ...string) error
+	// Changes lists the changed files as porcelain "XY path" entries. The
+	// path of a renamed or copied file is its new one.
+	Changes() ([]string, error)
+	Commit(message string) error
+	// Merging reports whether a merge is waiting for its commit
+	Merging() bool
+
+	// Conflicts lists the paths left unmerged by the last pull
+	Conflicts() ([]string, error)
+	// Stage returns a conflicted path at one side of the merge: 2 is ours
+	// and 3 is theirs. ok is false if the path was deleted on that side.
+	Stage(path string, n int) (content []byte, ok bool)
+
+	Tracked(path string) bool
+	// Move renames a tracked path and stages the rename
+	Move(from, to string) error
+
+	// Count returns the number of commits reachable from to but not from
+	// from; an empty from counts every commit reachable from to.
+	Count(from, to string) (int, error)
+	HasCommits() bool
+	// Log returns the commits touching path, newest first, following
+	// renames. Each revision's Path is the name at that commit.
+	Log(path string, limit int) ([]Revision, error)
+	Diff(rev Revision) (string, error)
+	Show(rev Revision) ([]byte, error)
+}
+
+// newGitEngine returns the named engine working on dir. Without a name
+// the git binary is used when it is installed.
+func newGitEngine(name, dir string) (gitEngine, error) {
+	switch name {
+	case "":
+		if _, err := exec.LookPath("git"); err != nil {
+			return &goGit{dir: dir}, nil
+		}
+		return &execGit{dir: dir}, nil
+	case EngineExec:
+		return &execGit{dir: dir}, nil
+	case EngineGoGit:
+		return &goGit{dir: dir}, nil
+	default:
+		return nil, fmt.Errorf("unknown git engine %q (want %q or %q)", name, EngineExec, EngineGoGit)
+	}
+}
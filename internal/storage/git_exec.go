@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// execGit is the gitEngine that runs the git binary
+type execGit struct {
+	dir string
+}
+
+func (e *execGit) run(args ...string) error {
+	cmdArgs := append([]string{"-C", e.dir}, args...)
+	cmd := exec.Command("git", cmdArgs...)
+	return runWithStderr(cmd)
+}
+
+func (e *execGit) runSilent(args ...string) error {
+	cmdArgs := append([]string{"-C", e.dir}, args...)
+	cmd := exec.Command("git", cmdArgs...)
+	cmd.Stdin = nil
+	cmd.Env = append(os.Environ(),
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_SSH_COMMAND=ssh -o BatchMode=yes",
+	)
+	return runWithStderr(cmd)
+}
+
+func (e *execGit) output(args ...string) ([]byte, error) {
+	cmdArgs := append([]string{"-C", e.dir}, args...)
+	return exec.Command("git", cmdArgs...).Output()
+}
+
+// runWithStderr runs cmd and adds what it printed on stderr to its error
+func runWithStderr(cmd *exec.Cmd) error {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+func (e *execGit) Init(branch, remote, url string) error {
+	if err := e.runSilent("init", "-b", branch); err != nil {
+		return fmt.Errorf("git init: %w", err)
+	}
+	if err := e.runSilent("remote", "add", remote, url); err != nil {
+		return fmt.Errorf("git remote add: %w", err)
+	}
+	return nil
+}
+
+func (e *execGit) Pull(remote, branch string) error {
+	// Merge rather than rebase so a conflict leaves every note in a
+	// state `margi resolve` understands.
+	return e.runSilent("pull", "--no-rebase", "--no-edit", remote, branch)
+}
+
+func (e *execGit) Push(remote, branch string) error {
+	return e.run("push", remote, branch)
+}
+
+func (e *execGit) AddAll() error {
+	return e.run("add", "-A")
+}
+
+func (e *execGit) Add(paths ...string) error {
+	args := append([]string{"add", "-A", "--"}, paths...)
+	return e.runSilent(args...)
+}
+
+func (e *execGit) Changes() ([]string, error) {
+	out, err := e.output("status", "--porcelain", "-z", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []string
+	entries := strings.Split(string(out), "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		if entry[0] == 'R' || entry[0] == 'C' {
+			i++ // The next entry is the rename source
+		}
+		changes = append(changes, entry)
+	}
+	return changes, nil
+}
+
+func (e *execGit) Commit(message string) error {
+	return e.run("commit", "-m", message)
+}
+
+func (e *execGit) Merging() bool {
+	return e.runSilent("rev-parse", "-q", "--verify", "MERGE_HEAD") == nil
+}
+
+func (e *execGit) Conflicts() ([]string, error) {
+	out, err := e.output("diff", "--name-only", "--diff-filter=U", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, rel := range strings.Split(string(out), "\x00") {
+		if rel != "" {
+			paths = append(paths, rel)
+		}
+	}
+	return paths, nil
+}
+
+func (e *execGit) Stage(path string, n int) ([]byte, bool) {
+	out, err := e.output("show", fmt.Sprintf(":%d:%s", n, path))
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+func (e *execGit) Tracked(path string) bool {
+	return e.runSilent("ls-files", "--error-unmatch", path) == nil
+}
+
+func (e *execGit) Move(from, to string) error {
+	return e.run("mv", from, to)
+}
+
+func (e *execGit) Count(from, to string) (int, error) {
+	rev := to
+	if from != "" {
+		rev = from + ".." + to
+	}
+	out, err := e.output("rev-list", "--count", rev)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(out)))
+}
+
+func (e *execGit) HasCommits() bool {
+	return e.runSilent("rev-parse", "-q", "--verify", "HEAD") == nil
+}
+
+// logFormat separates commits with \x1e and fields with \x1f
+const logFormat = "--format=%x1e%H%x1f%an%x1f%aI%x1f%s"
+
+func (e *execGit) Log(path string, limit int) ([]Revision, error) {
+	args := []string{"log", logFormat}
+	if limit > 0 {
+		args = append(args, fmt.Sprintf("-n%d", limit))
+	}
+	if path != "" {
+		args = append(args, "--follow", "--name-only", "--", path)
+	}
+
+	out, err := e.output(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := []Revision{}
+	for _, chunk := range strings.Split(string(out), "\x1e") {
+		lines := strings.Split(strings.TrimSpace(chunk), "\n")
+		fields := strings.Split(lines[0], "\x1f")
+		if len(fields) != 4 {
+			continue
+		}
+
+		rev := Revision{Hash: fields[0], Author: fields[1], Subject: fields[3]}
+		rev.Date, _ = time.Parse(time.RFC3339, fields[2])
+		if path != "" {
+			for _, line := range lines[1:] {
+				if line = strings.TrimSpace(line); line != "" {
+					rev.Path = line
+				}
+			}
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, nil
+}
+
+func (e *execGit) Diff(rev Revision) (string, error) {
+	args := []string{"show", "--format=", "--no-color", "-M", rev.Hash}
+	if rev.Path != "" {
+		// Both names are needed for the rename to be detected
+		args = append(args, "--", rev.Path)
+		if rev.OldPath != "" {
+			args = append(args, rev.OldPath)
+		}
+	}
+	out, err := e.output(args...)
+	return string(out), err
+}
+
+func (e *execGit) Show(rev Revision) ([]byte, error) {
+	return e.output("show", rev.Hash+":"+rev.Path)
+}
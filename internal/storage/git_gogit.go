@@ -0,0 +1,857 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+func init() {
+	// go-git reaches local remotes through git-upload-pack; serve them in
+	// process so no git binary is needed.
+	client.InstallProtocol("file", localServer{server.NewServer(localRepos{})})
+}
+
+// localServer is go-git's in-process server, minus its failure when a
+// fetch mentions local commits the remote doesn't have
+type localServer struct {
+	transport.Transport
+}
+
+func (s localServer) NewUploadPackSession(ep *transport.Endpoint, auth transport.AuthMethod) (transport.UploadPackSession, error) {
+	session, err := s.Transport.NewUploadPackSession(ep, auth)
+	if err != nil {
+		return nil, err
+	}
+	repo, err := localRepos{}.Load(ep)
+	if err != nil {
+		return nil, err
+	}
+	return knownHaves{session, repo}, nil
+}
+
+// knownHaves drops the commits the remote lacks from a fetch request
+type knownHaves struct {
+	transport.UploadPackSession
+	repo storer.Storer
+}
+
+func (s knownHaves) UploadPack(ctx context.Context, req *packp.UploadPackRequest) (*packp.UploadPackResponse, error) {
+	req.Haves = slices.DeleteFunc(req.Haves, func(hash plumbing.Hash) bool {
+		return s.repo.HasEncodedObject(hash) != nil
+	})
+	return s.UploadPackSession.UploadPack(ctx, req)
+}
+
+// localRepos loads the repositories of file remotes, bare or not
+type localRepos struct{}
+
+func (localRepos) Load(ep *transport.Endpoint) (storer.Storer, error) {
+	dir := ep.Path
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		dir = filepath.Join(dir, ".git")
+	} else if _, err := os.Stat(filepath.Join(dir, "config")); err != nil {
+		return nil, transport.ErrRepositoryNotFound
+	}
+	return filesystem.NewStorage(osfs.New(dir), cache.NewObjectLRUDefault()), nil
+}
+
+// goGit is the gitEngine built on go-git. Pulls fast-forward or merge
+// with mergeText, so no git binary is needed at all.
+type goGit struct {
+	dir string
+}
+
+// goGitMergeFile records the sides of each conflicted note while a merge
+// is in progress. It sits next to MERGE_HEAD, inside .git.
+const goGitMergeFile = "margi-merge.json"
+
+// goGitMerge is the content of goGitMergeFile. A side's blob hash is
+// empty when the note was deleted on that side.
+type goGitMerge struct {
+	Conflicts map[string]goGitSides `json:"conflicts"`
+}
+
+type goGitSides struct {
+	Ours   string `json:"ours,omitempty"`
+	Theirs string `json:"theirs,omitempty"`
+}
+
+// treeEntry is a file of a commit tree
+type treeEntry struct {
+	hash plumbing.Hash
+	mode filemode.FileMode
+}
+
+func (e *goGit) open() (*git.Repository, *git.Worktree, error) {
+	repo, err := git.PlainOpen(e.dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, nil, err
+	}
+	return repo, wt, nil
+}
+
+func (e *goGit) gitPath(name string) string {
+	return filepath.Join(e.dir, ".git", name)
+}
+
+func (e *goGit) Init(branch, remote, url string) error {
+	repo, err := git.PlainInitWithOptions(e.dir, &git.PlainInitOptions{
+		InitOptions: git.InitOptions{DefaultBranch: plumbing.NewBranchReferenceName(branch)},
+	})
+	if err != nil {
+		return fmt.Errorf("git init: %w", err)
+	}
+	if _, err := repo.CreateRemote(&config.RemoteConfig{Name: remote, URLs: []string{url}}); err != nil {
+		return fmt.Errorf("git remote add: %w", err)
+	}
+	return nil
+}
+
+func (e *goGit) Pull(remote, branch string) error {
+	repo, wt, err := e.open()
+	if err != nil {
+		return err
+	}
+	if e.Merging() {
+		return errors.New("a merge is in progress; resolve it before pulling")
+	}
+
+	refSpec := config.RefSpec(fmt.Sprintf("+refs/heads/%[1]s:refs/remotes/%[2]s/%[1]s", branch, remote))
+	err = repo.Fetch(&git.FetchOptions{
+		RemoteName: remote,
+		RefSpecs:   []config.RefSpec{refSpec},
+		Auth:       e.auth(repo, remote),
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return err
+	}
+
+	ref, err := repo.Reference(plumbing.NewRemoteReferenceName(remote, branch), true)
+	if err != nil {
+		return fmt.Errorf("couldn't find remote ref %s", branch)
+	}
+	theirs, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return err
+	}
+	theirsFiles, err := commitFiles(theirs)
+	if err != nil {
+		return err
+	}
+
+	head, err := repo.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		// Nothing committed yet: take the remote branch as it is
+		if err := e.apply(repo, wt, nil, theirsFiles, nil); err != nil {
+			return err
+		}
+		return repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(branch), theirs.Hash))
+	}
+	if err != nil {
+		return err
+	}
+	ours, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+	if ours.Hash == theirs.Hash {
+		return nil
+	}
+	if upToDate, err := theirs.IsAncestor(ours); err != nil || upToDate {
+		return err
+	}
+	oursFiles, err := commitFiles(ours)
+	if err != nil {
+		return err
+	}
+
+	if fastForward, err := ours.IsAncestor(theirs); err != nil {
+		return err
+	} else if fastForward {
+		if err := e.apply(repo, wt, oursFiles, theirsFiles, nil); err != nil {
+			return err
+		}
+		return repo.Storer.SetReference(plumbing.NewHashReference(head.Name(), theirs.Hash))
+	}
+
+	return e.merge(repo, wt, ours, theirs, oursFiles, theirsFiles, remote, branch)
+}
+
+// merge merges theirs into ours file by file. Notes changed on both sides
+// are merged line by line; what can't be merged is written with conflict
+// markers and left for `margi resolve`.
+func (e *goGit) merge(repo *git.Repository, wt *git.Worktree, ours, theirs *object.Commit,
+	oursFiles, theirsFiles map[string]treeEntry, remote, branch string) error {
+	baseFiles := map[string]treeEntry{}
+	if bases, err := ours.MergeBase(theirs); err != nil {
+		return err
+	} else if len(bases) > 0 {
+		if baseFiles, err = commitFiles(bases[0]); err != nil {
+			return err
+		}
+	}
+
+	label := remote + "/" + branch
+	result := map[string]treeEntry{}
+	contents := map[string][]byte{}
+	conflicts := map[string]goGitSides{}
+
+	paths := map[string]bool{}
+	for _, files := range []map[string]treeEntry{baseFiles, oursFiles, theirsFiles} {
+		for path := range files {
+			paths[path] = true
+		}
+	}
+	for path := range paths {
+		base, hasBase := baseFiles[path]
+		mine, hasMine := oursFiles[path]
+		yours, hasYours := theirsFiles[path]
+
+		switch {
+		case hasMine == hasYours && mine.hash == yours.hash,
+			hasBase == hasYours && base.hash == yours.hash:
+			if hasMine {
+				result[path] = mine
+			}
+		case hasBase == hasMine && base.hash == mine.hash:
+			if hasYours {
+				result[path] = yours
+			}
+		case hasMine && hasYours:
+			baseContent, err := e.blob(repo, base.hash, hasBase)
+			if err != nil {
+				return err
+			}
+			mineContent, err := e.blob(repo, mine.hash, true)
+			if err != nil {
+				return err
+			}
+			yoursContent, err := e.blob(repo, yours.hash, true)
+			if err != nil {
+				return err
+			}
+			merged, conflict := mergeText(baseContent, mineContent, yoursContent, "HEAD", label)
+			if conflict {
+				conflicts[path] = goGitSides{Ours: mine.hash.String(), Theirs: yours.hash.String()}
+			}
+			result[path] = treeEntry{mode: mine.mode}
+			contents[path] = merged
+		default:
+			// Deleted on one side and changed on the other: keep the
+			// changed version in place until it is resolved
+			sides := goGitSides{}
+			kept := mine
+			if hasMine {
+				sides.Ours = mine.hash.String()
+			} else {
+				sides.Theirs = yours.hash.String()
+				kept = yours
+			}
+			conflicts[path] = sides
+			result[path] = kept
+		}
+	}
+
+	if err := e.apply(repo, wt, oursFiles, result, contents, slices.Collect(maps.Keys(conflicts))...); err != nil {
+		return err
+	}
+	if err := os.WriteFile(e.gitPath("MERGE_HEAD"), []byte(theirs.Hash.String()+"\n"), 0644); err != nil {
+		return err
+	}
+
+	url := remote
+	if r, err := repo.Remote(remote); err == nil && len(r.Config().URLs) > 0 {
+		url = r.Config().URLs[0]
+	}
+	message := fmt.Sprintf("Merge branch '%s' of %s", branch, url)
+
+	if len(conflicts) > 0 {
+		if err := e.saveMerge(goGitMerge{Conflicts: conflicts}); err != nil {
+			return err
+		}
+		paths := slices.Sorted(maps.Keys(conflicts))
+		return fmt.Errorf("automatic merge failed; fix conflicts in %s and then commit the result", strings.Join(paths, ", "))
+	}
+	if err := e.Commit(message); err != nil {
+		return fmt.Errorf("git commit: %w", err)
+	}
+	return nil
+}
+
+// apply updates the working tree and index from the from files to the to
+// files. contents overrides the blob of a path; unstaged paths are
+// written but left out of the index. Local changes in the way abort
+// before anything is written.
+func (e *goGit) apply(repo *git.Repository, wt *git.Worktree, from, to map[string]treeEntry,
+	contents map[string][]byte, unstaged ...string) error {
+	var changed []string
+	for path, entry := range to {
+		old, ok := from[path]
+		if _, override := contents[path]; override || !ok || old.hash != entry.hash {
+			changed = append(changed, path)
+		}
+	}
+	for path := range from {
+		if _, ok := to[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+
+	status, err := wt.Status()
+	if err != nil {
+		return err
+	}
+	var dirty []string
+	for _, path := range changed {
+		if s, ok := status[path]; ok && (s.Staging != git.Unmodified || s.Worktree != git.Unmodified) {
+			dirty = append(dirty, "  "+path)
+		}
+	}
+	if len(dirty) > 0 {
+		return fmt.Errorf("your local changes to these files would be overwritten by the pull:\n%s\ncommit them first",
+			strings.Join(dirty, "\n"))
+	}
+
+	for _, path := range changed {
+		full := filepath.Join(e.dir, filepath.FromSlash(path))
+		entry, keep := to[path]
+		if !keep {
+			if err := os.Remove(full); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		} else {
+			content, ok := contents[path]
+			if !ok {
+				if content, err = e.blob(repo, entry.hash, true); err != nil {
+					return err
+				}
+			}
+			if err := EnsureDir(filepath.Dir(full)); err != nil {
+				return err
+			}
+			perm := os.FileMode(0644)
+			if entry.mode == filemode.Executable {
+				perm = 0755
+			}
+			if err := os.WriteFile(full, content, perm); err != nil {
+				return err
+			}
+		}
+		if slices.Contains(unstaged, path) {
+			continue
+		}
+		if _, err := wt.Add(path); err != nil {
+			return fmt.Errorf("git add %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// blob returns the content of a blob, or nothing when ok is false
+func (e *goGit) blob(repo *git.Repository, hash plumbing.Hash, ok bool) ([]byte, error) {
+	if !ok {
+		return nil, nil
+	}
+	blob, err := repo.BlobObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	r, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// commitFiles lists the files of a commit's tree
+func commitFiles(c *object.Commit) (map[string]treeEntry, error) {
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]treeEntry{}
+	err = tree.Files().ForEach(func(f *object.File) error {
+		files[f.Name] = treeEntry{hash: f.Hash, mode: f.Mode}
+		return nil
+	})
+	return files, err
+}
+
+func (e *goGit) loadMerge() goGitMerge {
+	var merge goGitMerge
+	if data, err := os.ReadFile(e.gitPath(goGitMergeFile)); err == nil {
+		json.Unmarshal(data, &merge)
+	}
+	return merge
+}
+
+func (e *goGit) saveMerge(merge goGitMerge) error {
+	data, err := json.MarshalIndent(merge, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(e.gitPath(goGitMergeFile), data, 0644)
+}
+
+func (e *goGit) Push(remote, branch string) error {
+	repo, err := git.PlainOpen(e.dir)
+	if err != nil {
+		return err
+	}
+	refSpec := config.RefSpec(fmt.Sprintf("refs/heads/%[1]s:refs/heads/%[1]s", branch))
+	err = repo.Push(&git.PushOptions{
+		RemoteName: remote,
+		RefSpecs:   []config.RefSpec{refSpec},
+		Auth:       e.auth(repo, remote),
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return err
+	}
+
+	// Keep the remote-tracking branch current for ahead/behind counts
+	head, err := repo.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		return nil
+	}
+	return repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewRemoteReferenceName(remote, branch), head.Hash()))
+}
+
+// auth picks credentials for ssh remotes: the ssh agent when one runs,
+// otherwise the default key files. Other remotes need none.
+func (e *goGit) auth(repo *git.Repository, remote string) transport.AuthMethod {
+	r, err := repo.Remote(remote)
+	if err != nil || len(r.Config().URLs) == 0 {
+		return nil
+	}
+	endpoint, err := transport.NewEndpoint(r.Config().URLs[0])
+	if err != nil || endpoint.Protocol != "ssh" {
+		return nil
+	}
+
+	username := endpoint.User
+	if username == "" {
+		username = "git"
+	}
+	if os.Getenv("SSH_AUTH_SOCK") != "" {
+		if auth, err := gitssh.NewSSHAgentAuth(username); err == nil {
+			return auth
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	for _, key := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+		if auth, err := gitssh.NewPublicKeysFromFile(username, filepath.Join(home, ".ssh", key), ""); err == nil {
+			return auth
+		}
+	}
+	return nil
+}
+
+func (e *goGit) AddAll() error {
+	_, wt, err := e.open()
+	if err != nil {
+		return err
+	}
+	return wt.AddWithOptions(&git.AddOptions{All: true})
+}
+
+func (e *goGit) Add(paths ...string) error {
+	_, wt, err := e.open()
+	if err != nil {
+		return err
+	}
+	merge := e.loadMerge()
+	for _, path := range paths {
+		delete(merge.Conflicts, path)
+		if !exists(filepath.Join(e.dir, filepath.FromSlash(path))) && !e.Tracked(path) {
+			continue
+		}
+		if _, err := wt.Add(path); err != nil {
+			return err
+		}
+	}
+	if e.Merging() {
+		return e.saveMerge(merge)
+	}
+	return nil
+}
+
+func (e *goGit) Changes() ([]string, error) {
+	_, wt, err := e.open()
+	if err != nil {
+		return nil, err
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []string
+	for path, s := range status {
+		if s.Staging == git.Unmodified && s.Worktree == git.Unmodified {
+			continue
+		}
+		changes = append(changes, fmt.Sprintf("%c%c %s", s.Staging, s.Worktree, path))
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i][3:] < changes[j][3:] })
+	return changes, nil
+}
+
+func (e *goGit) Commit(message string) error {
+	repo, wt, err := e.open()
+	if err != nil {
+		return err
+	}
+
+	opts := &git.CommitOptions{Author: e.signature(repo)}
+	merging := e.Merging()
+	if merging {
+		data, err := os.ReadFile(e.gitPath("MERGE_HEAD"))
+		if err != nil {
+			return err
+		}
+		head, err := repo.Head()
+		if err != nil {
+			return err
+		}
+		opts.Parents = []plumbing.Hash{head.Hash(), plumbing.NewHash(strings.TrimSpace(string(data)))}
+		opts.AllowEmptyCommits = true
+	}
+
+	if _, err := wt.Commit(message, opts); err != nil {
+		return err
+	}
+	if merging {
+		os.Remove(e.gitPath("MERGE_HEAD"))
+		os.Remove(e.gitPath(goGitMergeFile))
+	}
+	return nil
+}
+
+// signature is the commit author: $GIT_AUTHOR_NAME and $GIT_AUTHOR_EMAIL,
+// then the git config, then the current user at this host
+func (e *goGit) signature(repo *git.Repository) *object.Signature {
+	name, email := os.Getenv("GIT_AUTHOR_NAME"), os.Getenv("GIT_AUTHOR_EMAIL")
+	if cfg, err := repo.ConfigScoped(config.SystemScope); err == nil {
+		if name == "" {
+			name = cfg.User.Name
+		}
+		if email == "" {
+			email = cfg.User.Email
+		}
+	}
+	if name == "" {
+		name = "margi"
+		if u, err := user.Current(); err == nil && u.Username != "" {
+			name = u.Username
+		}
+	}
+	if email == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			host = "localhost"
+		}
+		email = name + "@" + host
+	}
+	return &object.Signature{Name: name, Email: email, When: time.Now()}
+}
+
+func (e *goGit) Merging() bool {
+	return exists(e.gitPath("MERGE_HEAD"))
+}
+
+func (e *goGit) Conflicts() ([]string, error) {
+	if !e.Merging() {
+		return nil, nil
+	}
+	return slices.Sorted(maps.Keys(e.loadMerge().Conflicts)), nil
+}
+
+func (e *goGit) Stage(path string, n int) ([]byte, bool) {
+	sides, ok := e.loadMerge().Conflicts[path]
+	if !ok {
+		return nil, false
+	}
+	hash := sides.Ours
+	if n == 3 {
+		hash = sides.Theirs
+	}
+	if hash == "" {
+		return nil, false
+	}
+	repo, err := git.PlainOpen(e.dir)
+	if err != nil {
+		return nil, false
+	}
+	content, err := e.blob(repo, plumbing.NewHash(hash), true)
+	if err != nil {
+		return nil, false
+	}
+	return content, true
+}
+
+func (e *goGit) Tracked(path string) bool {
+	repo, err := git.PlainOpen(e.dir)
+	if err != nil {
+		return false
+	}
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return false
+	}
+	_, err = idx.Entry(path)
+	return err == nil
+}
+
+func (e *goGit) Move(from, to string) error {
+	_, wt, err := e.open()
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(filepath.Join(e.dir, filepath.FromSlash(from)), filepath.Join(e.dir, filepath.FromSlash(to))); err != nil {
+		return err
+	}
+	if _, err := wt.Add(from); err != nil {
+		return err
+	}
+	_, err = wt.Add(to)
+	return err
+}
+
+func (e *goGit) Count(from, to string) (int, error) {
+	repo, err := git.PlainOpen(e.dir)
+	if err != nil {
+		return 0, err
+	}
+	toHash, err := repo.ResolveRevision(plumbing.Revision(to))
+	if err != nil {
+		return 0, err
+	}
+	exclude := map[plumbing.Hash]bool{}
+	if from != "" {
+		fromHash, err := repo.ResolveRevision(plumbing.Revision(from))
+		if err != nil {
+			return 0, err
+		}
+		if err := walkCommits(repo, *fromHash, func(c *object.Commit) { exclude[c.Hash] = true }); err != nil {
+			return 0, err
+		}
+	}
+
+	n := 0
+	err = walkCommits(repo, *toHash, func(c *object.Commit) {
+		if !exclude[c.Hash] {
+			n++
+		}
+	})
+	return n, err
+}
+
+// walkCommits calls fn for every commit reachable from hash
+func walkCommits(repo *git.Repository, hash plumbing.Hash, fn func(*object.Commit)) error {
+	iter, err := repo.Log(&git.LogOptions{From: hash})
+	if err != nil {
+		return err
+	}
+	return iter.ForEach(func(c *object.Commit) error {
+		fn(c)
+		return nil
+	})
+}
+
+func (e *goGit) HasCommits() bool {
+	repo, err := git.PlainOpen(e.dir)
+	if err != nil {
+		return false
+	}
+	_, err = repo.Head()
+	return err == nil
+}
+
+func (e *goGit) Log(path string, limit int) ([]Revision, error) {
+	repo, err := git.PlainOpen(e.dir)
+	if err != nil {
+		return nil, err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	iter, err := repo.Log(&git.LogOptions{From: head.Hash(), Order: git.LogOrderCommitterTime})
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := []Revision{}
+	current := path
+	err = iter.ForEach(func(c *object.Commit) error {
+		if limit > 0 && len(revisions) == limit {
+			return storer.ErrStop
+		}
+		rev := Revision{
+			Hash:    c.Hash.String(),
+			Author:  c.Author.Name,
+			Date:    c.Author.When,
+			Subject: strings.SplitN(strings.TrimSpace(c.Message), "\n", 2)[0],
+		}
+		if path == "" {
+			revisions = append(revisions, rev)
+			return nil
+		}
+
+		touched, renamedFrom, err := touches(c, current)
+		if err != nil || !touched {
+			return err
+		}
+		rev.Path = current
+		revisions = append(revisions, rev)
+		if renamedFrom != "" {
+			current = renamedFrom
+		}
+		return nil
+	})
+	return revisions, err
+}
+
+// touches reports whether commit c changed path compared to its parents,
+// and the name path had before when c renamed it
+func touches(c *object.Commit, path string) (bool, string, error) {
+	tree, err := c.Tree()
+	if err != nil {
+		return false, "", err
+	}
+	entry, err := tree.FindEntry(path)
+	if err != nil {
+		return false, "", nil
+	}
+
+	var first *object.Tree
+	for i := 0; i < c.NumParents(); i++ {
+		parent, err := c.Parent(i)
+		if err != nil {
+			return false, "", err
+		}
+		parentTree, err := parent.Tree()
+		if err != nil {
+			return false, "", err
+		}
+		if i == 0 {
+			first = parentTree
+		}
+		if old, err := parentTree.FindEntry(path); err == nil && old.Hash == entry.Hash {
+			return false, "", nil
+		}
+	}
+	if first == nil {
+		return true, "", nil
+	}
+	if _, err := first.FindEntry(path); err == nil {
+		return true, "", nil
+	}
+
+	changes, err := object.DiffTreeWithOptions(context.Background(), first, tree, object.DefaultDiffTreeOptions)
+	if err != nil {
+		return false, "", err
+	}
+	for _, change := range changes {
+		if change.To.Name == path && change.From.Name != "" && change.From.Name != path {
+			return true, change.From.Name, nil
+		}
+	}
+	return true, "", nil
+}
+
+func (e *goGit) Diff(rev Revision) (string, error) {
+	repo, err := git.PlainOpen(e.dir)
+	if err != nil {
+		return "", err
+	}
+	c, err := repo.CommitObject(plumbing.NewHash(rev.Hash))
+	if err != nil {
+		return "", err
+	}
+	tree, err := c.Tree()
+	if err != nil {
+		return "", err
+	}
+	var parentTree *object.Tree
+	if c.NumParents() > 0 {
+		parent, err := c.Parent(0)
+		if err != nil {
+			return "", err
+		}
+		if parentTree, err = parent.Tree(); err != nil {
+			return "", err
+		}
+	}
+
+	changes, err := object.DiffTreeWithOptions(context.Background(), parentTree, tree, object.DefaultDiffTreeOptions)
+	if err != nil {
+		return "", err
+	}
+	if rev.Path != "" {
+		names := []string{rev.Path}
+		if rev.OldPath != "" {
+			names = append(names, rev.OldPath)
+		}
+		changes = slices.DeleteFunc(changes, func(change *object.Change) bool {
+			return !slices.Contains(names, change.From.Name) && !slices.Contains(names, change.To.Name)
+		})
+	}
+	patch, err := changes.Patch()
+	if err != nil {
+		return "", err
+	}
+	return patch.String(), nil
+}
+
+func (e *goGit) Show(rev Revision) ([]byte, error) {
+	repo, err := git.PlainOpen(e.dir)
+	if err != nil {
+		return nil, err
+	}
+	c, err := repo.CommitObject(plumbing.NewHash(rev.Hash))
+	if err != nil {
+		return nil, err
+	}
+	f, err := c.File(rev.Path)
+	if err != nil {
+		return nil, err
+	}
+	content, err := f.Contents()
+	return []byte(content), err
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// Revision is a commit of the backup repository
+type Revision struct {
+	Hash    string
+	Author  string
+	Date    time.Time
+	Subject string
+	Path    string // Note path relative to the data dir at this revision; empty for repository logs
+	OldPath string // Note path at the previous revision when this one renamed it
+}
+
+// Short returns the abbreviated commit hash
+func (r Revision) Short() string {
+	if len(r.Hash) > 8 {
+		return r.Hash[:8]
+	}
+	return r.Hash
+}
+
+// Log returns the commits that touched the note at path, newest first,
+// following renames. An empty path returns the repository history. A
+// limit of 0 returns every commit.
+func (g *GitSync) Log(path string, limit int) ([]Revision, error) {
+	g.pullWg.Wait()
+
+	var rel string
+	if path != "" {
+		var err error
+		if rel, err = g.rel(path); err != nil {
+			return nil, err
+		}
+	}
+
+	revisions, err := g.git().Log(rel, limit)
+	if err != nil {
+		if !g.hasCommits() {
+			return []Revision{}, nil
+		}
+		return nil, fmt.Errorf("git log: %w", err)
+	}
+
+	for i := 0; i+1 < len(revisions); i++ {
+		if older := revisions[i+1].Path; older != revisions[i].Path {
+			revisions[i].OldPath = older
+		}
+	}
+
+	return revisions, nil
+}
+
+// Diff returns the changes a revision made to its note as a unified diff.
+// Encrypted notes only have a placeholder.
+func (g *GitSync) Diff(rev Revision) (string, error) {
+	if Encrypted() != nil {
+		return "Diffs are not available for encrypted notes, view the revision instead.\n", nil
+	}
+	diff, err := g.git().Diff(rev)
+	if err != nil {
+		return "", fmt.Errorf("git show: %w", err)
+	}
+	return diff, nil
+}
+
+// hasCommits reports whether the repository has at least one commit
+func (g *GitSync) hasCommits() bool {
+	return g.git().HasCommits()
+}
+
+// Show returns the content of the note as it was at a revision, decrypted
+// when the notes are encrypted
+func (g *GitSync) Show(rev Revision) ([]byte, error) {
+	if rev.Path == "" {
+		return nil, fmt.Errorf("revision %s has no note path", rev.Short())
+	}
+	out, err := g.git().Show(rev)
+	if err != nil {
+		return nil, fmt.Errorf("git show: %w", err)
+	}
+	return Decrypt(out)
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLog_FollowsRenames(t *testing.T) {
+	requireGit(t)
+	local := newLocalRepo(t)
+	writeRepoFile(t, local, "work/plan.md", "# Plan\n\nfirst\n")
+	gitCmd(t, local, "add", "-A")
+	gitCmd(t, local, "commit", "-q", "-m", "add plan")
+	writeRepoFile(t, local, "work/plan.md", "# Plan\n\nsecond\n")
+	writeRepoFile(t, local, "work/other.md", "other\n")
+	gitCmd(t, local, "commit", "-q", "-am", "edit plan")
+	gitCmd(t, local, "add", "-A")
+	gitCmd(t, local, "commit", "-q", "-m", "add other")
+	gitCmd(t, local, "mv", "work/plan.md", "work/roadmap.md")
+	gitCmd(t, local, "commit", "-q", "-m", "rename plan")
+
+	forEachEngine(t, func(t *testing.T, engine string) {
+		testLogFollowsRenames(t, newTestSync(t, engine, local, ""))
+	})
+}
+
+func testLogFollowsRenames(t *testing.T, g *GitSync) {
+	revisions, err := g.Log(g.dataDir+"/work/roadmap.md", 0)
+	if err != nil {
+		t.Fatalf("Log: %v", err)
+	}
+
+	subjects := make([]string, len(revisions))
+	for i, rev := range revisions {
+		subjects[i] = rev.Subject
+	}
+	if got := strings.Join(subjects, ","); got != "rename plan,edit plan,add plan" {
+		t.Fatalf("subjects = %s", got)
+	}
+	if revisions[0].Path != "work/roadmap.md" || revisions[0].OldPath != "work/plan.md" {
+		t.Errorf("rename revision = %+v", revisions[0])
+	}
+	if revisions[1].Path != "work/plan.md" || revisions[1].OldPath != "" {
+		t.Errorf("edit revision = %+v", revisions[1])
+	}
+
+	diff, err := g.Diff(revisions[1])
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	if !strings.Contains(diff, "-first") || !strings.Contains(diff, "+second") || strings.Contains(diff, "other") {
+		t.Errorf("edit diff = %q", diff)
+	}
+
+	diff, err = g.Diff(revisions[0])
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	if !strings.Contains(diff, "rename from work/plan.md") {
+		t.Errorf("rename diff = %q", diff)
+	}
+
+	content, err := g.Show(revisions[2])
+	if err != nil {
+		t.Fatalf("Show: %v", err)
+	}
+	if string(content) != "# Plan\n\nfirst\n" {
+		t.Errorf("Show(add plan) = %q", content)
+	}
+
+	all, err := g.Log("", 2)
+	if err != nil {
+		t.Fatalf("Log repository: %v", err)
+	}
+	if len(all) != 2 || all[0].Subject != "rename plan" || all[0].Path != "" {
+		t.Errorf("repository log = %+v", all)
+	}
+}
+
+func TestLog_EmptyRepository(t *testing.T) {
+	requireGit(t)
+	g := &GitSync{dataDir: newLocalRepo(t), remote: "origin", branch: "main"}
+
+	revisions, err := g.Log("", 0)
+	if err != nil || len(revisions) != 0 {
+		t.Errorf("Log on an empty repository = %v, %v", revisions, err)
+	}
+}
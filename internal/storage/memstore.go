@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"fmt"
+	"io/fs"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemStore is a NoteStore kept in memory, meant for tests. Note paths
+// have the form "collection/name".
+type MemStore struct {
+	mu          sync.Mutex
+	collections map[string]map[string]memNote
+	now         func() time.Time
+}
+
+type memNote struct {
+	content []byte
+	modTime time.Time
+}
+
+// NewMemStore returns an empty in-memory store
+func NewMemStore() *MemStore {
+	return &MemStore{
+		collections: map[string]map[string]memNote{},
+		now:         time.Now,
+	}
+}
+
+func (s *MemStore) item(collection, name string, n memNote) FileItem {
+	return FileItem{
+		Path:       collection + "/" + name,
+		Name:       name,
+		Collection: collection,
+		ModTime:    n.modTime,
+		Size:       int64(len(n.content)),
+	}
+}
+
+func (s *MemStore) Collections() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := []string{}
+	for name := range s.collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *MemStore) CreateCollection(name string) error {
+	if err := checkName("collection", name); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.collections[name] == nil {
+		s.collections[name] = map[string]memNote{}
+	}
+	return nil
+}
+
+func (s *MemStore) List() ([]FileItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files := []FileItem{}
+	for collection, notes := range s.collections {
+		for name, n := range notes {
+			files = append(files, s.item(collection, name, n))
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files, nil
+}
+
+func (s *MemStore) Read(collection, name string) ([]byte, error) {
+	if err := checkNames(collection, name); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := s.collections[collection][name]
+	if !ok {
+		return nil, notFound(collection, name)
+	}
+	return append([]byte(nil), n.content...), nil
+}
+
+func (s *MemStore) Write(collection, name string, content []byte) error {
+	if err := checkNames(collection, name); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.put(collection, name, content)
+	return nil
+}
+
+func (s *MemStore) Create(collection, name string, content []byte) (FileItem, error) {
+	if err := checkNames(collection, name); err != nil {
+		return FileItem{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.collections[collection][name]; ok {
+		return FileItem{}, fmt.Errorf("%s/%s: %w", collection, name, fs.ErrExist)
+	}
+	return s.put(collection, name, content), nil
+}
+
+func (s *MemStore) Move(collection, name, toCollection, toName string) (FileItem, error) {
+	if err := checkNames(collection, name); err != nil {
+		return FileItem{}, err
+	}
+	if err := checkNames(toCollection, toName); err != nil {
+		return FileItem{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := s.collections[collection][name]
+	if !ok {
+		return FileItem{}, notFound(collection, name)
+	}
+	if collection == toCollection && name == toName {
+		return s.item(collection, name, n), nil
+	}
+	if _, ok := s.collections[toCollection][toName]; ok {
+		return FileItem{}, fmt.Errorf("%s/%s: %w", toCollection, toName, fs.ErrExist)
+	}
+
+	delete(s.collections[collection], name)
+	if s.collections[toCollection] == nil {
+		s.collections[toCollection] = map[string]memNote{}
+	}
+	s.collections[toCollection][toName] = n
+	return s.item(toCollection, toName, n), nil
+}
+
+func (s *MemStore) Delete(collection, name string) error {
+	if err := checkNames(collection, name); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.collections[collection][name]; !ok {
+		return notFound(collection, name)
+	}
+	delete(s.collections[collection], name)
+	return nil
+}
+
+// put stores content under collection/name; the caller holds the lock
+func (s *MemStore) put(collection, name string, content []byte) FileItem {
+	if s.collections[collection] == nil {
+		s.collections[collection] = map[string]memNote{}
+	}
+	n := memNote{content: append([]byte(nil), content...), modTime: s.now()}
+	s.collections[collection][name] = n
+	return s.item(collection, name, n)
+}
@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"bytes"
+	"slices"
+)
+
+// maxMergeCells bounds the line table of a three-way merge. Larger files
+// are merged as a single block.
+const maxMergeCells = 4_000_000
+
+// hunk replaces base lines [start, end) with lines
+type hunk struct {
+	start, end int
+	lines      [][]byte
+}
+
+// mergeText merges the changes ours and theirs made to base, line by
+// line. Overlapping changes that differ are written as a conflict block
+// labelled with oursLabel and theirsLabel; conflict reports whether there
+// was one.
+func mergeText(base, ours, theirs []byte, oursLabel, theirsLabel string) (merged []byte, conflict bool) {
+	switch {
+	case bytes.Equal(ours, theirs), bytes.Equal(base, theirs):
+		return ours, false
+	case bytes.Equal(base, ours):
+		return theirs, false
+	}
+
+	baseLines := splitLines(base)
+	oursHunks := diffLines(baseLines, splitLines(ours))
+	theirsHunks := diffLines(baseLines, splitLines(theirs))
+
+	var out bytes.Buffer
+	pos := 0
+	for i, j := 0, 0; i < len(oursHunks) || j < len(theirsHunks); {
+		// Gather the changes of both sides that touch each other
+		start, end := len(baseLines)+1, -1
+		var mine, yours []hunk
+		take := func(h hunk) {
+			start, end = min(start, h.start), max(end, h.end)
+		}
+		if j >= len(theirsHunks) || (i < len(oursHunks) && oursHunks[i].start <= theirsHunks[j].start) {
+			take(oursHunks[i])
+			mine = append(mine, oursHunks[i])
+			i++
+		} else {
+			take(theirsHunks[j])
+			yours = append(yours, theirsHunks[j])
+			j++
+		}
+		for grew := true; grew; {
+			grew = false
+			if i < len(oursHunks) && oursHunks[i].start <= end {
+				take(oursHunks[i])
+				mine = append(mine, oursHunks[i])
+				i++
+				grew = true
+			}
+			if j < len(theirsHunks) && theirsHunks[j].start <= end {
+				take(theirsHunks[j])
+				yours = append(yours, theirsHunks[j])
+				j++
+				grew = true
+			}
+		}
+
+		writeLines(&out, baseLines[pos:start])
+		pos = end
+
+		oursPart := applyHunks(baseLines, mine, start, end)
+		theirsPart := applyHunks(baseLines, yours, start, end)
+		switch {
+		case len(yours) == 0:
+			writeLines(&out, oursPart)
+		case len(mine) == 0:
+			writeLines(&out, theirsPart)
+		case slices.EqualFunc(oursPart, theirsPart, bytes.Equal):
+			writeLines(&out, oursPart)
+		default:
+			conflict = true
+			out.WriteString("<<<<<<< " + oursLabel + "\n")
+			writeBlock(&out, oursPart)
+			out.WriteString("=======\n")
+			writeBlock(&out, theirsPart)
+			out.WriteString(">>>>>>> " + theirsLabel + "\n")
+		}
+	}
+	writeLines(&out, baseLines[pos:])
+
+	return out.Bytes(), conflict
+}
+
+// splitLines splits content after each newline. The last line has no
+// newline when content doesn't end with one.
+func splitLines(content []byte) [][]byte {
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the hunks turning a into b, from a longest common
+// subsequence of their lines
+func diffLines(a, b [][]byte) []hunk {
+	if len(a)*len(b) > maxMergeCells {
+		return []hunk{{start: 0, end: len(a), lines: b}}
+	}
+
+	// lcs[i][j] is the length of the LCS of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if bytes.Equal(a[i], b[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var hunks []hunk
+	var current *hunk
+	flush := func() {
+		if current != nil {
+			hunks = append(hunks, *current)
+			current = nil
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && bytes.Equal(a[i], b[j]):
+			flush()
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			if current == nil {
+				current = &hunk{start: i, end: i}
+			}
+			current.lines = append(current.lines, b[j])
+			j++
+		default:
+			if current == nil {
+				current = &hunk{start: i, end: i}
+			}
+			current.end = i + 1
+			i++
+		}
+	}
+	flush()
+	return hunks
+}
+
+// applyHunks returns base lines [start, end) with hunks applied
+func applyHunks(base [][]byte, hunks []hunk, start, end int) [][]byte {
+	var lines [][]byte
+	pos := start
+	for _, h := range hunks {
+		lines = append(lines, base[pos:h.start]...)
+		lines = append(lines, h.lines...)
+		pos = h.end
+	}
+	return append(lines, base[pos:end]...)
+}
+
+func writeLines(out *bytes.Buffer, lines [][]byte) {
+	for _, line := range lines {
+		out.Write(line)
+	}
+}
+
+// writeBlock writes one side of a conflict, ending it with a newline so
+// the marker that follows starts its own line
+func writeBlock(out *bytes.Buffer, lines [][]byte) {
+	writeLines(out, lines)
+	if n := len(lines); n > 0 && !bytes.HasSuffix(lines[n-1], []byte("\n")) {
+		out.WriteByte('\n')
+	}
+}
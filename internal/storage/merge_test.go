@@ -0,0 +1,35 @@
+package storage
+
+import "testing"
+
+func TestMergeText(t *testing.T) {
+	base := "a\nb\nc\nd\n"
+	tests := []struct {
+		name         string
+		ours, theirs string
+		want         string
+		conflict     bool
+	}{
+		{"separate lines", "A\nb\nc\nd\n", "a\nb\nc\nD\n", "A\nb\nc\nD\n", false},
+		{"same change", "a\nB\nc\nd\n", "a\nB\nc\nd\n", "a\nB\nc\nd\n", false},
+		{"one side", base, "a\nb\nc\nd\ne\n", "a\nb\nc\nd\ne\n", false},
+		{"insert and delete", "a\nnew\nb\nc\nd\n", "a\nb\nc\n", "a\nnew\nb\nc\n", false},
+		{"same line", "a\nmine\nc\nd\n", "a\ntheirs\nc\nd\n",
+			"a\n<<<<<<< HEAD\nmine\n=======\ntheirs\n>>>>>>> origin/main\nc\nd\n", true},
+		{"no final newline", "a\nb\nc\nmine", "a\nb\nc\ntheirs",
+			"a\nb\nc\n<<<<<<< HEAD\nmine\n=======\ntheirs\n>>>>>>> origin/main\n", true},
+	}
+	for _, tt := range tests {
+		got, conflict := mergeText([]byte(base), []byte(tt.ours), []byte(tt.theirs), "HEAD", "origin/main")
+		if string(got) != tt.want || conflict != tt.conflict {
+			t.Errorf("%s: mergeText() = %q, %v, want %q, %v", tt.name, got, conflict, tt.want, tt.conflict)
+		}
+	}
+}
+
+func TestMergeText_NoBase(t *testing.T) {
+	got, conflict := mergeText(nil, []byte("mine\n"), []byte("theirs\n"), "HEAD", "origin/main")
+	if !conflict || !HasConflictMarkers(got) {
+		t.Errorf("mergeText() = %q, %v, want a conflict", got, conflict)
+	}
+}
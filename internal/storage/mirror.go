@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gcaixeta/marginalia/internal/config"
+)
+
+// modifyWindow is how far modification times may differ for a mirrored
+// file to count as unchanged. Some filesystems only keep whole seconds.
+const modifyWindow = time.Second
+
+// MirrorMarker is written into the target directory by the first mirror.
+// Files are only removed from a target that has it, so pointing the backup
+// at the wrong directory can't wipe what was already there.
+const MirrorMarker = ".marginalia-mirror"
+
+// DirBackup mirrors the notes into a directory such as a mounted NAS or
+// USB drive. Like `rsync --delete`, notes removed locally are removed
+// from the mirror too.
+type DirBackup struct {
+	dataDir string
+	target  string
+}
+
+func NewDirBackup(cfg config.DirConfig) (*DirBackup, error) {
+	dataDir, err := DataDir()
+	if err != nil {
+		return nil, err
+	}
+	return &DirBackup{dataDir: dataDir, target: cfg.Path}, nil
+}
+
+// Synchronize does nothing: the mirror only receives changes
+func (d *DirBackup) Synchronize() error {
+	return nil
+}
+
+func (d *DirBackup) Wait() error {
+	return nil
+}
+
+// CommitAndPush brings the mirror up to date
+func (d *DirBackup) CommitAndPush(message string) error {
+	if err := sealPlaintext(d.dataDir); err != nil {
+		return err
+	}
+	if _, _, err := d.Mirror(); err != nil {
+		return fmt.Errorf("mirror: %w", err)
+	}
+	fmt.Println("↑ backed up")
+	return nil
+}
+
+func (d *DirBackup) Move(from, to string) error {
+	return moveFile(from, to)
+}
+
+// Mirror copies new and changed files to the target directory and
+// removes what no longer exists locally. Files with the same size and
+// modification time are skipped. The target must already exist, so an
+// unmounted drive is reported instead of filled in on the wrong disk, and
+// be either empty or marked with MirrorMarker by an earlier mirror.
+func (d *DirBackup) Mirror() (copied, removed int, err error) {
+	if info, err := os.Stat(d.target); err != nil {
+		return 0, 0, fmt.Errorf("backup directory not available: %w", err)
+	} else if !info.IsDir() {
+		return 0, 0, fmt.Errorf("%s is not a directory", d.target)
+	}
+	if err := d.claimTarget(); err != nil {
+		return 0, 0, err
+	}
+
+	files, err := backupFiles(d.dataDir)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	keep := map[string]bool{}
+	for _, file := range files {
+		keep[file.rel] = true
+		dst := filepath.Join(d.target, filepath.FromSlash(file.rel))
+		if file.info.IsDir() {
+			if err := os.MkdirAll(dst, 0755); err != nil {
+				return copied, removed, err
+			}
+			continue
+		}
+		if upToDate(dst, file.info) {
+			continue
+		}
+		if err := copyFile(filepath.Join(d.dataDir, filepath.FromSlash(file.rel)), dst, file.info); err != nil {
+			return copied, removed, err
+		}
+		copied++
+	}
+
+	// Collect first, removing while walking would upset the walk
+	var stale []string
+	err = filepath.WalkDir(d.target, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || path == d.target {
+			return err
+		}
+		rel, err := filepath.Rel(d.target, path)
+		if err != nil {
+			return err
+		}
+		if !strings.ContainsRune(rel, filepath.Separator) && skipBackup(entry.Name()) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !keep[filepath.ToSlash(rel)] {
+			stale = append(stale, path)
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return copied, removed, err
+	}
+	for _, path := range stale {
+		if err := os.RemoveAll(path); err != nil {
+			return copied, removed, err
+		}
+		removed++
+	}
+
+	return copied, removed, nil
+}
+
+// claimTarget writes MirrorMarker into an empty target and refuses a
+// target holding other files without it
+func (d *DirBackup) claimTarget() error {
+	marker := filepath.Join(d.target, MirrorMarker)
+	if _, err := os.Stat(marker); err == nil {
+		return nil
+	}
+	entries, err := os.ReadDir(d.target)
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("%s is not empty and was not created as a mirror; if it is one, create %s in it to confirm", d.target, MirrorMarker)
+	}
+	return os.WriteFile(marker, nil, 0644)
+}
+
+// upToDate reports whether dst matches a source file of the given info
+func upToDate(dst string, info fs.FileInfo) bool {
+	existing, err := os.Stat(dst)
+	if err != nil || !existing.Mode().IsRegular() || existing.Size() != info.Size() {
+		return false
+	}
+	return existing.ModTime().Sub(info.ModTime()).Abs() < modifyWindow
+}
+
+// copyFile copies src to dst through a temporary file, so an interrupted
+// copy never leaves a truncated note behind, and keeps its modification
+// time.
+func copyFile(src, dst string, info fs.FileInfo) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".margi-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := os.Chtimes(tmp.Name(), info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// moveFile renames a note for providers without history to keep
+func moveFile(from, to string) error {
+	if err := EnsureDir(filepath.Dir(to)); err != nil {
+		return err
+	}
+	return os.Rename(from, to)
+}
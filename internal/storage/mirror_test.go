@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirBackup_Mirror(t *testing.T) {
+	data, target := t.TempDir(), t.TempDir()
+	writeRepoFile(t, data, "work/plan.md", "# Plan\n")
+	writeRepoFile(t, data, "journal/day.md", "# Day\n")
+	writeRepoFile(t, data, ".trash/work/old.md", "old\n")
+	writeRepoFile(t, data, ".index/index.json", "{}")
+	if err := os.MkdirAll(filepath.Join(data, "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Left by an earlier mirror
+	writeRepoFile(t, target, MirrorMarker, "")
+	writeRepoFile(t, target, "gone/stale.md", "stale\n")
+
+	d := &DirBackup{dataDir: data, target: target}
+	copied, removed, err := d.Mirror()
+	if err != nil {
+		t.Fatalf("Mirror: %v", err)
+	}
+	if copied != 3 || removed != 1 {
+		t.Errorf("copied, removed = %d, %d, want 3, 1", copied, removed)
+	}
+	for _, rel := range []string{"work/plan.md", "journal/day.md", ".trash/work/old.md", "empty"} {
+		if !exists(filepath.Join(target, rel)) {
+			t.Errorf("expected %s in the mirror", rel)
+		}
+	}
+	for _, rel := range []string{".index", "gone"} {
+		if exists(filepath.Join(target, rel)) {
+			t.Errorf("expected %s to be left out of the mirror", rel)
+		}
+	}
+
+	// Unchanged files are not copied again; deletions propagate
+	os.Remove(filepath.Join(data, "journal", "day.md"))
+	copied, removed, err = d.Mirror()
+	if err != nil {
+		t.Fatalf("Mirror: %v", err)
+	}
+	if copied != 0 || removed != 1 || exists(filepath.Join(target, "journal", "day.md")) {
+		t.Errorf("second mirror: copied, removed = %d, %d", copied, removed)
+	}
+}
+
+func TestDirBackup_MissingTarget(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "unmounted")
+	d := &DirBackup{dataDir: t.TempDir(), target: target}
+	if _, _, err := d.Mirror(); err == nil {
+		t.Fatal("expected an error for a missing backup directory")
+	}
+	if exists(target) {
+		t.Error("Mirror created the missing backup directory")
+	}
+}
+
+func TestDirBackup_ForeignTarget(t *testing.T) {
+	data, target := t.TempDir(), t.TempDir()
+	writeRepoFile(t, data, "work/plan.md", "# Plan\n")
+	writeRepoFile(t, target, "photos/cat.jpg", "cat")
+
+	d := &DirBackup{dataDir: data, target: target}
+	if _, _, err := d.Mirror(); err == nil {
+		t.Fatal("expected an error for a non-empty directory without the marker")
+	}
+	if !exists(filepath.Join(target, "photos", "cat.jpg")) || exists(filepath.Join(target, "work")) {
+		t.Error("Mirror touched a directory it didn't create")
+	}
+
+	// An empty directory is claimed, then mirrored into from then on
+	empty := t.TempDir()
+	d.target = empty
+	if _, _, err := d.Mirror(); err != nil {
+		t.Fatalf("Mirror into an empty directory: %v", err)
+	}
+	if !exists(filepath.Join(empty, MirrorMarker)) {
+		t.Error("expected the marker in the new mirror")
+	}
+	os.Remove(filepath.Join(data, "work", "plan.md"))
+	if _, removed, err := d.Mirror(); err != nil || removed != 1 {
+		t.Errorf("second mirror: removed = %d, err = %v", removed, err)
+	}
+}
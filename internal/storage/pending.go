@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// pushAttempts is how many times a pending push is tried in a row before
+// waiting for the next backoff
+const pushAttempts = 3
+
+// retryDelay is the pause between attempts in a row, doubled each time
+var retryDelay = 500 * time.Millisecond
+
+// retryBackoff is how long to wait before the background retry after
+// attempts failed pushes: one minute, doubling up to an hour.
+func retryBackoff(attempts int) time.Duration {
+	backoff := time.Minute
+	for i := 1; i < attempts && backoff < time.Hour; i++ {
+		backoff *= 2
+	}
+	return min(backoff, time.Hour)
+}
+
+// Pending returns the number of commits a failed push left behind. It is
+// cheap enough to call on every render.
+func (g *GitSync) Pending() int {
+	if g == nil {
+		return 0
+	}
+	return int(g.pending.Load())
+}
+
+// RetryPush pushes the commits left behind by a failed push right away,
+// ignoring the backoff. It returns the number of commits pushed.
+func (g *GitSync) RetryPush() (int, error) {
+	g.pullWg.Wait()
+	return g.retryPending(true)
+}
+
+// retryPending pushes pending commits once their backoff has passed, or
+// immediately when forced. Each round tries a few times in a row to get
+// past brief network hiccups.
+func (g *GitSync) retryPending(force bool) (int, error) {
+	state := g.loadState()
+	if !force && (state.PendingCommits == 0 || time.Now().Before(state.NextRetry)) {
+		return 0, nil
+	}
+
+	ahead, _ := g.ahead()
+	if ahead == 0 {
+		if state.PendingCommits > 0 {
+			g.recordPush(nil) // Pushed by other means, e.g. plain git
+		}
+		return 0, nil
+	}
+
+	var err error
+	for attempt := 0; attempt < pushAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(retryDelay << (attempt - 1))
+		}
+		if err = g.git().Push(g.remote, g.branch); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		err = fmt.Errorf("git push: %w", err)
+		g.recordPush(err)
+		return 0, err
+	}
+	g.recordPush(nil)
+	return ahead, nil
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+// newOfflineSync returns a GitSync with one commit that could not be
+// pushed because the remote was unreachable, and the bare repository
+// that should have received it.
+func newOfflineSync(t *testing.T) (*GitSync, string) {
+	t.Helper()
+	requireGit(t)
+	retryDelay = time.Millisecond
+	t.Cleanup(func() { retryDelay = 500 * time.Millisecond })
+
+	bare := newBareRepo(t)
+	local := newLocalRepo(t)
+	gitCmd(t, local, "remote", "add", "origin", "/nonexistent/repo")
+	writeRepoFile(t, local, "work/a.md", "a\n")
+
+	g := &GitSync{dataDir: local, repo: bare, remote: "origin", branch: "main"}
+	if err := g.CommitAndPush("add a"); err == nil {
+		t.Fatal("expected the push to fail")
+	}
+	return g, bare
+}
+
+func TestPendingAfterFailedPush(t *testing.T) {
+	g, _ := newOfflineSync(t)
+
+	if g.Pending() != 1 {
+		t.Errorf("Pending() = %d, want 1", g.Pending())
+	}
+	state := g.loadState()
+	if state.PendingCommits != 1 || state.PushAttempts != 1 || !state.NextRetry.After(time.Now()) {
+		t.Errorf("state = %+v, want one pending commit and a retry scheduled", state)
+	}
+
+	// The pending count survives a restart
+	restarted := &GitSync{dataDir: g.dataDir, remote: "origin", branch: "main"}
+	restarted.pending.Store(int64(restarted.loadState().PendingCommits))
+	if restarted.Pending() != 1 {
+		t.Errorf("Pending() after restart = %d, want 1", restarted.Pending())
+	}
+}
+
+func TestRetryPending_Backoff(t *testing.T) {
+	g, bare := newOfflineSync(t)
+	gitCmd(t, g.dataDir, "remote", "set-url", "origin", bare)
+
+	// Not due yet: nothing happens
+	if n, err := g.retryPending(false); n != 0 || err != nil {
+		t.Fatalf("retryPending before the backoff = %d, %v", n, err)
+	}
+	if g.Pending() != 1 {
+		t.Fatalf("Pending() = %d, want 1", g.Pending())
+	}
+
+	// Due: the background retry flushes the queue
+	state := g.loadState()
+	state.NextRetry = time.Now().Add(-time.Second)
+	g.saveState(state)
+	if n, err := g.retryPending(false); n != 1 || err != nil {
+		t.Fatalf("retryPending = %d, %v; want 1 commit pushed", n, err)
+	}
+	if g.Pending() != 0 || g.loadState().PushAttempts != 0 {
+		t.Errorf("queue not cleared: pending %d, state %+v", g.Pending(), g.loadState())
+	}
+}
+
+func TestRetryPush_Forced(t *testing.T) {
+	g, bare := newOfflineSync(t)
+
+	if _, err := g.RetryPush(); err == nil {
+		t.Fatal("expected RetryPush to fail while offline")
+	}
+	if attempts := g.loadState().PushAttempts; attempts != 2 {
+		t.Errorf("PushAttempts = %d, want 2", attempts)
+	}
+
+	gitCmd(t, g.dataDir, "remote", "set-url", "origin", bare)
+	n, err := g.RetryPush()
+	if err != nil || n != 1 {
+		t.Fatalf("RetryPush = %d, %v; want 1 commit pushed", n, err)
+	}
+	if g.Pending() != 0 {
+		t.Errorf("Pending() = %d, want 0", g.Pending())
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := map[int]time.Duration{
+		1:  time.Minute,
+		2:  2 * time.Minute,
+		4:  8 * time.Minute,
+		20: time.Hour,
+	}
+	for attempts, want := range tests {
+		if got := retryBackoff(attempts); got != want {
+			t.Errorf("retryBackoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/gcaixeta/marginalia/internal/config"
+	"github.com/gcaixeta/marginalia/internal/crypt"
+)
+
+// Backup is where the notes of a vault are backed up. Commands report
+// every change through it and each provider decides what that means: a
+// git commit, a mirrored directory, a new archive or a WebDAV sync.
+type Backup interface {
+	// Synchronize starts bringing in remote changes in the background
+	Synchronize() error
+	// Wait blocks until the work started by Synchronize finishes and
+	// returns its error
+	Wait() error
+	// CommitAndPush backs up the changes made to the notes. message
+	// describes them, for providers that keep a history.
+	CommitAndPush(message string) error
+	// Move renames a note, keeping its history where the provider has one
+	Move(from, to string) error
+}
+
+// NewBackup returns the provider configured in cfg, or nil when backup is
+// not configured. The result is a true nil, never a nil pointer in the
+// interface.
+func NewBackup(cfg *config.BackupConfig) (Backup, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "git":
+		if cfg.Git.Repo == "" {
+			return nil, nil
+		}
+		g, err := NewGitSync(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return g, nil
+	case "dir":
+		if cfg.Dir.Path == "" {
+			return nil, fmt.Errorf("backup.dir.path is not set")
+		}
+		d, err := NewDirBackup(cfg.Dir)
+		if err != nil {
+			return nil, err
+		}
+		return d, nil
+	case "snapshot":
+		if cfg.Snapshot.Path == "" {
+			return nil, fmt.Errorf("backup.snapshot.path is not set")
+		}
+		s, err := NewSnapshotBackup(cfg.Snapshot)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case "webdav":
+		if cfg.WebDAV.URL == "" {
+			return nil, fmt.Errorf("backup.webdav.url is not set")
+		}
+		w, err := NewWebDAVBackup(cfg.WebDAV)
+		if err != nil {
+			return nil, err
+		}
+		return w, nil
+	default:
+		return nil, fmt.Errorf("unknown backup provider %q", cfg.Provider)
+	}
+}
+
+// skipBackup reports whether a top-level entry of the data dir stays out
+// of file backups. Hidden entries such as the search index and a git
+// repository do, except the trash and the encryption parameters.
+func skipBackup(name string) bool {
+	return strings.HasPrefix(name, ".") && name != TrashDir && name != crypt.ParamsFile
+}
+
+// backupFile is a file or directory of the data dir included in file
+// backups
+type backupFile struct {
+	rel  string // Relative to the data dir, with forward slashes
+	info fs.FileInfo
+}
+
+// backupFiles lists the directories and regular files of dataDir that
+// file backups hold, sorted by path so directories come first
+func backupFiles(dataDir string) ([]backupFile, error) {
+	var files []backupFile
+	err := filepath.WalkDir(dataDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dataDir {
+			return nil
+		}
+		rel, err := filepath.Rel(dataDir, path)
+		if err != nil {
+			return err
+		}
+		if !strings.ContainsRune(rel, filepath.Separator) && skipBackup(d.Name()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, backupFile{rel: filepath.ToSlash(rel), info: info})
+		return nil
+	})
+	return files, err
+}
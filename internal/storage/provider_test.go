@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/gcaixeta/marginalia/internal/config"
+)
+
+func TestNewBackup(t *testing.T) {
+	UseDataDir(t.TempDir())
+	defer UseDataDir("")
+
+	tests := []struct {
+		name    string
+		cfg     config.BackupConfig
+		want    string // Provider type, "" for none
+		wantErr bool
+	}{
+		{"none", config.BackupConfig{}, "", false},
+		{"git without repo", config.BackupConfig{Provider: "git"}, "", false},
+		{"git", config.BackupConfig{Provider: "git", Git: config.GitConfig{Repo: "/remote"}}, "git", false},
+		{"dir", config.BackupConfig{Provider: "dir", Dir: config.DirConfig{Path: "/mnt/nas"}}, "dir", false},
+		{"dir without path", config.BackupConfig{Provider: "dir"}, "", true},
+		{"snapshot", config.BackupConfig{Provider: "snapshot", Snapshot: config.SnapshotConfig{Path: "/mnt/usb"}}, "snapshot", false},
+		{"webdav", config.BackupConfig{Provider: "webdav", WebDAV: config.WebDAVConfig{URL: "https://cloud.example.com/dav/notes"}}, "webdav", false},
+		{"webdav without url", config.BackupConfig{Provider: "webdav"}, "", true},
+		{"webdav bad url", config.BackupConfig{Provider: "webdav", WebDAV: config.WebDAVConfig{URL: "cloud.example.com"}}, "", true},
+		{"unknown", config.BackupConfig{Provider: "s3"}, "", true},
+		{"bad git engine", config.BackupConfig{Provider: "git", Git: config.GitConfig{Repo: "/remote", Engine: "jgit"}}, "", true},
+	}
+	for _, tt := range tests {
+		backup, err := NewBackup(&tt.cfg)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+
+		got := ""
+		switch backup.(type) {
+		case *GitSync:
+			got = "git"
+		case *DirBackup:
+			got = "dir"
+		case *SnapshotBackup:
+			got = "snapshot"
+		case *WebDAVBackup:
+			got = "webdav"
+		}
+		if got != tt.want || (tt.want == "" && backup != nil) {
+			t.Errorf("%s: NewBackup() = %T, want %s", tt.name, backup, tt.want)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package storage
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gcaixeta/marginalia/internal/config"
+)
+
+// Snapshot archives are named margi-20261017-150405.tar.gz after the
+// local time they were taken
+const (
+	snapshotPrefix = "margi-"
+	snapshotLayout = "20060102-150405"
+	snapshotExt    = ".tar.gz"
+)
+
+// Snapshot is an archive written by SnapshotBackup
+type Snapshot struct {
+	Path string
+	Time time.Time
+}
+
+// SnapshotBackup writes a timestamped .tar.gz archive of the notes after
+// every change and prunes old archives by its retention rules
+type SnapshotBackup struct {
+	dataDir string
+	cfg     config.SnapshotConfig
+	now     func() time.Time
+}
+
+func NewSnapshotBackup(cfg config.SnapshotConfig) (*SnapshotBackup, error) {
+	dataDir, err := DataDir()
+	if err != nil {
+		return nil, err
+	}
+	return &SnapshotBackup{dataDir: dataDir, cfg: cfg, now: time.Now}, nil
+}
+
+// Synchronize does nothing: snapshots are only ever written
+func (s *SnapshotBackup) Synchronize() error {
+	return nil
+}
+
+func (s *SnapshotBackup) Wait() error {
+	return nil
+}
+
+// CommitAndPush takes a snapshot of the notes
+func (s *SnapshotBackup) CommitAndPush(message string) error {
+	if err := sealPlaintext(s.dataDir); err != nil {
+		return err
+	}
+	snapshot, err := s.Snapshot()
+	if err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	if snapshot != nil {
+		fmt.Println("↑ backed up")
+	}
+	return nil
+}
+
+func (s *SnapshotBackup) Move(from, to string) error {
+	return moveFile(from, to)
+}
+
+// Snapshot archives the notes and prunes old snapshots. Nothing is
+// written when the notes haven't changed since the latest snapshot, in
+// which case it returns nil.
+func (s *SnapshotBackup) Snapshot() (*Snapshot, error) {
+	if info, err := os.Stat(s.cfg.Path); err != nil {
+		return nil, fmt.Errorf("snapshot directory not available: %w", err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", s.cfg.Path)
+	}
+
+	files, err := backupFiles(s.dataDir)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint := fingerprintFiles(files)
+
+	snapshots, err := s.Snapshots()
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) > 0 && archiveFingerprint(snapshots[0].Path) == fingerprint {
+		return nil, nil
+	}
+
+	now := s.now()
+	snapshot := Snapshot{
+		Path: filepath.Join(s.cfg.Path, snapshotPrefix+now.Format(snapshotLayout)+snapshotExt),
+		Time: now,
+	}
+	if err := s.writeArchive(snapshot.Path, files, fingerprint, now); err != nil {
+		return nil, err
+	}
+
+	snapshots, err = s.Snapshots()
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, s.prune(snapshots)
+}
+
+// writeArchive writes files to a .tar.gz at path through a temporary
+// file. The fingerprint goes in the gzip comment.
+func (s *SnapshotBackup) writeArchive(path string, files []backupFile, fingerprint string, now time.Time) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".margi-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	gz := gzip.NewWriter(tmp)
+	gz.Comment = fingerprint
+	gz.ModTime = now
+	tw := tar.NewWriter(gz)
+
+	for _, file := range files {
+		header, err := tar.FileInfoHeader(file.info, "")
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		header.Name = file.rel
+		if file.info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			tmp.Close()
+			return err
+		}
+		if file.info.IsDir() {
+			continue
+		}
+		if err := copyInto(tw, filepath.Join(s.dataDir, filepath.FromSlash(file.rel))); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func copyInto(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// Snapshots returns the snapshots in the snapshot directory, newest first
+func (s *SnapshotBackup) Snapshots() ([]Snapshot, error) {
+	entries, err := os.ReadDir(s.cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []Snapshot
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, snapshotPrefix) || !strings.HasSuffix(name, snapshotExt) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, snapshotPrefix), snapshotExt)
+		t, err := time.ParseInLocation(snapshotLayout, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, Snapshot{Path: filepath.Join(s.cfg.Path, name), Time: t})
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Time.After(snapshots[j].Time) })
+	return snapshots, nil
+}
+
+// prune deletes the snapshots the retention rules don't keep
+func (s *SnapshotBackup) prune(snapshots []Snapshot) error {
+	keep := retainSnapshots(snapshots, s.cfg)
+	for _, snapshot := range snapshots {
+		if keep[snapshot.Path] {
+			continue
+		}
+		if err := os.Remove(snapshot.Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// retainSnapshots returns the paths of the snapshots to keep, given
+// snapshots newest first: the KeepLast newest, plus the newest snapshot
+// of each of the latest KeepDaily days, KeepWeekly weeks and KeepMonthly
+// months that have one. Without any rule every snapshot is kept.
+func retainSnapshots(snapshots []Snapshot, cfg config.SnapshotConfig) map[string]bool {
+	keep := map[string]bool{}
+	all := cfg.KeepLast == 0 && cfg.KeepDaily == 0 && cfg.KeepWeekly == 0 && cfg.KeepMonthly == 0
+	for i, snapshot := range snapshots {
+		if all || i < cfg.KeepLast {
+			keep[snapshot.Path] = true
+		}
+	}
+
+	byPeriod := func(periods int, period func(time.Time) string) {
+		seen := map[string]bool{}
+		for _, snapshot := range snapshots {
+			if len(seen) == periods {
+				return
+			}
+			key := period(snapshot.Time)
+			if !seen[key] {
+				seen[key] = true
+				keep[snapshot.Path] = true
+			}
+		}
+	}
+	byPeriod(cfg.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") })
+	byPeriod(cfg.KeepWeekly, func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	})
+	byPeriod(cfg.KeepMonthly, func(t time.Time) string { return t.Format("2006-01") })
+
+	return keep
+}
+
+// fingerprintFiles hashes the names, sizes and modification times of
+// files, to tell whether anything changed since a snapshot
+func fingerprintFiles(files []backupFile) string {
+	h := sha256.New()
+	for _, file := range files {
+		fmt.Fprintf(h, "%s\x00%d\x00%d\x00%v\n", file.rel, file.info.Size(), file.info.ModTime().UnixNano(), file.info.IsDir())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// archiveFingerprint returns the fingerprint stored in a snapshot, or ""
+func archiveFingerprint(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return ""
+	}
+	defer gz.Close()
+	return gz.Comment
+}
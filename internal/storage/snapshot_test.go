@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gcaixeta/marginalia/internal/config"
+)
+
+func archiveNames(t *testing.T, path string) []string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, header.Name)
+	}
+	return names
+}
+
+func TestSnapshotBackup_Snapshot(t *testing.T) {
+	data, dir := t.TempDir(), t.TempDir()
+	writeRepoFile(t, data, "work/plan.md", "# Plan\n")
+	writeRepoFile(t, data, ".index/index.json", "{}")
+
+	now := time.Date(2026, 10, 17, 9, 30, 0, 0, time.Local)
+	s := &SnapshotBackup{dataDir: data, cfg: config.SnapshotConfig{Path: dir}, now: func() time.Time { return now }}
+
+	snapshot, err := s.Snapshot()
+	if err != nil || snapshot == nil {
+		t.Fatalf("Snapshot() = %v, %v", snapshot, err)
+	}
+	if filepath.Base(snapshot.Path) != "margi-20261017-093000.tar.gz" {
+		t.Errorf("snapshot name = %s", filepath.Base(snapshot.Path))
+	}
+	if names := archiveNames(t, snapshot.Path); !slices.Equal(names, []string{"work/", "work/plan.md"}) {
+		t.Errorf("archive holds %v", names)
+	}
+
+	// Nothing changed: no new snapshot
+	now = now.Add(time.Hour)
+	if snapshot, err := s.Snapshot(); err != nil || snapshot != nil {
+		t.Errorf("Snapshot() without changes = %v, %v", snapshot, err)
+	}
+
+	writeRepoFile(t, data, "work/todo.md", "todo\n")
+	if snapshot, err := s.Snapshot(); err != nil || snapshot == nil {
+		t.Fatalf("Snapshot() after a change = %v, %v", snapshot, err)
+	}
+	if snapshots, _ := s.Snapshots(); len(snapshots) != 2 || !snapshots[0].Time.Equal(now) {
+		t.Errorf("Snapshots() = %v", snapshots)
+	}
+}
+
+func TestRetainSnapshots(t *testing.T) {
+	day := func(d, hour int) Snapshot {
+		at := time.Date(2026, 10, d, hour, 0, 0, 0, time.Local)
+		return Snapshot{Path: at.Format(snapshotLayout), Time: at}
+	}
+	// Newest first: two on the 17th, one a day back to the 12th, then
+	// last month
+	snapshots := []Snapshot{day(17, 18), day(17, 9), day(16, 9), day(15, 9), day(14, 9), day(13, 9), day(12, 9), day(-20, 9)}
+
+	tests := []struct {
+		cfg  config.SnapshotConfig
+		want []int // Indexes of snapshots kept
+	}{
+		{config.SnapshotConfig{}, []int{0, 1, 2, 3, 4, 5, 6, 7}},
+		{config.SnapshotConfig{KeepLast: 2}, []int{0, 1}},
+		{config.SnapshotConfig{KeepDaily: 3}, []int{0, 2, 3}},
+		{config.SnapshotConfig{KeepLast: 1, KeepMonthly: 2}, []int{0, 7}},
+		{config.SnapshotConfig{KeepWeekly: 2}, []int{0, 7}}, // The 12th to the 17th share a week
+	}
+	for _, tt := range tests {
+		keep := retainSnapshots(snapshots, tt.cfg)
+		var got []int
+		for i, snapshot := range snapshots {
+			if keep[snapshot.Path] {
+				got = append(got, i)
+			}
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%+v: kept %v, want %v", tt.cfg, got, tt.want)
+		}
+	}
+}
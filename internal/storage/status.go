@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// syncStateFile records the outcome of the last pull and push. It lives
+// inside .git so it is never committed.
+const syncStateFile = "margi-sync.json"
+
+// syncState is the content of syncStateFile
+type syncState struct {
+	LastPull      time.Time `json:"last_pull,omitempty"`
+	LastPush      time.Time `json:"last_push,omitempty"`
+	LastPullError string    `json:"last_pull_error,omitempty"`
+	LastPushError string    `json:"last_push_error,omitempty"`
+
+	// Commits a failed push left behind and when to try again
+	PendingCommits int       `json:"pending_commits,omitempty"`
+	PushAttempts   int       `json:"push_attempts,omitempty"`
+	NextRetry      time.Time `json:"next_retry,omitempty"`
+}
+
+// SyncStatus describes the state of the backup repository
+type SyncStatus struct {
+	Repo          string
+	Remote        string
+	Branch        string
+	Ahead         int  // Local commits not pushed yet
+	Behind        int  // Remote commits not pulled yet, as of the last fetch
+	HasUpstream   bool // Whether the remote branch is known locally
+	Uncommitted   []string
+	Conflicts     []Conflict
+	Merging       bool
+	LastPull      time.Time
+	LastPush      time.Time
+	LastPullError string
+	LastPushError string
+	NextRetry     time.Time // When the background retry of a failed push runs next
+}
+
+// Status reports ahead/behind counts, uncommitted notes, conflicts and
+// the time of the last successful pull and push.
+func (g *GitSync) Status() (SyncStatus, error) {
+	g.pullWg.Wait()
+
+	state := g.loadState()
+	status := SyncStatus{
+		Repo:          g.repo,
+		Remote:        g.remote,
+		Branch:        g.branch,
+		LastPull:      state.LastPull,
+		LastPush:      state.LastPush,
+		LastPullError: state.LastPullError,
+		LastPushError: state.LastPushError,
+		NextRetry:     state.NextRetry,
+	}
+
+	status.Ahead, status.HasUpstream = g.ahead()
+	if status.HasUpstream {
+		status.Behind = g.behind()
+	}
+
+	changes, err := g.git().Changes()
+	if err != nil {
+		return status, fmt.Errorf("git status: %w", err)
+	}
+	status.Uncommitted = changes
+
+	if status.Conflicts, err = g.Conflicts(); err != nil {
+		return status, err
+	}
+	status.Merging = g.Merging()
+
+	return status, nil
+}
+
+func (g *GitSync) statePath() string {
+	return filepath.Join(g.dataDir, ".git", syncStateFile)
+}
+
+func (g *GitSync) loadState() syncState {
+	var state syncState
+	if data, err := os.ReadFile(g.statePath()); err == nil {
+		json.Unmarshal(data, &state)
+	}
+	return state
+}
+
+func (g *GitSync) saveState(state syncState) {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return
+	}
+	// Best effort: the state is informational only
+	os.WriteFile(g.statePath(), data, 0644)
+}
+
+// recordPull stores the outcome of a pull. A failed pull keeps the time
+// of the last successful one.
+func (g *GitSync) recordPull(err error) {
+	state := g.loadState()
+	if err != nil {
+		state.LastPullError = err.Error()
+	} else {
+		state.LastPull = time.Now()
+		state.LastPullError = ""
+	}
+	g.saveState(state)
+}
+
+// recordPush stores the outcome of a push. A failure leaves the commits
+// pending and schedules the next background retry.
+func (g *GitSync) recordPush(err error) {
+	state := g.loadState()
+	if err != nil {
+		state.LastPushError = err.Error()
+		state.PendingCommits, _ = g.ahead()
+		state.PushAttempts++
+		state.NextRetry = time.Now().Add(retryBackoff(state.PushAttempts))
+	} else {
+		state.LastPush = time.Now()
+		state.LastPushError = ""
+		state.PendingCommits = 0
+		state.PushAttempts = 0
+		state.NextRetry = time.Time{}
+	}
+	g.pending.Store(int64(state.PendingCommits))
+	g.saveState(state)
+}
+
+// ahead counts the local commits missing from the remote branch. ok is
+// false when the remote branch was never fetched, in which case every
+// local commit counts.
+func (g *GitSync) ahead() (n int, ok bool) {
+	if n, err := g.git().Count(g.remote+"/"+g.branch, "HEAD"); err == nil {
+		return n, true
+	}
+	n, _ = g.git().Count("", "HEAD")
+	return n, false
+}
+
+// behind counts the remote commits not merged yet, as of the last fetch
+func (g *GitSync) behind() int {
+	n, _ := g.git().Count("HEAD", g.remote+"/"+g.branch)
+	return n
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStatus(t *testing.T) {
+	requireGit(t)
+	bare := newBareRepo(t)
+	local := newLocalRepo(t)
+	gitCmd(t, local, "remote", "add", "origin", bare)
+	writeRepoFile(t, local, "work/a.md", "a\n")
+
+	g := &GitSync{dataDir: local, repo: bare, remote: "origin", branch: "main"}
+	if err := g.CommitAndPush("add a"); err != nil {
+		t.Fatalf("CommitAndPush: %v", err)
+	}
+
+	// One more remote commit, then a pull to fetch it
+	other := cloneRepo(t, bare)
+	writeRepoFile(t, other, "work/b.md", "b\n")
+	gitCmd(t, other, "add", "-A")
+	gitCmd(t, other, "commit", "-q", "-m", "add b")
+	gitCmd(t, other, "push", "-q", "origin", "main")
+	g.Synchronize()
+	if err := g.Wait(); err != nil {
+		t.Fatalf("pull: %v", err)
+	}
+
+	// A local commit not pushed and an uncommitted note
+	writeRepoFile(t, local, "work/c.md", "c\n")
+	gitCmd(t, local, "add", "-A")
+	gitCmd(t, local, "commit", "-q", "-m", "add c")
+	writeRepoFile(t, local, "journal/d.md", "d\n")
+
+	status, err := g.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if !status.HasUpstream || status.Ahead != 1 || status.Behind != 0 {
+		t.Errorf("ahead/behind = %d/%d (upstream %v), want 1/0", status.Ahead, status.Behind, status.HasUpstream)
+	}
+	if len(status.Uncommitted) != 1 || !strings.HasSuffix(status.Uncommitted[0], "journal/d.md") {
+		t.Errorf("Uncommitted = %q, want journal/d.md", status.Uncommitted)
+	}
+	if status.LastPull.IsZero() || status.LastPush.IsZero() {
+		t.Errorf("LastPull/LastPush not recorded: %v / %v", status.LastPull, status.LastPush)
+	}
+	if status.LastPullError != "" || status.LastPushError != "" {
+		t.Errorf("unexpected errors: %q / %q", status.LastPullError, status.LastPushError)
+	}
+}
+
+func TestStatus_RecordsPullError(t *testing.T) {
+	requireGit(t)
+	local := newLocalRepo(t)
+	gitCmd(t, local, "remote", "add", "origin", "/nonexistent/repo")
+
+	g := &GitSync{dataDir: local, repo: "/nonexistent/repo", remote: "origin", branch: "main"}
+	g.Synchronize()
+	if err := g.Wait(); err == nil {
+		t.Fatal("expected the pull to fail")
+	}
+
+	status, err := g.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if status.LastPullError == "" || !status.LastPull.IsZero() {
+		t.Errorf("pull error not recorded: %+v", status)
+	}
+	if status.HasUpstream {
+		t.Error("HasUpstream should be false without a fetched remote branch")
+	}
+}
@@ -0,0 +1,261 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// NoteStore is the backend holding a vault's notes. Notes are addressed by
+// collection and file name; FileItem.Path is whatever the backend uses to
+// identify a note and must not be assumed to be on the local filesystem.
+//
+// Missing notes are reported with errors matching fs.ErrNotExist and
+// conflicting names with errors matching fs.ErrExist.
+type NoteStore interface {
+	// Collections returns the collection names, sorted
+	Collections() ([]string, error)
+	// CreateCollection creates an empty collection; existing ones are kept
+	CreateCollection(name string) error
+	// List returns every note in every collection
+	List() ([]FileItem, error)
+	Read(collection, name string) ([]byte, error)
+	// Write replaces the content of a note, creating it if needed
+	Write(collection, name string, content []byte) error
+	// Create adds a new note, failing if one with the same name exists
+	Create(collection, name string, content []byte) (FileItem, error)
+	// Move renames a note, possibly into another collection, failing if
+	// the target name is taken
+	Move(collection, name, toCollection, toName string) (FileItem, error)
+	Delete(collection, name string) error
+}
+
+// storeOverride replaces the local store when set by UseStore
+var storeOverride NoteStore
+
+// UseStore makes Store return s instead of the local store of DataDir.
+// A nil s restores the default.
+func UseStore(s NoteStore) {
+	storeOverride = s
+}
+
+// Store returns the note store commands work on: the one set by UseStore
+// or a LocalStore rooted at DataDir.
+func Store() (NoteStore, error) {
+	if storeOverride != nil {
+		return storeOverride, nil
+	}
+	dataDir, err := DataDir()
+	if err != nil {
+		return nil, err
+	}
+	return NewLocalStore(dataDir), nil
+}
+
+// checkName rejects collection and note names that would escape the
+// store or land in one of its dot directories.
+func checkName(kind, name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid %s name %q", kind, name)
+	}
+	return nil
+}
+
+// CheckCollection rejects a collection name that would escape the store or
+// land in one of its dot directories
+func CheckCollection(name string) error {
+	return checkName("collection", name)
+}
+
+func checkNames(collection, name string) error {
+	if err := checkName("collection", collection); err != nil {
+		return err
+	}
+	return checkName("note", name)
+}
+
+// LocalStore keeps notes as files in one directory per collection
+type LocalStore struct {
+	root string
+}
+
+// NewLocalStore returns a store rooted at dir
+func NewLocalStore(dir string) *LocalStore {
+	return &LocalStore{root: dir}
+}
+
+// Root returns the directory holding the collections
+func (s *LocalStore) Root() string {
+	return s.root
+}
+
+func (s *LocalStore) path(collection, name string) string {
+	return filepath.Join(s.root, collection, name)
+}
+
+func (s *LocalStore) Collections() ([]string, error) {
+	entries, err := os.ReadDir(s.root)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		// Dot directories hold git, the search index and the trash
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *LocalStore) CreateCollection(name string) error {
+	if err := checkName("collection", name); err != nil {
+		return err
+	}
+	return EnsureDir(filepath.Join(s.root, name))
+}
+
+func (s *LocalStore) List() ([]FileItem, error) {
+	files := []FileItem{}
+
+	err := filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip entries with errors
+		}
+
+		if d.IsDir() && strings.HasPrefix(d.Name(), ".") {
+			return fs.SkipDir
+		}
+
+		// Files directly in the root are state files, not notes
+		if !d.IsDir() && filepath.Dir(path) != s.root {
+			info, err := d.Info()
+			if err != nil {
+				return nil // Skip if we can't get info
+			}
+
+			// Get the collection name (parent directory name)
+			parentDir := filepath.Dir(path)
+			collectionName := filepath.Base(parentDir)
+
+			files = append(files, FileItem{
+				Path:       path,
+				Name:       d.Name(),
+				Collection: collectionName,
+				ModTime:    info.ModTime(),
+				Size:       info.Size(),
+			})
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+func (s *LocalStore) Read(collection, name string) ([]byte, error) {
+	if err := checkNames(collection, name); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(s.path(collection, name))
+}
+
+func (s *LocalStore) Write(collection, name string, content []byte) error {
+	if err := checkNames(collection, name); err != nil {
+		return err
+	}
+	if err := EnsureDir(filepath.Join(s.root, collection)); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path(collection, name), content, 0644)
+}
+
+func (s *LocalStore) Create(collection, name string, content []byte) (FileItem, error) {
+	if err := checkNames(collection, name); err != nil {
+		return FileItem{}, err
+	}
+	if err := EnsureDir(filepath.Join(s.root, collection)); err != nil {
+		return FileItem{}, err
+	}
+
+	path := s.path(collection, name)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return FileItem{}, err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return FileItem{}, err
+	}
+	if err := f.Close(); err != nil {
+		return FileItem{}, err
+	}
+
+	return s.stat(collection, name)
+}
+
+func (s *LocalStore) Move(collection, name, toCollection, toName string) (FileItem, error) {
+	if err := checkNames(collection, name); err != nil {
+		return FileItem{}, err
+	}
+	if err := checkNames(toCollection, toName); err != nil {
+		return FileItem{}, err
+	}
+
+	from, to := s.path(collection, name), s.path(toCollection, toName)
+	if from != to {
+		if exists(to) {
+			return FileItem{}, fmt.Errorf("%s/%s: %w", toCollection, toName, fs.ErrExist)
+		}
+		if err := EnsureDir(filepath.Dir(to)); err != nil {
+			return FileItem{}, err
+		}
+		if err := os.Rename(from, to); err != nil {
+			return FileItem{}, err
+		}
+	}
+
+	return s.stat(toCollection, toName)
+}
+
+func (s *LocalStore) Delete(collection, name string) error {
+	if err := checkNames(collection, name); err != nil {
+		return err
+	}
+	return os.Remove(s.path(collection, name))
+}
+
+func (s *LocalStore) stat(collection, name string) (FileItem, error) {
+	path := s.path(collection, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return FileItem{}, err
+	}
+	return FileItem{
+		Path:       path,
+		Name:       name,
+		Collection: collection,
+		ModTime:    info.ModTime(),
+		Size:       info.Size(),
+	}, nil
+}
+
+// notFound reports a missing note in the same shape for every backend
+func notFound(collection, name string) error {
+	return fmt.Errorf("%s/%s: %w", collection, name, fs.ErrNotExist)
+}
+
+// IsNotExist reports whether err means a note or collection is missing
+func IsNotExist(err error) bool {
+	return errors.Is(err, fs.ErrNotExist)
+}
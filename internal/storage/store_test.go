@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+// testStores runs fn against every NoteStore implementation
+func testStores(t *testing.T, fn func(t *testing.T, s NoteStore)) {
+	t.Run("local", func(t *testing.T) { fn(t, NewLocalStore(t.TempDir())) })
+	t.Run("mem", func(t *testing.T) { fn(t, NewMemStore()) })
+}
+
+func TestStore_CreateReadWrite(t *testing.T) {
+	testStores(t, func(t *testing.T, s NoteStore) {
+		file, err := s.Create("work", "a.md", []byte("hello"))
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if file.Collection != "work" || file.Name != "a.md" || file.Size != 5 {
+			t.Errorf("Create returned %+v", file)
+		}
+
+		if _, err := s.Create("work", "a.md", []byte("again")); !errors.Is(err, fs.ErrExist) {
+			t.Errorf("second Create: got %v, want fs.ErrExist", err)
+		}
+
+		if err := s.Write("work", "a.md", []byte("updated")); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		content, err := s.Read("work", "a.md")
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		if string(content) != "updated" {
+			t.Errorf("Read = %q, want %q", content, "updated")
+		}
+
+		if _, err := s.Read("work", "missing.md"); !IsNotExist(err) {
+			t.Errorf("Read missing: got %v, want not exist", err)
+		}
+	})
+}
+
+func TestStore_ListAndCollections(t *testing.T) {
+	testStores(t, func(t *testing.T, s NoteStore) {
+		if err := s.CreateCollection("empty"); err != nil {
+			t.Fatalf("CreateCollection: %v", err)
+		}
+		for _, name := range []string{"a.md", "b.md"} {
+			if _, err := s.Create("work", name, []byte(name)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := s.Create("journal", "c.md", nil); err != nil {
+			t.Fatal(err)
+		}
+
+		names, err := s.Collections()
+		if err != nil {
+			t.Fatalf("Collections: %v", err)
+		}
+		want := []string{"empty", "journal", "work"}
+		if len(names) != len(want) {
+			t.Fatalf("Collections = %v, want %v", names, want)
+		}
+		for i := range want {
+			if names[i] != want[i] {
+				t.Errorf("Collections[%d] = %q, want %q", i, names[i], want[i])
+			}
+		}
+
+		files, err := s.List()
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(files) != 3 {
+			t.Errorf("List returned %d files, want 3", len(files))
+		}
+	})
+}
+
+func TestStore_MoveAndDelete(t *testing.T) {
+	testStores(t, func(t *testing.T, s NoteStore) {
+		if _, err := s.Create("work", "a.md", []byte("a")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Create("journal", "b.md", []byte("b")); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := s.Move("work", "a.md", "journal", "b.md"); !errors.Is(err, fs.ErrExist) {
+			t.Errorf("Move onto existing note: got %v, want fs.ErrExist", err)
+		}
+
+		moved, err := s.Move("work", "a.md", "journal", "a.md")
+		if err != nil {
+			t.Fatalf("Move: %v", err)
+		}
+		if moved.Collection != "journal" || moved.Name != "a.md" {
+			t.Errorf("Move returned %+v", moved)
+		}
+		if _, err := s.Read("work", "a.md"); !IsNotExist(err) {
+			t.Errorf("old location still readable: %v", err)
+		}
+		if content, err := s.Read("journal", "a.md"); err != nil || string(content) != "a" {
+			t.Errorf("Read moved note = %q, %v", content, err)
+		}
+
+		if err := s.Delete("journal", "a.md"); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if err := s.Delete("journal", "a.md"); !IsNotExist(err) {
+			t.Errorf("second Delete: got %v, want not exist", err)
+		}
+	})
+}
+
+func TestStore_RejectsEscapingNames(t *testing.T) {
+	testStores(t, func(t *testing.T, s NoteStore) {
+		for _, tc := range [][2]string{
+			{"..", "a.md"},
+			{"work", "../a.md"},
+			{".trash", "a.md"},
+			{"work", ""},
+		} {
+			if _, err := s.Create(tc[0], tc[1], nil); err == nil {
+				t.Errorf("Create(%q, %q) succeeded, want error", tc[0], tc[1])
+			}
+		}
+	})
+}
+
+func TestUseStore(t *testing.T) {
+	mem := NewMemStore()
+	UseStore(mem)
+	defer UseStore(nil)
+
+	if _, err := mem.Create("work", "a.md", []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ListAllFiles()
+	if err != nil {
+		t.Fatalf("ListAllFiles: %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "work/a.md" {
+		t.Errorf("ListAllFiles = %v, want the in-memory note", files)
+	}
+}
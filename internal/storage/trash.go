@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gcaixeta/marginalia/internal/crypt"
+)
+
+// TrashDir is the directory, relative to the data dir, that holds deleted
+// notes. It starts with a dot so ListAllFiles skips it.
+const TrashDir = ".trash"
+
+// trashInfoExt is the extension of the sidecar file recording where a
+// trashed note came from and when it was deleted.
+const trashInfoExt = ".trashinfo"
+
+// TrashItem is a deleted note waiting in the trash
+type TrashItem struct {
+	Path       string    `json:"-"`          // Full path of the note inside the trash
+	Name       string    `json:"name"`       // Original file name
+	Collection string    `json:"collection"` // Original collection
+	DeletedAt  time.Time `json:"deleted_at"`
+	Size       int64     `json:"-"`
+}
+
+// Trash manages the trash directory of a data dir
+type Trash struct {
+	root  string
+	crypt *CryptStore // Set when the notes are encrypted
+}
+
+// NewTrash returns the trash of the given data dir. The notes of an
+// encrypted store stay encrypted in the trash, and so do the records of
+// where they came from.
+func NewTrash(dataDir string) *Trash {
+	t := &Trash{root: filepath.Join(dataDir, TrashDir)}
+	if c := Encrypted(); c != nil && c.root == dataDir {
+		t.crypt = c
+	}
+	return t
+}
+
+// OpenTrash returns the trash of the default data dir
+func OpenTrash() (*Trash, error) {
+	dataDir, err := DataDir()
+	if err != nil {
+		return nil, err
+	}
+	return NewTrash(dataDir), nil
+}
+
+// Put moves a note into the trash, keeping its collection and deletion time
+func (t *Trash) Put(file FileItem) (TrashItem, error) {
+	dir := filepath.Join(t.root, file.Collection)
+	if err := EnsureDir(dir); err != nil {
+		return TrashItem{}, err
+	}
+
+	src, stored := file.Path, file.Name
+	if t.crypt != nil {
+		src = t.crypt.path(file.Collection, file.Name)
+		stored = filepath.Base(src)
+	}
+
+	// The same name can be trashed twice (deleted, restored, deleted again).
+	base := strings.TrimSuffix(stored, filepath.Ext(stored))
+	target := filepath.Join(dir, stored)
+	for i := 1; exists(target) || exists(target+trashInfoExt); i++ {
+		target = filepath.Join(dir, fmt.Sprintf("%s~%d%s", base, i, filepath.Ext(stored)))
+	}
+
+	item := TrashItem{
+		Path:       target,
+		Name:       file.Name,
+		Collection: file.Collection,
+		DeletedAt:  time.Now(),
+		Size:       file.Size,
+	}
+
+	info, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return TrashItem{}, err
+	}
+	if t.crypt != nil {
+		info = t.crypt.key.Seal("", info)
+	}
+	if err := os.WriteFile(target+trashInfoExt, info, 0644); err != nil {
+		return TrashItem{}, err
+	}
+	if err := os.Rename(src, target); err != nil {
+		os.Remove(target + trashInfoExt)
+		return TrashItem{}, err
+	}
+	if t.crypt != nil {
+		crypt.Wipe(file.Path) // The decrypted copy
+	}
+
+	return item, nil
+}
+
+// List returns the trashed notes, most recently deleted first
+func (t *Trash) List() ([]TrashItem, error) {
+	items := []TrashItem{}
+
+	infos, err := filepath.Glob(filepath.Join(t.root, "*", "*"+trashInfoExt))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, infoPath := range infos {
+		data, err := os.ReadFile(infoPath)
+		if err != nil {
+			continue
+		}
+		if t.crypt != nil && crypt.IsSealed(data) {
+			if _, data, err = t.crypt.key.Open(data); err != nil {
+				continue
+			}
+		}
+		var item TrashItem
+		if err := json.Unmarshal(data, &item); err != nil {
+			continue // Not ours, or corrupt
+		}
+		item.Path = strings.TrimSuffix(infoPath, trashInfoExt)
+		stat, err := os.Stat(item.Path)
+		if err != nil {
+			continue // Orphan sidecar
+		}
+		item.Size = stat.Size()
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+
+	return items, nil
+}
+
+// Restore moves a trashed note back to its original collection. It fails
+// if a note with the same name was created there in the meantime.
+func (t *Trash) Restore(item TrashItem) (string, error) {
+	dataDir := filepath.Dir(t.root)
+	target := filepath.Join(dataDir, item.Collection, item.Name)
+	if t.crypt != nil {
+		target = t.crypt.path(item.Collection, item.Name)
+	}
+	if exists(target) {
+		return "", fmt.Errorf("a file named %s already exists in collection %s", item.Name, item.Collection)
+	}
+
+	if err := EnsureDir(filepath.Dir(target)); err != nil {
+		return "", err
+	}
+	if err := os.Rename(item.Path, target); err != nil {
+		return "", err
+	}
+	if err := os.Remove(item.Path + trashInfoExt); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	t.removeEmptyDir(filepath.Dir(item.Path))
+
+	return target, nil
+}
+
+// Delete permanently removes a trashed note
+func (t *Trash) Delete(item TrashItem) error {
+	if err := os.Remove(item.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.Remove(item.Path + trashInfoExt); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	t.removeEmptyDir(filepath.Dir(item.Path))
+	return nil
+}
+
+// Empty permanently removes notes deleted more than olderThan ago. A zero
+// duration empties the whole trash. It returns the number of notes removed.
+func (t *Trash) Empty(olderThan time.Duration) (int, error) {
+	items, err := t.List()
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	removed := 0
+	for _, item := range items {
+		if olderThan > 0 && item.DeletedAt.After(cutoff) {
+			continue
+		}
+		if err := t.Delete(item); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+
+	return removed, nil
+}
+
+// removeEmptyDir removes a collection directory inside the trash once its
+// last note is gone.
+func (t *Trash) removeEmptyDir(dir string) {
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) == 0 {
+		os.Remove(dir)
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
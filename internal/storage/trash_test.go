@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestNote(t *testing.T, dataDir, collection, name string) FileItem {
+	t.Helper()
+	path := filepath.Join(dataDir, collection, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("# "+name+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return FileItem{Path: path, Name: name, Collection: collection}
+}
+
+func TestTrashPutAndRestore(t *testing.T) {
+	dataDir := t.TempDir()
+	file := writeTestNote(t, dataDir, "work", "note.md")
+	trash := NewTrash(dataDir)
+
+	item, err := trash.Put(file)
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, err := os.Stat(file.Path); !os.IsNotExist(err) {
+		t.Fatal("expected note to leave its collection")
+	}
+
+	items, err := trash.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(items) != 1 || items[0].Collection != "work" || items[0].Name != "note.md" || items[0].Path != item.Path {
+		t.Fatalf("List() = %+v", items)
+	}
+	if time.Since(items[0].DeletedAt) > time.Minute {
+		t.Errorf("unexpected DeletedAt %v", items[0].DeletedAt)
+	}
+
+	restored, err := trash.Restore(items[0])
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if restored != file.Path {
+		t.Errorf("Restore() = %s, want %s", restored, file.Path)
+	}
+	if items, _ := trash.List(); len(items) != 0 {
+		t.Errorf("expected empty trash after restore, got %+v", items)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, TrashDir, "work")); !os.IsNotExist(err) {
+		t.Error("expected empty collection dir to be removed from trash")
+	}
+}
+
+func TestTrashRestoreConflict(t *testing.T) {
+	dataDir := t.TempDir()
+	trash := NewTrash(dataDir)
+
+	item, err := trash.Put(writeTestNote(t, dataDir, "work", "note.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestNote(t, dataDir, "work", "note.md")
+
+	if _, err := trash.Restore(item); err == nil {
+		t.Error("Restore() expected error when the note exists again")
+	}
+}
+
+func TestTrashSameNameTwice(t *testing.T) {
+	dataDir := t.TempDir()
+	trash := NewTrash(dataDir)
+
+	first, err := trash.Put(writeTestNote(t, dataDir, "work", "note.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := trash.Put(writeTestNote(t, dataDir, "work", "note.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Path == second.Path {
+		t.Fatal("expected distinct trash paths")
+	}
+
+	items, _ := trash.List()
+	if len(items) != 2 || items[0].Name != "note.md" || items[1].Name != "note.md" {
+		t.Errorf("List() = %+v", items)
+	}
+}
+
+func TestTrashEmptyOlderThan(t *testing.T) {
+	dataDir := t.TempDir()
+	trash := NewTrash(dataDir)
+
+	old, err := trash.Put(writeTestNote(t, dataDir, "work", "old.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := trash.Put(writeTestNote(t, dataDir, "work", "new.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Backdate the first deletion.
+	info := []byte(`{"name":"old.md","collection":"work","deleted_at":"` +
+		time.Now().Add(-40*24*time.Hour).Format(time.RFC3339) + `"}`)
+	if err := os.WriteFile(old.Path+trashInfoExt, info, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := trash.Empty(30 * 24 * time.Hour)
+	if err != nil {
+		t.Fatalf("Empty() error = %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Empty() removed %d, want 1", removed)
+	}
+
+	items, _ := trash.List()
+	if len(items) != 1 || items[0].Name != "new.md" {
+		t.Errorf("List() after Empty = %+v", items)
+	}
+
+	if removed, _ := trash.Empty(0); removed != 1 {
+		t.Errorf("Empty(0) removed %d, want 1", removed)
+	}
+}
@@ -0,0 +1,385 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gcaixeta/marginalia/internal/config"
+)
+
+// webdavStateFile records, in the data dir, what every file looked like
+// on both sides after the last sync
+const webdavStateFile = ".webdav.json"
+
+type webdavState struct {
+	URL   string                     `json:"url"`
+	Files map[string]webdavFileState `json:"files"`
+}
+
+type webdavFileState struct {
+	Version string    `json:"version"` // Server ETag, or modification time and size
+	ModTime time.Time `json:"mtime"`   // Local modification time
+	Size    int64     `json:"size"`    // Local size
+}
+
+// WebDAVConflict is a note changed on both sides since the last sync.
+// The local version stays at Path and the server's is kept next to it
+// at Copy.
+type WebDAVConflict struct {
+	Path string
+	Copy string
+}
+
+// WebDAVResult counts what a sync did
+type WebDAVResult struct {
+	Uploaded      int
+	Downloaded    int
+	RemovedLocal  int // Deleted on the server since the last sync
+	RemovedRemote int // Deleted locally since the last sync
+	Conflicts     []WebDAVConflict
+}
+
+// WebDAVBackup syncs the notes both ways with a WebDAV folder. A state
+// file remembers each file as of the last sync, so a change on either
+// side can be told apart from a deletion on the other.
+type WebDAVBackup struct {
+	dataDir string
+	url     string
+	client  *davClient
+
+	syncMu    sync.Mutex // Serializes syncs
+	wg        sync.WaitGroup
+	mu        sync.Mutex // Guards the fields below
+	err       error
+	conflicts []WebDAVConflict
+}
+
+func NewWebDAVBackup(cfg config.WebDAVConfig) (*WebDAVBackup, error) {
+	dataDir, err := DataDir()
+	if err != nil {
+		return nil, err
+	}
+	password := cfg.Password
+	if password == "" {
+		password = os.Getenv("MARGI_WEBDAV_PASSWORD")
+	}
+	client, err := newDavClient(cfg.URL, cfg.User, password)
+	if err != nil {
+		return nil, err
+	}
+	return &WebDAVBackup{dataDir: dataDir, url: cfg.URL, client: client}, nil
+}
+
+// Synchronize starts a sync in the background
+func (w *WebDAVBackup) Synchronize() error {
+	w.wg.Go(func() {
+		result, err := w.Sync()
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.err = err
+		w.conflicts = append(w.conflicts, result.Conflicts...)
+	})
+	return nil
+}
+
+func (w *WebDAVBackup) Wait() error {
+	w.wg.Wait()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// CommitAndPush syncs again so local changes reach the server, and
+// reports the conflicts found since the last call
+func (w *WebDAVBackup) CommitAndPush(message string) error {
+	w.wg.Wait()
+	if err := sealPlaintext(w.dataDir); err != nil {
+		return err
+	}
+	result, err := w.Sync()
+
+	w.mu.Lock()
+	conflicts := append(w.conflicts, result.Conflicts...)
+	w.conflicts = nil
+	w.mu.Unlock()
+	for _, c := range conflicts {
+		fmt.Printf("! %s changed on both sides, the server version was kept as %s\n", c.Path, c.Copy)
+	}
+
+	if err != nil {
+		return fmt.Errorf("webdav: %w", err)
+	}
+	fmt.Println("↑ synced")
+	return nil
+}
+
+func (w *WebDAVBackup) Move(from, to string) error {
+	return moveFile(from, to)
+}
+
+// Sync brings the data dir and the server folder in line. Files changed
+// on one side are copied to the other, deletions propagate and a note
+// changed on both sides keeps both versions. Directories are created on
+// both sides but never removed.
+func (w *WebDAVBackup) Sync() (result WebDAVResult, err error) {
+	w.syncMu.Lock()
+	defer w.syncMu.Unlock()
+
+	remote, err := w.client.list()
+	if err != nil {
+		return result, err
+	}
+	files, err := backupFiles(w.dataDir)
+	if err != nil {
+		return result, err
+	}
+	state, err := w.loadState()
+	if err != nil {
+		return result, err
+	}
+
+	local := map[string]fs.FileInfo{}
+	for _, file := range files {
+		if file.info.IsDir() {
+			if entry, ok := remote[file.rel]; !ok || !entry.dir {
+				if err := w.client.mkcol(file.rel); err != nil {
+					return result, err
+				}
+			}
+			continue
+		}
+		local[file.rel] = file.info
+	}
+	for rel, entry := range remote {
+		if entry.dir {
+			if err := EnsureDir(w.local(rel)); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	paths := map[string]bool{}
+	for rel := range local {
+		paths[rel] = true
+	}
+	for rel, entry := range remote {
+		if !entry.dir {
+			paths[rel] = true
+		}
+	}
+	for rel := range state.Files {
+		paths[rel] = true
+	}
+	sorted := make([]string, 0, len(paths))
+	for rel := range paths {
+		sorted = append(sorted, rel)
+	}
+	sort.Strings(sorted)
+
+	// The state is saved whatever happens, so the work done before an
+	// error isn't mistaken for changes on the next sync
+	defer func() {
+		if serr := w.saveState(state); serr != nil && err == nil {
+			err = serr
+		}
+	}()
+	for _, rel := range sorted {
+		if err = w.syncFile(rel, local, remote, state, &result); err != nil {
+			return result, err
+		}
+	}
+	return result, err
+}
+
+// syncFile brings one file in line on both sides and updates its state
+func (w *WebDAVBackup) syncFile(rel string, local map[string]fs.FileInfo, remote map[string]davEntry, state *webdavState, result *WebDAVResult) error {
+	info, haveLocal := local[rel]
+	entry, haveRemote := remote[rel]
+	if haveRemote && entry.dir {
+		haveRemote = false
+	}
+	last, known := state.Files[rel]
+
+	localChanged := haveLocal && (!known || !last.ModTime.Equal(info.ModTime()) || last.Size != info.Size())
+	remoteChanged := haveRemote && (!known || last.Version != entry.version())
+
+	switch {
+	case !haveLocal && !haveRemote:
+		delete(state.Files, rel)
+		return nil
+
+	case haveLocal && haveRemote:
+		switch {
+		case !localChanged && !remoteChanged:
+			return nil
+		case !remoteChanged:
+			return w.upload(rel, state, result)
+		case !localChanged:
+			return w.download(entry, state, result)
+		}
+		return w.resolve(entry, state, result)
+
+	case haveLocal:
+		if known && !localChanged {
+			// Deleted on the server
+			if err := os.Remove(w.local(rel)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			delete(state.Files, rel)
+			result.RemovedLocal++
+			return nil
+		}
+		return w.upload(rel, state, result)
+
+	default:
+		if known && !remoteChanged {
+			// Deleted locally
+			if err := w.client.delete(rel); err != nil {
+				return err
+			}
+			delete(state.Files, rel)
+			result.RemovedRemote++
+			return nil
+		}
+		return w.download(entry, state, result)
+	}
+}
+
+// resolve handles a file changed on both sides. Identical contents are
+// just recorded; otherwise the server version is saved next to the
+// local one and both are uploaded.
+func (w *WebDAVBackup) resolve(entry davEntry, state *webdavState, result *WebDAVResult) error {
+	rel := entry.rel
+	theirs, err := w.client.get(rel)
+	if err != nil {
+		return err
+	}
+	ours, err := os.ReadFile(w.local(rel))
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(ours, theirs) {
+		return w.record(rel, entry.version(), state)
+	}
+
+	copyPath := theirsPath(w.local(rel))
+	copyRel, err := filepath.Rel(w.dataDir, copyPath)
+	if err != nil {
+		return err
+	}
+	copyRel = filepath.ToSlash(copyRel)
+	if err := writeFileAtomic(copyPath, theirs); err != nil {
+		return err
+	}
+	if err := w.upload(copyRel, state, result); err != nil {
+		return err
+	}
+	if err := w.upload(rel, state, result); err != nil {
+		return err
+	}
+	result.Conflicts = append(result.Conflicts, WebDAVConflict{Path: rel, Copy: copyRel})
+	return nil
+}
+
+func (w *WebDAVBackup) upload(rel string, state *webdavState, result *WebDAVResult) error {
+	data, err := os.ReadFile(w.local(rel))
+	if err != nil {
+		return err
+	}
+	version, err := w.client.put(rel, data)
+	if err != nil {
+		return err
+	}
+	result.Uploaded++
+	return w.record(rel, version, state)
+}
+
+// download fetches a file from the server. It records the version seen
+// when listing: should the file change in between, the next sync simply
+// fetches it again.
+func (w *WebDAVBackup) download(entry davEntry, state *webdavState, result *WebDAVResult) error {
+	data, err := w.client.get(entry.rel)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(w.local(entry.rel), data); err != nil {
+		return err
+	}
+	result.Downloaded++
+	return w.record(entry.rel, entry.version(), state)
+}
+
+// record stores the state of a file that is now the same on both sides
+func (w *WebDAVBackup) record(rel, version string, state *webdavState) error {
+	info, err := os.Stat(w.local(rel))
+	if err != nil {
+		return err
+	}
+	state.Files[rel] = webdavFileState{Version: version, ModTime: info.ModTime(), Size: info.Size()}
+	return nil
+}
+
+func (w *WebDAVBackup) local(rel string) string {
+	return filepath.Join(w.dataDir, filepath.FromSlash(rel))
+}
+
+// loadState reads the state file. A state recorded against another URL
+// is dropped, so pointing the vault at a new folder merges the two
+// instead of deleting everything the new one lacks.
+func (w *WebDAVBackup) loadState() (*webdavState, error) {
+	state := &webdavState{URL: w.url, Files: map[string]webdavFileState{}}
+	data, err := os.ReadFile(filepath.Join(w.dataDir, webdavStateFile))
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var saved webdavState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", webdavStateFile, err)
+	}
+	if saved.URL != w.url || saved.Files == nil {
+		return state, nil
+	}
+	return &saved, nil
+}
+
+func (w *WebDAVBackup) saveState(state *webdavState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(w.dataDir, webdavStateFile), data)
+}
+
+// writeFileAtomic writes data to path through a temporary file, so a
+// failed write never leaves a truncated note behind
+func writeFileAtomic(path string, data []byte) error {
+	if err := EnsureDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".margi-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,270 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// davClient speaks the small part of WebDAV the webdav provider needs
+type davClient struct {
+	base     *url.URL // Folder holding the collections, ending in a slash
+	user     string
+	password string
+	http     *http.Client
+}
+
+// davEntry is a file or collection on the server
+type davEntry struct {
+	rel      string // Relative to the base folder, with forward slashes
+	dir      bool
+	etag     string
+	modified time.Time
+	size     int64
+}
+
+// version identifies the content of an entry: its ETag, or its
+// modification time and size on servers that don't send one
+func (e davEntry) version() string {
+	if e.etag != "" {
+		return e.etag
+	}
+	return fmt.Sprintf("%s/%d", e.modified.UTC().Format(time.RFC3339), e.size)
+}
+
+// davError is an unexpected response status
+type davError struct {
+	method string
+	rel    string
+	status string
+}
+
+func (e *davError) Error() string {
+	return fmt.Sprintf("webdav %s %s: %s", e.method, e.rel, e.status)
+}
+
+func newDavClient(rawURL, user, password string) (*davClient, error) {
+	base, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webdav url: %w", err)
+	}
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf("invalid webdav url %q", rawURL)
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+		base.RawPath = ""
+	}
+	return &davClient{
+		base:     base,
+		user:     user,
+		password: password,
+		http:     &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (c *davClient) url(rel string, dir bool) string {
+	u := c.base
+	if rel != "" {
+		u = c.base.JoinPath(strings.Split(rel, "/")...)
+	}
+	s := u.String()
+	if dir && !strings.HasSuffix(s, "/") {
+		s += "/"
+	}
+	return s
+}
+
+func (c *davClient) do(method, rel string, dir bool, body []byte, header map[string]string) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, c.url(rel, dir), reader)
+	if err != nil {
+		return nil, err
+	}
+	if c.user != "" || c.password != "" {
+		req.SetBasicAuth(c.user, c.password)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	return c.http.Do(req)
+}
+
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:"><d:prop>
+<d:resourcetype/><d:getetag/><d:getlastmodified/><d:getcontentlength/>
+</d:prop></d:propfind>`
+
+type davMultistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Status string `xml:"status"`
+			Prop   struct {
+				ETag         string `xml:"getetag"`
+				LastModified string `xml:"getlastmodified"`
+				Length       int64  `xml:"getcontentlength"`
+				Type         struct {
+					Collection *struct{} `xml:"collection"`
+				} `xml:"resourcetype"`
+			} `xml:"prop"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+// propfind returns the entry at rel and, with depth 1, its children
+func (c *davClient) propfind(rel string, dir bool, depth string) ([]davEntry, error) {
+	resp, err := c.do("PROPFIND", rel, dir, []byte(propfindBody), map[string]string{
+		"Depth":        depth,
+		"Content-Type": "application/xml; charset=utf-8",
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, &davError{"PROPFIND", "/" + rel, resp.Status}
+	}
+
+	var ms davMultistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("webdav PROPFIND /%s: %w", rel, err)
+	}
+
+	var entries []davEntry
+	for _, r := range ms.Responses {
+		href, err := url.Parse(r.Href)
+		if err != nil {
+			continue
+		}
+		path, ok := strings.CutPrefix(href.Path, c.base.Path)
+		if !ok {
+			if href.Path+"/" != c.base.Path {
+				continue
+			}
+			path = ""
+		}
+		entry := davEntry{rel: strings.Trim(path, "/")}
+		for _, ps := range r.Propstat {
+			// Missing properties come back empty with a 404 status
+			if !strings.Contains(ps.Status, " 200 ") {
+				continue
+			}
+			entry.dir = ps.Prop.Type.Collection != nil
+			entry.etag = strings.TrimPrefix(ps.Prop.ETag, "W/")
+			entry.size = ps.Prop.Length
+			if t, err := http.ParseTime(ps.Prop.LastModified); err == nil {
+				entry.modified = t
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// list walks the folder and returns everything below it by path. Servers
+// such as Nextcloud refuse infinite depth, so it goes one level at a time.
+func (c *davClient) list() (map[string]davEntry, error) {
+	entries := map[string]davEntry{}
+	pending := []string{""}
+	for len(pending) > 0 {
+		dir := pending[0]
+		pending = pending[1:]
+		children, err := c.propfind(dir, true, "1")
+		if err != nil {
+			return nil, err
+		}
+		for _, child := range children {
+			if child.rel == dir || child.rel == "" {
+				continue
+			}
+			if dir == "" && skipBackup(child.rel) {
+				continue
+			}
+			entries[child.rel] = child
+			if child.dir {
+				pending = append(pending, child.rel)
+			}
+		}
+	}
+	return entries, nil
+}
+
+// stat returns the entry for a single file
+func (c *davClient) stat(rel string) (davEntry, error) {
+	entries, err := c.propfind(rel, false, "0")
+	if err != nil {
+		return davEntry{}, err
+	}
+	if len(entries) == 0 {
+		return davEntry{}, &davError{"PROPFIND", "/" + rel, "empty response"}
+	}
+	entries[0].rel = rel
+	return entries[0], nil
+}
+
+func (c *davClient) get(rel string) ([]byte, error) {
+	resp, err := c.do("GET", rel, false, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &davError{"GET", "/" + rel, resp.Status}
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// put uploads a file and returns its new version
+func (c *davClient) put(rel string, data []byte) (string, error) {
+	resp, err := c.do("PUT", rel, false, data, nil)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", &davError{"PUT", "/" + rel, resp.Status}
+	}
+	if etag := strings.TrimPrefix(resp.Header.Get("ETag"), "W/"); etag != "" {
+		return etag, nil
+	}
+	entry, err := c.stat(rel)
+	if err != nil {
+		return "", err
+	}
+	return entry.version(), nil
+}
+
+// delete removes a file, succeeding when it is already gone
+func (c *davClient) delete(rel string) error {
+	resp, err := c.do("DELETE", rel, false, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound || (resp.StatusCode >= 200 && resp.StatusCode <= 299) {
+		return nil
+	}
+	return &davError{"DELETE", "/" + rel, resp.Status}
+}
+
+// mkcol creates a collection, succeeding when it already exists
+func (c *davClient) mkcol(rel string) error {
+	resp, err := c.do("MKCOL", rel, true, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusMethodNotAllowed || (resp.StatusCode >= 200 && resp.StatusCode <= 299) {
+		return nil
+	}
+	return &davError{"MKCOL", "/" + rel, resp.Status}
+}
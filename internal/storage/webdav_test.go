@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/webdav"
+)
+
+// newWebDAVServer serves a temporary directory over WebDAV under /dav/
+// and returns the folder URL and the directory
+func newWebDAVServer(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	mux := http.NewServeMux()
+	mux.Handle("/dav/", &webdav.Handler{
+		Prefix:     "/dav",
+		FileSystem: webdav.Dir(dir),
+		LockSystem: webdav.NewMemLS(),
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server.URL + "/dav/notes", dir
+}
+
+// newTestWebDAV returns a provider syncing a new data dir with url, like
+// one more machine sharing the notes
+func newTestWebDAV(t *testing.T, url string) *WebDAVBackup {
+	t.Helper()
+	client, err := newDavClient(url, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &WebDAVBackup{dataDir: t.TempDir(), url: url, client: client}
+}
+
+func syncWebDAV(t *testing.T, w *WebDAVBackup) WebDAVResult {
+	t.Helper()
+	result, err := w.Sync()
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	return result
+}
+
+func mustList(t *testing.T, dataDir string) []FileItem {
+	t.Helper()
+	files, err := NewLocalStore(dataDir).List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestWebDAVBackup_Sync(t *testing.T) {
+	url, server := newWebDAVServer(t)
+	if err := os.Mkdir(filepath.Join(server, "notes"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	laptop, desktop := newTestWebDAV(t, url), newTestWebDAV(t, url)
+
+	writeRepoFile(t, laptop.dataDir, "work/plan.md", "# Plan\n")
+	writeRepoFile(t, laptop.dataDir, "my notes/first idea.md", "# Idea\n")
+	writeRepoFile(t, laptop.dataDir, ".index/index.json", "{}")
+	if r := syncWebDAV(t, laptop); r.Uploaded != 2 {
+		t.Errorf("first sync uploaded %d files, want 2", r.Uploaded)
+	}
+	if exists(filepath.Join(server, "notes", ".index")) {
+		t.Error("the search index was uploaded")
+	}
+	for _, file := range mustList(t, laptop.dataDir) {
+		if file.Name == webdavStateFile {
+			t.Error("the state file is listed as a note")
+		}
+	}
+	if r := syncWebDAV(t, laptop); r.Uploaded != 0 || r.Downloaded != 0 {
+		t.Errorf("unchanged sync = %+v, want nothing done", r)
+	}
+
+	if r := syncWebDAV(t, desktop); r.Downloaded != 2 {
+		t.Errorf("desktop downloaded %d files, want 2", r.Downloaded)
+	}
+	if got := readFile(t, filepath.Join(desktop.dataDir, "my notes", "first idea.md")); got != "# Idea\n" {
+		t.Errorf("downloaded note = %q", got)
+	}
+
+	// Edits and deletions travel both ways
+	writeRepoFile(t, desktop.dataDir, "work/plan.md", "# Plan\n\n- ship it\n")
+	os.Remove(filepath.Join(desktop.dataDir, "my notes", "first idea.md"))
+	if r := syncWebDAV(t, desktop); r.Uploaded != 1 || r.RemovedRemote != 1 {
+		t.Errorf("desktop sync = %+v, want 1 upload and 1 remote removal", r)
+	}
+	if r := syncWebDAV(t, laptop); r.Downloaded != 1 || r.RemovedLocal != 1 {
+		t.Errorf("laptop sync = %+v, want 1 download and 1 local removal", r)
+	}
+	if got := readFile(t, filepath.Join(laptop.dataDir, "work", "plan.md")); got != "# Plan\n\n- ship it\n" {
+		t.Errorf("laptop plan = %q", got)
+	}
+	if exists(filepath.Join(laptop.dataDir, "my notes", "first idea.md")) {
+		t.Error("the note deleted on the desktop is still on the laptop")
+	}
+}
+
+func TestWebDAVBackup_ConflictKeepsBoth(t *testing.T) {
+	url, server := newWebDAVServer(t)
+	os.Mkdir(filepath.Join(server, "notes"), 0755)
+	laptop, desktop := newTestWebDAV(t, url), newTestWebDAV(t, url)
+
+	writeRepoFile(t, laptop.dataDir, "work/plan.md", "# Plan\n")
+	syncWebDAV(t, laptop)
+	syncWebDAV(t, desktop)
+
+	writeRepoFile(t, laptop.dataDir, "work/plan.md", "# Plan\n\nlaptop\n")
+	writeRepoFile(t, desktop.dataDir, "work/plan.md", "# Plan\n\nfrom the desktop\n")
+	syncWebDAV(t, desktop)
+	r := syncWebDAV(t, laptop)
+	want := WebDAVConflict{Path: "work/plan.md", Copy: "work/plan-theirs.md"}
+	if len(r.Conflicts) != 1 || r.Conflicts[0] != want {
+		t.Fatalf("conflicts = %+v, want %+v", r.Conflicts, want)
+	}
+	if got := readFile(t, filepath.Join(laptop.dataDir, "work", "plan.md")); got != "# Plan\n\nlaptop\n" {
+		t.Errorf("local version = %q", got)
+	}
+	if got := readFile(t, filepath.Join(laptop.dataDir, "work", "plan-theirs.md")); got != "# Plan\n\nfrom the desktop\n" {
+		t.Errorf("server copy = %q", got)
+	}
+
+	// Both versions reach the other machine
+	syncWebDAV(t, desktop)
+	if got := readFile(t, filepath.Join(desktop.dataDir, "work", "plan.md")); got != "# Plan\n\nlaptop\n" {
+		t.Errorf("desktop plan = %q", got)
+	}
+	if !exists(filepath.Join(desktop.dataDir, "work", "plan-theirs.md")) {
+		t.Error("the conflict copy did not reach the desktop")
+	}
+}
+
+func TestWebDAVBackup_NewURLMerges(t *testing.T) {
+	url, server := newWebDAVServer(t)
+	os.Mkdir(filepath.Join(server, "notes"), 0755)
+	os.Mkdir(filepath.Join(server, "other"), 0755)
+	w := newTestWebDAV(t, url)
+	writeRepoFile(t, w.dataDir, "work/plan.md", "# Plan\n")
+	syncWebDAV(t, w)
+
+	// The new folder lacks the note, which must not read as a deletion
+	other := newTestWebDAV(t, url[:len(url)-len("notes")]+"other")
+	other.dataDir = w.dataDir
+	if r := syncWebDAV(t, other); r.RemovedLocal != 0 || r.Uploaded != 1 {
+		t.Errorf("sync with a new folder = %+v, want the note uploaded", r)
+	}
+	if !exists(filepath.Join(w.dataDir, "work", "plan.md")) {
+		t.Error("the note was deleted")
+	}
+}
+
+func TestWebDAVBackup_MissingFolder(t *testing.T) {
+	url, _ := newWebDAVServer(t)
+	w := newTestWebDAV(t, url)
+	writeRepoFile(t, w.dataDir, "work/plan.md", "# Plan\n")
+	if _, err := w.Sync(); err == nil {
+		t.Fatal("expected an error for a missing folder")
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/gcaixeta/marginalia/internal/search"
 	"github.com/gcaixeta/marginalia/internal/storage"
 )
 
@@ -24,8 +25,17 @@ var (
 	browseNormalStyle = lipgloss.NewStyle().
 				Foreground(lipgloss.Color("252")).
 				PaddingLeft(4)
+
+	previewPaneStyle = lipgloss.NewStyle().
+				BorderStyle(lipgloss.NormalBorder()).
+				BorderLeft(true).
+				BorderForeground(lipgloss.Color("240")).
+				PaddingLeft(1)
 )
 
+// minPreviewWidth is the narrowest terminal that gets the split view
+const minPreviewWidth = 80
+
 type BrowsePickerModel struct {
 	allFiles      []storage.FileItem
 	filteredFiles []storage.FileItem
@@ -36,10 +46,20 @@ type BrowsePickerModel struct {
 	filterMode    bool
 	width         int
 	height        int
+	index         *search.Index // Optional, lets the filter match note contents
+	showPreview   bool
+	previewPath   string // File currently rendered in previewLines
+	previewWidth  int    // Width previewLines were rendered for
+	previewLines  []string
+	previewScroll int
 }
 
 func NewBrowsePickerModel() (BrowsePickerModel, error) {
-	files, err := storage.ListAllFiles()
+	store, err := storage.Store()
+	if err != nil {
+		return BrowsePickerModel{}, err
+	}
+	files, err := store.List()
 	if err != nil {
 		return BrowsePickerModel{}, err
 	}
@@ -51,9 +71,17 @@ func NewBrowsePickerModel() (BrowsePickerModel, error) {
 		return files[i].Name < files[j].Name
 	})
 
+	// The index is a nice-to-have: without it the filter only matches names.
+	var index *search.Index
+	if dataDir, err := storage.PlainDir(); err == nil {
+		index, _ = search.Refresh(dataDir, store, files)
+	}
+
 	model := BrowsePickerModel{
-		allFiles: files,
-		cursor:   0,
+		allFiles:    files,
+		cursor:      0,
+		index:       index,
+		showPreview: true,
 	}
 	model.updateFilteredFiles()
 	return model, nil
@@ -64,6 +92,12 @@ func (m BrowsePickerModel) Init() tea.Cmd {
 }
 
 func (m BrowsePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	m, cmd := m.handleMsg(msg)
+	m.syncPreview()
+	return m, cmd
+}
+
+func (m BrowsePickerModel) handleMsg(msg tea.Msg) (BrowsePickerModel, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -125,6 +159,15 @@ func (m BrowsePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			case "/":
 				m.filterMode = true
+
+			case "p":
+				m.showPreview = !m.showPreview
+
+			case "J", "ctrl+d", "pgdown":
+				m.scrollPreview(m.previewHeight() / 2)
+
+			case "K", "ctrl+u", "pgup":
+				m.scrollPreview(-m.previewHeight() / 2)
 			}
 		}
 	}
@@ -132,6 +175,86 @@ func (m BrowsePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// previewVisible reports whether the split view fits in the terminal
+func (m BrowsePickerModel) previewVisible() bool {
+	return m.showPreview && m.width >= minPreviewWidth && m.height > 0
+}
+
+// paneWidths splits the terminal width between the list and the preview
+func (m BrowsePickerModel) paneWidths() (listW, previewW int) {
+	listW = m.width * 2 / 5
+	if listW < 30 {
+		listW = 30
+	}
+	previewW = m.width - listW - 2 // border + padding
+	return listW, previewW
+}
+
+// previewHeight is the number of preview lines that fit on screen
+func (m BrowsePickerModel) previewHeight() int {
+	h := m.height - 4 // statusline (2) + preview header + blank line
+	if h < 1 {
+		h = 1
+	}
+	return h
+}
+
+func (m *BrowsePickerModel) scrollPreview(delta int) {
+	maxScroll := len(m.previewLines) - m.previewHeight()
+	m.previewScroll = min(max(m.previewScroll+delta, 0), max(maxScroll, 0))
+}
+
+// syncPreview re-renders the preview when the highlighted note or the
+// pane width changed.
+func (m *BrowsePickerModel) syncPreview() {
+	if !m.previewVisible() || len(m.filteredFiles) == 0 {
+		return
+	}
+
+	file := m.filteredFiles[m.cursor]
+	_, previewW := m.paneWidths()
+	if file.Path == m.previewPath && previewW == m.previewWidth {
+		return
+	}
+
+	content, err := readNote(file)
+	if err != nil {
+		m.previewLines = []string{errorStyle.Render(fmt.Sprintf("Cannot read note: %v", err))}
+	} else {
+		m.previewLines = renderPreview(content, previewW)
+	}
+	if file.Path != m.previewPath {
+		m.previewScroll = 0
+	}
+	m.previewPath = file.Path
+	m.previewWidth = previewW
+	m.scrollPreview(0)
+}
+
+// renderPreviewPane renders the visible window of the preview
+func (m BrowsePickerModel) renderPreviewPane(width, height int) string {
+	var b strings.Builder
+
+	if len(m.filteredFiles) > 0 {
+		file := m.filteredFiles[m.cursor]
+		header := file.Collection + "/" + file.Name
+		if len(m.previewLines) > m.previewHeight() {
+			end := min(m.previewScroll+m.previewHeight(), len(m.previewLines))
+			header += fmt.Sprintf("  %d–%d/%d", m.previewScroll+1, end, len(m.previewLines))
+		}
+		b.WriteString(helpStyle.UnsetPaddingTop().Render(truncate(header, width)))
+		b.WriteString("\n\n")
+
+		end := min(m.previewScroll+m.previewHeight(), len(m.previewLines))
+		for i := m.previewScroll; i < end; i++ {
+			b.WriteString(m.previewLines[i])
+			b.WriteString("\n")
+		}
+	}
+
+	return previewPaneStyle.Width(width).Height(height).MaxHeight(height).Render(b.String())
+}
+
 func (m BrowsePickerModel) View() string {
 	if m.cancelled {
 		return ""
@@ -143,9 +266,14 @@ func (m BrowsePickerModel) View() string {
 		statusline = modeFilterStyle.Render("-- FILTER --") +
 			helpStyle.Render(fmt.Sprintf("  %d note(s)  [Esc] normal • [Enter] open", len(m.filteredFiles)))
 	} else {
+		help := "[↑↓/jk] navigate • [/] filter • [Enter] open • [p] preview • [Q] quit"
+		if m.previewVisible() {
+			help = "[↑↓/jk] navigate • [J/K] scroll preview • [/] filter • [Enter] open • [p] hide preview • [Q] quit"
+		}
 		statusline = modeNormalStyle.Render("-- NORMAL --") +
-			helpStyle.Render(fmt.Sprintf("  %d note(s)  [↑↓/jk] navigate • [/] filter • [Enter] open • [Q] quit", len(m.filteredFiles)))
+			helpStyle.Render(fmt.Sprintf("  %d note(s)  %s", len(m.filteredFiles), help))
 	}
+	statusline += pendingIndicator()
 
 	// 2. Determine maxVisible based on available height
 	statuslineH := lipgloss.Height(statusline)
@@ -262,7 +390,17 @@ func (m BrowsePickerModel) View() string {
 
 	content := b.String()
 
-	// 4. Compose: constrain content and pin statusline to bottom
+	// 4. Split view: list on the left, preview of the highlighted note on the right
+	if m.previewVisible() {
+		listW, previewW := m.paneWidths()
+		contentH := m.height - statuslineH
+		content = lipgloss.JoinHorizontal(lipgloss.Top,
+			lipgloss.NewStyle().Width(listW).MaxWidth(listW).Render(content),
+			m.renderPreviewPane(previewW, contentH),
+		)
+	}
+
+	// 5. Compose: constrain content and pin statusline to bottom
 	if m.height > 0 {
 		contentArea := lipgloss.NewStyle().Height(m.height - statuslineH).Render(content)
 		return lipgloss.JoinVertical(lipgloss.Top, contentArea, statusline)
@@ -277,18 +415,54 @@ func (m *BrowsePickerModel) updateFilteredFiles() {
 	}
 
 	m.filteredFiles = []storage.FileItem{}
-	inputLower := strings.ToLower(m.input)
+	text, tags := splitTagTokens(m.input)
+
+	// #tag tokens narrow the list down to tagged notes; they need the index.
+	candidates := m.allFiles
+	if len(tags) > 0 {
+		if m.index == nil {
+			return
+		}
+		candidates = m.index.FilterTags(m.allFiles, tags)
+	}
 
-	for _, file := range m.allFiles {
+	if text == "" {
+		m.filteredFiles = candidates
+		return
+	}
+
+	inputLower := strings.ToLower(text)
+
+	contentMatches := map[string]bool{}
+	if m.index != nil {
+		for _, file := range m.index.Filter(candidates, text) {
+			contentMatches[file.Path] = true
+		}
+	}
+
+	for _, file := range candidates {
 		nameLower := strings.ToLower(file.Name)
 		collectionLower := strings.ToLower(file.Collection)
 
-		if strings.Contains(nameLower, inputLower) || strings.Contains(collectionLower, inputLower) {
+		if strings.Contains(nameLower, inputLower) || strings.Contains(collectionLower, inputLower) || contentMatches[file.Path] {
 			m.filteredFiles = append(m.filteredFiles, file)
 		}
 	}
 }
 
+// splitTagTokens separates "#tag" tokens from the rest of a filter input
+func splitTagTokens(input string) (text string, tags []string) {
+	var words []string
+	for _, field := range strings.Fields(input) {
+		if len(field) > 1 && strings.HasPrefix(field, "#") {
+			tags = append(tags, field)
+		} else {
+			words = append(words, field)
+		}
+	}
+	return strings.Join(words, " "), tags
+}
+
 func RunBrowsePicker() (*storage.FileItem, error) {
 	model, err := NewBrowsePickerModel()
 	if err != nil {
@@ -308,3 +482,12 @@ func RunBrowsePicker() (*storage.FileItem, error) {
 
 	return m.selected, nil
 }
+
+// readNote reads a note's content through the configured note store
+func readNote(file storage.FileItem) ([]byte, error) {
+	store, err := storage.Store()
+	if err != nil {
+		return nil, err
+	}
+	return store.Read(file.Collection, file.Name)
+}
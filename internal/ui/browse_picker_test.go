@@ -6,6 +6,7 @@ import (
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gcaixeta/marginalia/internal/search"
 	"github.com/gcaixeta/marginalia/internal/storage"
 )
 
@@ -136,3 +137,57 @@ func TestBrowsePickerViewShowsMode(t *testing.T) {
 		t.Error("Expected view to show cursor █ in filter mode")
 	}
 }
+
+func TestBrowsePickerFilterMatchesContent(t *testing.T) {
+	store := storage.NewMemStore()
+	if err := store.Write("journal", "note-one", []byte("talked about the kubernetes migration\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	m := newTestBrowseModel()
+	index, err := search.Refresh(t.TempDir(), store, m.allFiles)
+	if err != nil {
+		t.Fatalf("search.Refresh() error = %v", err)
+	}
+	m.index = index
+
+	m.input = "kubernetes"
+	m.updateFilteredFiles()
+
+	if len(m.filteredFiles) != 1 || m.filteredFiles[0].Name != "note-one" {
+		t.Errorf("Expected content match on note-one, got %v", m.filteredFiles)
+	}
+}
+
+func TestBrowsePickerFilterTagTokens(t *testing.T) {
+	store := storage.NewMemStore()
+	m := newTestBrowseModel()
+	for i, content := range []string{"Standup notes #work\n", "Weekend plans #home\n"} {
+		if err := store.Write("journal", m.allFiles[i].Name, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	index, err := search.Refresh(t.TempDir(), store, m.allFiles)
+	if err != nil {
+		t.Fatalf("search.Refresh() error = %v", err)
+	}
+	m.index = index
+
+	m.input = "#home"
+	m.updateFilteredFiles()
+	if len(m.filteredFiles) != 1 || m.filteredFiles[0].Name != "note-two" {
+		t.Errorf("Expected #home to match note-two, got %v", m.filteredFiles)
+	}
+
+	m.input = "#work note"
+	m.updateFilteredFiles()
+	if len(m.filteredFiles) != 1 || m.filteredFiles[0].Name != "note-one" {
+		t.Errorf("Expected '#work note' to match note-one, got %v", m.filteredFiles)
+	}
+
+	m.input = "#work weekend"
+	m.updateFilteredFiles()
+	if len(m.filteredFiles) != 0 {
+		t.Errorf("Expected no match for '#work weekend', got %v", m.filteredFiles)
+	}
+}
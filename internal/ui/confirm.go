@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"fmt"
 	"path/filepath"
 	"strings"
 
@@ -34,31 +35,51 @@ var (
 				Foreground(lipgloss.Color("240")).
 				PaddingLeft(2)
 
+	trashInfoStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("245")).
+			PaddingTop(1).
+			PaddingBottom(1)
+
 	selectedButtonStyle = lipgloss.NewStyle().
 				Foreground(lipgloss.Color("86")).
 				Bold(true).
 				PaddingLeft(2)
 )
 
+// ConfirmAction is what the user chose in the confirmation dialog
+type ConfirmAction int
+
+const (
+	ConfirmCancel    ConfirmAction = iota // Keep the note
+	ConfirmTrash                          // Move the note to the trash
+	ConfirmPermanent                      // Delete the note permanently
+)
+
 // ConfirmModel holds the state of the confirmation dialog
 type ConfirmModel struct {
-	filePath    string
-	relPath     string
-	confirmed   bool
-	cancelled   bool
-	cursor      int // 0 = No, 1 = Yes
+	relPaths  []string
+	confirmed bool
+	cancelled bool
+	permanent bool // The user asked for permanent deletion with [D]
+	cursor    int  // 0 = No, 1 = Yes
 }
 
-// NewConfirmModel creates a new confirmation dialog model
-func NewConfirmModel(filePath string, dataDir string) ConfirmModel {
-	relPath, err := filepath.Rel(dataDir, filePath)
-	if err != nil {
-		relPath = filePath
+// maxListedFiles is how many files the dialog lists before summarizing
+const maxListedFiles = 10
+
+// NewConfirmModel creates a new confirmation dialog model for one or more files
+func NewConfirmModel(filePaths []string, dataDir string) ConfirmModel {
+	relPaths := make([]string, 0, len(filePaths))
+	for _, filePath := range filePaths {
+		relPath, err := filepath.Rel(dataDir, filePath)
+		if err != nil {
+			relPath = filePath
+		}
+		relPaths = append(relPaths, relPath)
 	}
 
 	return ConfirmModel{
-		filePath: filePath,
-		relPath:  relPath,
+		relPaths: relPaths,
 		cursor:   0, // Default to "No"
 	}
 }
@@ -73,10 +94,25 @@ func (m ConfirmModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch msg.String() {
-		case "ctrl+c", "esc", "q", "n":
+		case "esc":
+			// Leaving permanent mode goes back to the trash question
+			if m.permanent {
+				m.permanent = false
+				m.cursor = 0
+				return m, nil
+			}
+			m.cancelled = true
+			return m, tea.Quit
+
+		case "ctrl+c", "q", "n":
 			m.cancelled = true
 			return m, tea.Quit
 
+		case "d", "D":
+			// Permanent deletion is an explicit extra step and starts on "No"
+			m.permanent = true
+			m.cursor = 0
+
 		case "enter", "y":
 			if msg.String() == "y" || m.cursor == 1 {
 				m.confirmed = true
@@ -108,20 +144,52 @@ func (m ConfirmModel) View() string {
 	var b strings.Builder
 
 	// Title
-	b.WriteString(confirmTitleStyle.Render("⚠  CONFIRMAR EXCLUSÃO"))
+	if m.permanent {
+		b.WriteString(confirmTitleStyle.Render("⚠  EXCLUSÃO PERMANENTE"))
+	} else {
+		b.WriteString(confirmTitleStyle.Render("⚠  CONFIRMAR EXCLUSÃO"))
+	}
 	b.WriteString("\n\n")
 
 	// File info
-	b.WriteString("Arquivo: ")
-	b.WriteString(fileInfoStyle.Render(m.relPath))
-	b.WriteString("\n\n")
+	if len(m.relPaths) == 1 {
+		b.WriteString("Arquivo: ")
+		b.WriteString(fileInfoStyle.Render(m.relPaths[0]))
+		b.WriteString("\n\n")
+	} else {
+		b.WriteString(fmt.Sprintf("Arquivos (%d):\n", len(m.relPaths)))
+		for i, relPath := range m.relPaths {
+			if i == maxListedFiles {
+				b.WriteString(fileInfoStyle.Render(fmt.Sprintf("… e mais %d", len(m.relPaths)-maxListedFiles)))
+				b.WriteString("\n")
+				break
+			}
+			b.WriteString(fileInfoStyle.Render("• " + relPath))
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
 
 	// Warning
-	b.WriteString(warningStyle.Render("Esta ação não pode ser desfeita!"))
+	if m.permanent {
+		b.WriteString(warningStyle.Render("Esta ação não pode ser desfeita!"))
+	} else if len(m.relPaths) == 1 {
+		b.WriteString(trashInfoStyle.Render("A nota será movida para a lixeira (margi trash restore)."))
+	} else {
+		b.WriteString(trashInfoStyle.Render("As notas serão movidas para a lixeira (margi trash restore)."))
+	}
 	b.WriteString("\n\n")
 
 	// Buttons
-	b.WriteString("Tem certeza que deseja excluir este arquivo?\n\n")
+	target := "este arquivo"
+	if len(m.relPaths) > 1 {
+		target = fmt.Sprintf("estes %d arquivos", len(m.relPaths))
+	}
+	if m.permanent {
+		b.WriteString(fmt.Sprintf("Tem certeza que deseja excluir %s permanentemente?\n\n", target))
+	} else {
+		b.WriteString(fmt.Sprintf("Mover %s para a lixeira?\n\n", target))
+	}
 
 	// No button (default)
 	if m.cursor == 0 {
@@ -133,30 +201,50 @@ func (m ConfirmModel) View() string {
 	b.WriteString("    ")
 
 	// Yes button
+	yesLabel := "[Y] Sim, mover para a lixeira"
+	if m.permanent {
+		yesLabel = "[Y] Sim, excluir"
+	}
 	if m.cursor == 1 {
-		b.WriteString(selectedButtonStyle.Render("▸ [Y] Sim, excluir"))
+		b.WriteString(selectedButtonStyle.Render("▸ " + yesLabel))
 	} else {
-		b.WriteString(confirmButtonStyle.Render("  [Y] Sim, excluir"))
+		b.WriteString(confirmButtonStyle.Render("  " + yesLabel))
 	}
 
 	b.WriteString("\n\n")
 
 	// Help text
-	b.WriteString(helpStyle.Render("[←→/hl/Tab] navegar • [Enter] confirmar • [Esc/Q] cancelar"))
+	if m.permanent {
+		b.WriteString(helpStyle.Render("[←→/hl/Tab] navegar • [Enter] confirmar • [Esc] voltar • [Q] cancelar"))
+	} else {
+		b.WriteString(helpStyle.Render("[←→/hl/Tab] navegar • [Enter] confirmar • [D] excluir permanentemente • [Esc/Q] cancelar"))
+	}
 
 	return b.String()
 }
 
-// RunConfirmDialog runs the confirmation dialog and returns true if confirmed
-func RunConfirmDialog(filePath string, dataDir string) (bool, error) {
-	model := NewConfirmModel(filePath, dataDir)
+// Action returns what the user chose
+func (m ConfirmModel) Action() ConfirmAction {
+	switch {
+	case !m.confirmed:
+		return ConfirmCancel
+	case m.permanent:
+		return ConfirmPermanent
+	default:
+		return ConfirmTrash
+	}
+}
+
+// RunConfirmDialog runs the confirmation dialog and returns the chosen action
+func RunConfirmDialog(filePaths []string, dataDir string) (ConfirmAction, error) {
+	model := NewConfirmModel(filePaths, dataDir)
 
 	finalModel, err := runProgram(model)
 	if err != nil {
-		return false, err
+		return ConfirmCancel, err
 	}
 
 	m := finalModel.(ConfirmModel)
 
-	return m.confirmed, nil
+	return m.Action(), nil
 }
@@ -0,0 +1,92 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func pressKey(m ConfirmModel, key string) ConfirmModel {
+	var msg tea.KeyMsg
+	switch key {
+	case "enter":
+		msg = tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		msg = tea.KeyMsg{Type: tea.KeyEsc}
+	default:
+		msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+	}
+	updated, _ := m.Update(msg)
+	return updated.(ConfirmModel)
+}
+
+func TestConfirmDefaultsToTrash(t *testing.T) {
+	m := NewConfirmModel([]string{"/data/work/note.md"}, "/data")
+
+	if !strings.Contains(m.View(), "lixeira") {
+		t.Error("Expected dialog to mention the trash")
+	}
+
+	m = pressKey(m, "y")
+	if m.Action() != ConfirmTrash {
+		t.Errorf("Expected ConfirmTrash after y, got %v", m.Action())
+	}
+}
+
+func TestConfirmEnterOnNoCancels(t *testing.T) {
+	m := NewConfirmModel([]string{"/data/work/note.md"}, "/data")
+
+	m = pressKey(m, "enter")
+	if m.Action() != ConfirmCancel {
+		t.Errorf("Expected ConfirmCancel, got %v", m.Action())
+	}
+}
+
+func TestConfirmPermanentIsExtraStep(t *testing.T) {
+	m := NewConfirmModel([]string{"/data/work/note.md"}, "/data")
+
+	m = pressKey(m, "l") // Move to "Yes"
+	m = pressKey(m, "d")
+	if !m.permanent {
+		t.Fatal("Expected permanent mode after d")
+	}
+	if m.cursor != 0 {
+		t.Error("Expected cursor to reset to No in permanent mode")
+	}
+	if !strings.Contains(m.View(), "não pode ser desfeita") {
+		t.Error("Expected permanent warning in view")
+	}
+
+	// Esc goes back to the trash question instead of cancelling
+	m = pressKey(m, "esc")
+	if m.permanent || m.cancelled {
+		t.Fatal("Expected Esc to leave permanent mode without cancelling")
+	}
+
+	m = pressKey(m, "d")
+	m = pressKey(m, "y")
+	if m.Action() != ConfirmPermanent {
+		t.Errorf("Expected ConfirmPermanent, got %v", m.Action())
+	}
+}
+
+func TestConfirmListsAllFiles(t *testing.T) {
+	var paths []string
+	for i := 0; i < maxListedFiles+2; i++ {
+		paths = append(paths, fmt.Sprintf("/data/work/note-%02d.md", i))
+	}
+	m := NewConfirmModel(paths, "/data")
+
+	view := m.View()
+	if !strings.Contains(view, "Arquivos (12)") {
+		t.Error("Expected view to show the number of files")
+	}
+	if !strings.Contains(view, "work/note-00.md") || !strings.Contains(view, "work/note-09.md") {
+		t.Error("Expected view to list the first files")
+	}
+	if strings.Contains(view, "work/note-10.md") || !strings.Contains(view, "e mais 2") {
+		t.Error("Expected view to summarize files past the limit")
+	}
+}
@@ -0,0 +1,122 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/gcaixeta/marginalia/internal/storage"
+)
+
+var conflictTitleStyle = lipgloss.NewStyle().
+	Bold(true).
+	Foreground(lipgloss.Color("214")).
+	PaddingBottom(1)
+
+// ConflictChoice is how the user wants a conflicted note resolved
+type ConflictChoice int
+
+const (
+	ConflictQuit   ConflictChoice = iota // Leave the remaining notes for later
+	ConflictOurs                         // Keep the local version
+	ConflictTheirs                       // Keep the remote version
+	ConflictBoth                         // Keep both as separate notes
+	ConflictEdit                         // Merge by hand in the editor
+)
+
+// ConflictPickerModel lists the notes left conflicted by a pull and asks
+// how to resolve one of them
+type ConflictPickerModel struct {
+	conflicts []storage.Conflict
+	cursor    int
+	choice    ConflictChoice
+}
+
+// NewConflictPickerModel creates a picker over conflicts
+func NewConflictPickerModel(conflicts []storage.Conflict) ConflictPickerModel {
+	return ConflictPickerModel{conflicts: conflicts}
+}
+
+func (m ConflictPickerModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m ConflictPickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	switch keyMsg.String() {
+	case "ctrl+c", "q", "esc":
+		m.choice = ConflictQuit
+		return m, tea.Quit
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.conflicts)-1 {
+			m.cursor++
+		}
+	case "o":
+		return m.choose(ConflictOurs)
+	case "t":
+		return m.choose(ConflictTheirs)
+	case "b":
+		return m.choose(ConflictBoth)
+	case "e", "enter":
+		return m.choose(ConflictEdit)
+	}
+
+	return m, nil
+}
+
+func (m ConflictPickerModel) choose(choice ConflictChoice) (tea.Model, tea.Cmd) {
+	if len(m.conflicts) == 0 {
+		return m, nil
+	}
+	m.choice = choice
+	return m, tea.Quit
+}
+
+func (m ConflictPickerModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(conflictTitleStyle.Render(fmt.Sprintf("⚠  %d note(s) with merge conflicts", len(m.conflicts))))
+	b.WriteString("\n")
+
+	for i, c := range m.conflicts {
+		if i == m.cursor {
+			b.WriteString(selectedFileStyle.Render("▸ " + c.Rel()))
+		} else {
+			b.WriteString(normalFileStyle.Render(c.Rel()))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(helpStyle.Render("[o] keep mine • [t] keep theirs • [b] keep both • [e/Enter] edit merged file • [q] quit"))
+	return b.String()
+}
+
+// Selected returns the highlighted note and what to do with it
+func (m ConflictPickerModel) Selected() (storage.Conflict, ConflictChoice) {
+	if m.choice == ConflictQuit || len(m.conflicts) == 0 {
+		return storage.Conflict{}, ConflictQuit
+	}
+	return m.conflicts[m.cursor], m.choice
+}
+
+// RunConflictPicker shows the conflicted notes and returns the one the
+// user picked along with the chosen resolution
+func RunConflictPicker(conflicts []storage.Conflict) (storage.Conflict, ConflictChoice, error) {
+	finalModel, err := runProgram(NewConflictPickerModel(conflicts))
+	if err != nil {
+		return storage.Conflict{}, ConflictQuit, err
+	}
+
+	conflict, choice := finalModel.(ConflictPickerModel).Selected()
+	return conflict, choice, nil
+}
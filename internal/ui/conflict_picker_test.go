@@ -0,0 +1,54 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gcaixeta/marginalia/internal/storage"
+)
+
+func newTestConflictModel() ConflictPickerModel {
+	return NewConflictPickerModel([]storage.Conflict{
+		{Collection: "work", Name: "plan.md"},
+		{Collection: "journal", Name: "today.md"},
+	})
+}
+
+func pressConflictKey(m ConflictPickerModel, key string) ConflictPickerModel {
+	msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+	if key == "down" {
+		msg = tea.KeyMsg{Type: tea.KeyDown}
+	}
+	updated, _ := m.Update(msg)
+	return updated.(ConflictPickerModel)
+}
+
+func TestConflictPickerChoices(t *testing.T) {
+	tests := map[string]ConflictChoice{
+		"o": ConflictOurs,
+		"t": ConflictTheirs,
+		"b": ConflictBoth,
+		"e": ConflictEdit,
+		"q": ConflictQuit,
+	}
+	for key, want := range tests {
+		m := pressConflictKey(pressConflictKey(newTestConflictModel(), "down"), key)
+		c, choice := m.Selected()
+		if choice != want {
+			t.Errorf("key %q: choice = %d, want %d", key, choice, want)
+		}
+		if want != ConflictQuit && c.Rel() != "journal/today.md" {
+			t.Errorf("key %q: selected %s, want journal/today.md", key, c.Rel())
+		}
+	}
+}
+
+func TestConflictPickerView(t *testing.T) {
+	view := newTestConflictModel().View()
+	for _, want := range []string{"2 note(s)", "work/plan.md", "journal/today.md", "keep theirs"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view is missing %q", want)
+		}
+	}
+}
@@ -51,6 +51,10 @@ var (
 	modeFilterStyle = lipgloss.NewStyle().
 				Foreground(lipgloss.Color("205")).
 				Bold(true)
+
+	markStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Bold(true)
 )
 
 // DeletePickerModel holds the state of the file deletion picker
@@ -59,7 +63,8 @@ type DeletePickerModel struct {
 	filteredFiles []storage.FileItem
 	input         string
 	cursor        int
-	selected      *storage.FileItem
+	marked        map[string]bool // Paths of the files marked for deletion
+	selected      []storage.FileItem
 	cancelled     bool
 	err           error
 	filterMode    bool
@@ -86,6 +91,7 @@ func NewDeletePickerModel(initialFilter string) (DeletePickerModel, error) {
 		allFiles: files,
 		input:    initialFilter,
 		cursor:   0,
+		marked:   map[string]bool{},
 	}
 
 	model.updateFilteredFiles()
@@ -115,8 +121,7 @@ func (m DeletePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.filterMode = false
 
 			case "enter":
-				if len(m.filteredFiles) > 0 {
-					m.selected = &m.filteredFiles[m.cursor]
+				if m.selectForDeletion() {
 					return m, tea.Quit
 				}
 
@@ -143,8 +148,7 @@ func (m DeletePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, tea.Quit
 
 			case "enter":
-				if len(m.filteredFiles) > 0 {
-					m.selected = &m.filteredFiles[m.cursor]
+				if m.selectForDeletion() {
 					return m, tea.Quit
 				}
 
@@ -160,6 +164,24 @@ func (m DeletePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			case "/":
 				m.filterMode = true
+
+			case " ":
+				if len(m.filteredFiles) > 0 {
+					m.toggleMark(m.filteredFiles[m.cursor])
+					if m.cursor < len(m.filteredFiles)-1 {
+						m.cursor++
+					}
+				}
+
+			case "a":
+				for _, file := range m.filteredFiles {
+					m.setMark(file, true)
+				}
+
+			case "i":
+				for _, file := range m.filteredFiles {
+					m.toggleMark(file)
+				}
 			}
 		}
 	}
@@ -167,6 +189,36 @@ func (m DeletePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// selectForDeletion sets selected to the marked files, or to the file under
+// the cursor when nothing is marked. It reports whether anything was selected.
+func (m *DeletePickerModel) selectForDeletion() bool {
+	m.selected = nil
+	for _, file := range m.allFiles {
+		if m.marked[file.Path] {
+			m.selected = append(m.selected, file)
+		}
+	}
+	if len(m.selected) == 0 && len(m.filteredFiles) > 0 {
+		m.selected = []storage.FileItem{m.filteredFiles[m.cursor]}
+	}
+	return len(m.selected) > 0
+}
+
+func (m *DeletePickerModel) toggleMark(file storage.FileItem) {
+	m.setMark(file, !m.marked[file.Path])
+}
+
+func (m *DeletePickerModel) setMark(file storage.FileItem, marked bool) {
+	if m.marked == nil {
+		m.marked = map[string]bool{}
+	}
+	if marked {
+		m.marked[file.Path] = true
+	} else {
+		delete(m.marked, file.Path)
+	}
+}
+
 // View renders the UI
 func (m DeletePickerModel) View() string {
 	if m.cancelled {
@@ -180,8 +232,12 @@ func (m DeletePickerModel) View() string {
 			helpStyle.Render(fmt.Sprintf("  %d note(s)  [Esc] normal • [Enter] select", len(m.filteredFiles)))
 	} else {
 		statusline = modeNormalStyle.Render("-- NORMAL --") +
-			helpStyle.Render(fmt.Sprintf("  %d note(s)  [↑↓/jk] navigate • [/] filter • [Enter] select • [Q] cancel", len(m.filteredFiles)))
+			helpStyle.Render(fmt.Sprintf("  %d note(s)  [↑↓/jk] navigate • [Space] mark • [a] all • [i] invert • [/] filter • [Enter] select • [Q] cancel", len(m.filteredFiles)))
+	}
+	if len(m.marked) > 0 {
+		statusline += markStyle.Render(fmt.Sprintf("  %d marked", len(m.marked)))
 	}
+	statusline += pendingIndicator()
 
 	// 2. Determine maxVisible based on available height
 	statuslineH := lipgloss.Height(statusline)
@@ -199,7 +255,7 @@ func (m DeletePickerModel) View() string {
 	var b strings.Builder
 
 	// Title
-	b.WriteString(deleteTitleStyle.Render("Select Note to Delete"))
+	b.WriteString(deleteTitleStyle.Render("Select Notes to Delete"))
 	b.WriteString("\n\n")
 
 	// Input field
@@ -252,7 +308,11 @@ func (m DeletePickerModel) View() string {
 				cursor = "▸ "
 				style = selectedFileStyle
 			}
-			line := style.Render(fmt.Sprintf("%s%s %s", cursor, file.Name, fileDateStyle.Render(fmt.Sprintf("(%s)", dateStr))))
+			mark := "  "
+			if m.marked[file.Path] {
+				mark = markStyle.Render("✗ ")
+			}
+			line := style.Render(fmt.Sprintf("%s%s%s %s", cursor, mark, file.Name, fileDateStyle.Render(fmt.Sprintf("(%s)", dateStr))))
 			flatList = append(flatList, flatItem{fileIdx: i, label: line})
 		}
 
@@ -336,8 +396,9 @@ func (m *DeletePickerModel) updateFilteredFiles() {
 	}
 }
 
-// RunDeletePicker runs the delete picker and returns the selected file or nil if cancelled
-func RunDeletePicker(initialFilter string) (*storage.FileItem, error) {
+// RunDeletePicker runs the delete picker and returns the files marked for
+// deletion, or the highlighted file when none were marked
+func RunDeletePicker(initialFilter string) ([]storage.FileItem, error) {
 	model, err := NewDeletePickerModel(initialFilter)
 	if err != nil {
 		return nil, err
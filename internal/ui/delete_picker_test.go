@@ -100,7 +100,7 @@ func TestDeletePickerEnterSelectsInFilterMode(t *testing.T) {
 	updated, cmd := m.Update(msg)
 	m = updated.(DeletePickerModel)
 
-	if m.selected == nil {
+	if len(m.selected) == 0 {
 		t.Error("Expected selected to be set after Enter in filter mode")
 	}
 	if cmd == nil {
@@ -136,3 +136,52 @@ func TestDeletePickerViewShowsMode(t *testing.T) {
 		t.Error("Expected view to show cursor █ in filter mode")
 	}
 }
+
+func TestDeletePickerMarkWithSpace(t *testing.T) {
+	m := newTestDeleteModel()
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	m = updated.(DeletePickerModel)
+
+	if !m.marked["/tmp/note-one.md"] {
+		t.Error("Expected note-one to be marked after space")
+	}
+	if m.cursor != 1 {
+		t.Errorf("Expected cursor to advance after marking, got %d", m.cursor)
+	}
+	if !strings.Contains(m.View(), "1 marked") {
+		t.Error("Expected status line to show marked count")
+	}
+
+	// Enter returns the marked files, not the one under the cursor
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(DeletePickerModel)
+	if len(m.selected) != 1 || m.selected[0].Name != "note-one" {
+		t.Errorf("Expected only note-one selected, got %v", m.selected)
+	}
+	if cmd == nil {
+		t.Error("Expected quit command after Enter")
+	}
+}
+
+func TestDeletePickerSelectAllAndInvert(t *testing.T) {
+	m := newTestDeleteModel()
+	m.input = "one"
+	m.updateFilteredFiles()
+
+	// "a" marks only the filtered files
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}})
+	m = updated.(DeletePickerModel)
+	if len(m.marked) != 1 || !m.marked["/tmp/note-one.md"] {
+		t.Errorf("Expected only note-one marked, got %v", m.marked)
+	}
+
+	// Clear the filter and invert: note-one unmarked, note-two marked
+	m.input = ""
+	m.updateFilteredFiles()
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'i'}})
+	m = updated.(DeletePickerModel)
+	if len(m.marked) != 1 || !m.marked["/tmp/note-two.md"] {
+		t.Errorf("Expected only note-two marked after invert, got %v", m.marked)
+	}
+}
@@ -0,0 +1,305 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/gcaixeta/marginalia/internal/storage"
+)
+
+// Styles for the diff preview
+var (
+	diffAddStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("71"))
+
+	diffDelStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("167"))
+
+	diffHunkStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("39"))
+)
+
+// HistoryAction is what the user wants done with a revision
+type HistoryAction int
+
+const (
+	HistoryQuit    HistoryAction = iota // Leave the history
+	HistoryRestore                      // Restore the note to the revision
+	HistoryView                         // Open the revision read-only
+)
+
+// DiffFunc returns the changes a revision made to the note
+type DiffFunc func(storage.Revision) (string, error)
+
+// HistoryPickerModel lists the revisions of a note next to the diff each
+// one made
+type HistoryPickerModel struct {
+	title      string // The note, as "collection/name"
+	revisions  []storage.Revision
+	diff       DiffFunc
+	cursor     int
+	confirming bool // Waiting for y/n before restoring
+	action     HistoryAction
+	width      int
+	height     int
+
+	diffHash   string // Revision currently rendered in diffLines
+	diffWidth  int    // Width diffLines were rendered for
+	diffLines  []string
+	diffScroll int
+}
+
+// NewHistoryPickerModel creates a picker over a note's revisions, newest
+// first, with the cursor on revision cursor
+func NewHistoryPickerModel(title string, revisions []storage.Revision, diff DiffFunc, cursor int) HistoryPickerModel {
+	return HistoryPickerModel{
+		title:     title,
+		revisions: revisions,
+		diff:      diff,
+		cursor:    min(max(cursor, 0), max(len(revisions)-1, 0)),
+	}
+}
+
+func (m HistoryPickerModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m HistoryPickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	m, cmd := m.handleMsg(msg)
+	m.syncDiff()
+	return m, cmd
+}
+
+func (m HistoryPickerModel) handleMsg(msg tea.Msg) (HistoryPickerModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		return m, nil
+	case tea.KeyMsg:
+		if m.confirming {
+			switch msg.String() {
+			case "y", "Y":
+				m.action = HistoryRestore
+				return m, tea.Quit
+			case "ctrl+c":
+				m.action = HistoryQuit
+				return m, tea.Quit
+			default:
+				m.confirming = false
+			}
+			return m, nil
+		}
+
+		switch msg.String() {
+		case "ctrl+c", "esc", "q", "Q":
+			m.action = HistoryQuit
+			return m, tea.Quit
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(m.revisions)-1 {
+				m.cursor++
+			}
+		case "J", "ctrl+d", "pgdown":
+			m.scrollDiff(m.diffHeight() / 2)
+		case "K", "ctrl+u", "pgup":
+			m.scrollDiff(-m.diffHeight() / 2)
+		case "r":
+			if len(m.revisions) > 0 {
+				m.confirming = true
+			}
+		case "enter", "o":
+			if len(m.revisions) > 0 {
+				m.action = HistoryView
+				return m, tea.Quit
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// diffVisible reports whether the split view fits in the terminal
+func (m HistoryPickerModel) diffVisible() bool {
+	return m.width >= minPreviewWidth && m.height > 0
+}
+
+// paneWidths splits the terminal width between the list and the diff
+func (m HistoryPickerModel) paneWidths() (listW, diffW int) {
+	listW = m.width * 2 / 5
+	if listW < 30 {
+		listW = 30
+	}
+	diffW = m.width - listW - 2 // border + padding
+	return listW, diffW
+}
+
+// diffHeight is the number of diff lines that fit on screen
+func (m HistoryPickerModel) diffHeight() int {
+	return max(m.height-4, 1) // statusline (2) + diff header + blank line
+}
+
+func (m *HistoryPickerModel) scrollDiff(delta int) {
+	maxScroll := len(m.diffLines) - m.diffHeight()
+	m.diffScroll = min(max(m.diffScroll+delta, 0), max(maxScroll, 0))
+}
+
+// syncDiff renders the diff of the highlighted revision when it or the
+// pane width changed
+func (m *HistoryPickerModel) syncDiff() {
+	if !m.diffVisible() || len(m.revisions) == 0 || m.diff == nil {
+		return
+	}
+
+	rev := m.revisions[m.cursor]
+	_, diffW := m.paneWidths()
+	if rev.Hash == m.diffHash && diffW == m.diffWidth {
+		return
+	}
+
+	diff, err := m.diff(rev)
+	if err != nil {
+		m.diffLines = []string{errorStyle.Render(fmt.Sprintf("Cannot read revision: %v", err))}
+	} else {
+		m.diffLines = renderDiff(diff, diffW)
+	}
+	if rev.Hash != m.diffHash {
+		m.diffScroll = 0
+	}
+	m.diffHash = rev.Hash
+	m.diffWidth = diffW
+	m.scrollDiff(0)
+}
+
+// renderDiff colors a unified diff, skipping git's file headers
+func renderDiff(diff string, width int) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimRight(diff, "\n"), "\n") {
+		line = truncate(strings.ReplaceAll(line, "\t", "    "), width)
+		switch {
+		case strings.HasPrefix(line, "diff --git"), strings.HasPrefix(line, "index "),
+			strings.HasPrefix(line, "--- "), strings.HasPrefix(line, "+++ "):
+			continue
+		case strings.HasPrefix(line, "@@"):
+			lines = append(lines, diffHunkStyle.Render(line))
+		case strings.HasPrefix(line, "+"):
+			lines = append(lines, diffAddStyle.Render(line))
+		case strings.HasPrefix(line, "-"):
+			lines = append(lines, diffDelStyle.Render(line))
+		default:
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		lines = append(lines, emptyMessageStyle.UnsetPaddingLeft().Render("No changes to this note"))
+	}
+	return lines
+}
+
+func (m HistoryPickerModel) renderDiffPane(width, height int) string {
+	var b strings.Builder
+
+	if len(m.revisions) > 0 {
+		header := m.revisions[m.cursor].Short() + " " + m.revisions[m.cursor].Subject
+		if len(m.diffLines) > m.diffHeight() {
+			end := min(m.diffScroll+m.diffHeight(), len(m.diffLines))
+			header += fmt.Sprintf("  %d–%d/%d", m.diffScroll+1, end, len(m.diffLines))
+		}
+		b.WriteString(helpStyle.UnsetPaddingTop().Render(truncate(header, width)))
+		b.WriteString("\n\n")
+
+		end := min(m.diffScroll+m.diffHeight(), len(m.diffLines))
+		for i := m.diffScroll; i < end; i++ {
+			b.WriteString(m.diffLines[i])
+			b.WriteString("\n")
+		}
+	}
+
+	return previewPaneStyle.Width(width).Height(height).MaxHeight(height).Render(b.String())
+}
+
+func (m HistoryPickerModel) View() string {
+	var statusline string
+	if m.confirming {
+		rev := m.revisions[m.cursor]
+		statusline = warningStyle.UnsetPadding().Render(fmt.Sprintf("Restore %s to %s (%s)? [y/N]",
+			m.title, rev.Short(), rev.Date.Local().Format("2006-01-02 15:04")))
+	} else {
+		help := "[↑↓/jk] navigate • [Enter/o] open read-only • [r] restore • [Q] quit"
+		if m.diffVisible() {
+			help = "[↑↓/jk] navigate • [J/K] scroll diff • [Enter/o] open read-only • [r] restore • [Q] quit"
+		}
+		statusline = helpStyle.Render(fmt.Sprintf("%d revision(s)  %s", len(m.revisions), help))
+	}
+	statuslineH := lipgloss.Height(statusline)
+
+	maxVisible := 15
+	if m.height > 0 {
+		maxVisible = max(m.height-statuslineH-3, 1) // title(2) + blank
+	}
+
+	var b strings.Builder
+	b.WriteString(browseTitleStyle.Render("History of " + m.title))
+	b.WriteString("\n\n")
+
+	if len(m.revisions) == 0 {
+		b.WriteString(emptyMessageStyle.Render("No committed history"))
+		b.WriteString("\n")
+	}
+
+	start := 0
+	if m.cursor >= maxVisible {
+		start = m.cursor - maxVisible + 1
+	}
+	end := min(start+maxVisible, len(m.revisions))
+	for i := start; i < end; i++ {
+		rev := m.revisions[i]
+		date := fileDateStyle.Render(rev.Date.Local().Format("2006-01-02 15:04"))
+		if i == m.cursor {
+			b.WriteString(browseSelectedStyle.Render(fmt.Sprintf("▸ %s %s %s", rev.Short(), date, rev.Subject)))
+		} else {
+			b.WriteString(browseNormalStyle.Render(fmt.Sprintf("  %s %s %s", rev.Short(), date, rev.Subject)))
+		}
+		b.WriteString("\n")
+	}
+
+	content := b.String()
+
+	if m.diffVisible() {
+		listW, diffW := m.paneWidths()
+		contentH := m.height - statuslineH
+		content = lipgloss.JoinHorizontal(lipgloss.Top,
+			lipgloss.NewStyle().Width(listW).MaxWidth(listW).Render(content),
+			m.renderDiffPane(diffW, contentH),
+		)
+	}
+
+	if m.height > 0 {
+		contentArea := lipgloss.NewStyle().Height(m.height - statuslineH).Render(content)
+		return lipgloss.JoinVertical(lipgloss.Top, contentArea, statusline)
+	}
+	return content + "\n" + statusline
+}
+
+// Selected returns the index of the highlighted revision and what to do
+// with it
+func (m HistoryPickerModel) Selected() (int, HistoryAction) {
+	return m.cursor, m.action
+}
+
+// RunHistoryPicker shows a note's revisions and returns the index of the
+// one the user picked along with the chosen action
+func RunHistoryPicker(title string, revisions []storage.Revision, diff DiffFunc, cursor int) (int, HistoryAction, error) {
+	finalModel, err := runProgram(NewHistoryPickerModel(title, revisions, diff, cursor))
+	if err != nil {
+		return cursor, HistoryQuit, err
+	}
+
+	index, action := finalModel.(HistoryPickerModel).Selected()
+	return index, action, nil
+}
@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gcaixeta/marginalia/internal/storage"
+)
+
+func newTestHistoryModel() HistoryPickerModel {
+	revisions := []storage.Revision{
+		{Hash: "bbbbbbbbbbbb", Subject: "edit: plan", Date: time.Now()},
+		{Hash: "aaaaaaaaaaaa", Subject: "add: plan", Date: time.Now().Add(-time.Hour)},
+	}
+	diff := func(rev storage.Revision) (string, error) {
+		return "diff --git a/work/plan.md b/work/plan.md\n@@ -1 +1 @@\n-old " + rev.Short() + "\n+new " + rev.Short() + "\n", nil
+	}
+	return NewHistoryPickerModel("work/plan.md", revisions, diff, 0)
+}
+
+func sendHistoryKey(m HistoryPickerModel, msg tea.Msg) HistoryPickerModel {
+	updated, _ := m.Update(msg)
+	return updated.(HistoryPickerModel)
+}
+
+func historyKey(key string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+}
+
+func TestHistoryPickerDiffPreview(t *testing.T) {
+	m := sendHistoryKey(newTestHistoryModel(), tea.WindowSizeMsg{Width: 120, Height: 30})
+	if !strings.Contains(m.View(), "+new bbbbbbbb") {
+		t.Error("Expected the diff of the newest revision")
+	}
+
+	m = sendHistoryKey(m, historyKey("j"))
+	view := m.View()
+	if !strings.Contains(view, "+new aaaaaaaa") || strings.Contains(view, "diff --git") {
+		t.Error("Expected the diff of the older revision without git headers")
+	}
+}
+
+func TestHistoryPickerRestoreNeedsConfirmation(t *testing.T) {
+	m := sendHistoryKey(newTestHistoryModel(), historyKey("j"))
+	m = sendHistoryKey(m, historyKey("r"))
+	if !m.confirming || !strings.Contains(m.View(), "Restore work/plan.md to aaaaaaaa") {
+		t.Fatal("Expected r to ask for confirmation")
+	}
+
+	// Anything but y backs out
+	m = sendHistoryKey(m, historyKey("n"))
+	if _, action := m.Selected(); m.confirming || action != HistoryQuit {
+		t.Fatal("Expected n to cancel the restore")
+	}
+
+	m = sendHistoryKey(sendHistoryKey(m, historyKey("r")), historyKey("y"))
+	if index, action := m.Selected(); index != 1 || action != HistoryRestore {
+		t.Errorf("Selected() = %d, %d; want 1, HistoryRestore", index, action)
+	}
+}
+
+func TestHistoryPickerOpen(t *testing.T) {
+	m := NewHistoryPickerModel("work/plan.md", newTestHistoryModel().revisions, nil, 5)
+	if index, _ := m.Selected(); index != 1 {
+		t.Errorf("Expected the cursor to be clamped to the last revision, got %d", index)
+	}
+
+	m = sendHistoryKey(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if _, action := m.Selected(); action != HistoryView {
+		t.Errorf("Expected Enter to open the revision, got %d", action)
+	}
+}
@@ -0,0 +1,211 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/gcaixeta/marginalia/internal/note"
+)
+
+// Styles for the Markdown preview pane
+var (
+	previewHeadingStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("75")).
+				Bold(true)
+
+	previewSubheadingStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("39")).
+				Bold(true)
+
+	previewCodeStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("180"))
+
+	previewQuoteStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("245")).
+				Italic(true)
+
+	previewFieldStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("241"))
+
+	previewBulletStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("170"))
+)
+
+var (
+	headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	bulletPattern  = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	orderedPattern = regexp.MustCompile(`^(\s*)(\d+[.)])\s+(.*)$`)
+)
+
+// renderPreview renders a note for the preview pane: front matter fields
+// first, then the body with styled headings, lists, quotes and code blocks.
+// Lines are wrapped (prose) or truncated (code) to width.
+func renderPreview(content []byte, width int) []string {
+	if width < 10 {
+		width = 10
+	}
+
+	var lines []string
+	meta, body, err := note.Parse(content)
+	if err == nil && meta.Format != note.FormatNone {
+		lines = append(lines, renderFields(meta, width)...)
+		lines = append(lines, previewFieldStyle.Render(strings.Repeat("─", width)))
+	}
+
+	inFence := false
+	for _, line := range strings.Split(strings.TrimRight(string(body), "\n"), "\n") {
+		line = strings.ReplaceAll(line, "\t", "    ")
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			lines = append(lines, previewCodeStyle.Render(truncate("  "+strings.Repeat("┄", width/2), width)))
+			continue
+		}
+		if inFence {
+			lines = append(lines, previewCodeStyle.Render(truncate("  "+line, width)))
+			continue
+		}
+
+		if m := headingPattern.FindStringSubmatch(line); m != nil {
+			style := previewSubheadingStyle
+			if len(m[1]) == 1 {
+				style = previewHeadingStyle
+			}
+			for _, l := range wrapText(m[2], width) {
+				lines = append(lines, style.Render(l))
+			}
+			continue
+		}
+
+		if m := bulletPattern.FindStringSubmatch(line); m != nil {
+			lines = append(lines, renderListItem(m[1], "•", m[2], width)...)
+			continue
+		}
+		if m := orderedPattern.FindStringSubmatch(line); m != nil {
+			lines = append(lines, renderListItem(m[1], m[2], m[3], width)...)
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, ">") {
+			text := strings.TrimSpace(strings.TrimPrefix(trimmed, ">"))
+			for _, l := range wrapText(text, width-2) {
+				lines = append(lines, previewQuoteStyle.Render("│ "+l))
+			}
+			continue
+		}
+
+		lines = append(lines, wrapText(line, width)...)
+	}
+
+	return lines
+}
+
+// renderFields renders the front matter as "key: value" lines, known
+// fields first and the rest sorted by key.
+func renderFields(meta note.Metadata, width int) []string {
+	var fields [][2]string
+	add := func(key, value string) {
+		if value != "" {
+			fields = append(fields, [2]string{key, value})
+		}
+	}
+	formatTime := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format("2006-01-02 15:04")
+	}
+
+	add("title", meta.Title)
+	if len(meta.Tags) > 0 {
+		add("tags", "#"+strings.Join(note.MergeTags(meta.Tags), " #"))
+	}
+	add("aliases", strings.Join(meta.Aliases, ", "))
+	add("created", formatTime(meta.Created))
+	add("updated", formatTime(meta.Updated))
+
+	keys := make([]string, 0, len(meta.Fields))
+	for key := range meta.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		add(key, fmt.Sprint(meta.Fields[key]))
+	}
+
+	var lines []string
+	for _, f := range fields {
+		lines = append(lines, previewFieldStyle.Render(truncate(f[0]+": "+f[1], width)))
+	}
+	return lines
+}
+
+func renderListItem(indent, marker, text string, width int) []string {
+	prefix := indent + marker + " "
+	pad := strings.Repeat(" ", lipgloss.Width(prefix))
+
+	var lines []string
+	for i, l := range wrapText(text, width-len(pad)) {
+		if i == 0 {
+			lines = append(lines, indent+previewBulletStyle.Render(marker)+" "+l)
+		} else {
+			lines = append(lines, pad+l)
+		}
+	}
+	return lines
+}
+
+// wrapText word-wraps plain text to width, hard-breaking words that are
+// longer than a line.
+func wrapText(text string, width int) []string {
+	if width < 1 {
+		width = 1
+	}
+	if strings.TrimSpace(text) == "" {
+		return []string{""}
+	}
+
+	var lines []string
+	var current []rune
+	for _, word := range strings.Fields(text) {
+		w := []rune(word)
+		for len(w) > width {
+			if len(current) > 0 {
+				lines = append(lines, string(current))
+				current = nil
+			}
+			lines = append(lines, string(w[:width]))
+			w = w[width:]
+		}
+		switch {
+		case len(current) == 0:
+			current = w
+		case len(current)+1+len(w) <= width:
+			current = append(append(current, ' '), w...)
+		default:
+			lines = append(lines, string(current))
+			current = w
+		}
+	}
+	if len(current) > 0 {
+		lines = append(lines, string(current))
+	}
+	return lines
+}
+
+// truncate cuts s to width runes, marking the cut with an ellipsis
+func truncate(s string, width int) string {
+	r := []rune(s)
+	if len(r) <= width {
+		return s
+	}
+	if width < 1 {
+		return ""
+	}
+	return string(r[:width-1]) + "…"
+}
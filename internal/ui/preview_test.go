@@ -0,0 +1,73 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/gcaixeta/marginalia/internal/storage"
+)
+
+func TestRenderPreview(t *testing.T) {
+	content := []byte("---\ntitle: Plan\ntags: [work]\nproject: apollo\n---\n" +
+		"# Plan\n\n- first item\n1. numbered\n> quoted\n```\ncode line\n```\n")
+
+	lines := renderPreview(content, 40)
+	text := strings.Join(lines, "\n")
+
+	for _, want := range []string{"title: Plan", "tags: #work", "project: apollo", "• first item", "1. numbered", "│ quoted", "code line"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected preview to contain %q, got:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "# Plan") || strings.Contains(text, "```") {
+		t.Errorf("Expected Markdown syntax to be rendered, got:\n%s", text)
+	}
+}
+
+func TestRenderPreviewWrapsToWidth(t *testing.T) {
+	long := strings.Repeat("word ", 30)
+	for _, line := range renderPreview([]byte(long+"\n"), 20) {
+		if w := lipgloss.Width(line); w > 20 {
+			t.Errorf("Line %q is %d wide, want <= 20", line, w)
+		}
+	}
+}
+
+func TestBrowsePickerPreviewToggle(t *testing.T) {
+	store := storage.NewMemStore()
+	storage.UseStore(store)
+	defer storage.UseStore(nil)
+	file, err := store.Create("journal", "note-one", []byte("# Hello preview\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := newTestBrowseModel()
+	m.allFiles[0].Path = file.Path
+	m.showPreview = true
+
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 120, Height: 30})
+	m = updated.(BrowsePickerModel)
+	if !m.previewVisible() {
+		t.Fatal("Expected preview to be visible on a wide terminal")
+	}
+	if !strings.Contains(m.View(), "Hello preview") {
+		t.Error("Expected view to contain the rendered preview")
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'p'}})
+	m = updated.(BrowsePickerModel)
+	if m.previewVisible() || strings.Contains(m.View(), "Hello preview") {
+		t.Error("Expected p to hide the preview")
+	}
+
+	// Narrow terminals fall back to the list only
+	m.showPreview = true
+	updated, _ = m.Update(tea.WindowSizeMsg{Width: 60, Height: 30})
+	m = updated.(BrowsePickerModel)
+	if m.previewVisible() {
+		t.Error("Expected preview to be hidden on a narrow terminal")
+	}
+}
@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/gcaixeta/marginalia/internal/snippet"
+)
+
+var promptLabelStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("245"))
+
+// PromptFormModel asks for the values a collection template declares
+// before the note is created
+type PromptFormModel struct {
+	title     string
+	prompts   []snippet.Prompt
+	values    []string
+	cursor    int
+	done      bool
+	cancelled bool
+}
+
+// NewPromptFormModel creates a form over prompts, each field starting at
+// the prompt's initial value
+func NewPromptFormModel(title string, prompts []snippet.Prompt) PromptFormModel {
+	values := make([]string, len(prompts))
+	for i, p := range prompts {
+		values[i] = p.Initial()
+	}
+	return PromptFormModel{title: title, prompts: prompts, values: values}
+}
+
+func (m PromptFormModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m PromptFormModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok || len(m.prompts) == 0 {
+		return m, nil
+	}
+	p := m.prompts[m.cursor]
+
+	switch keyMsg.Type {
+	case tea.KeyCtrlC, tea.KeyEsc:
+		m.cancelled = true
+		return m, tea.Quit
+	case tea.KeyEnter:
+		if m.cursor == len(m.prompts)-1 {
+			m.done = true
+			return m, tea.Quit
+		}
+		m.cursor++
+	case tea.KeyTab, tea.KeyDown:
+		m.cursor = (m.cursor + 1) % len(m.prompts)
+	case tea.KeyShiftTab, tea.KeyUp:
+		m.cursor = (m.cursor + len(m.prompts) - 1) % len(m.prompts)
+	case tea.KeyLeft, tea.KeyRight:
+		if len(p.Choices) > 0 {
+			step := 1
+			if keyMsg.Type == tea.KeyLeft {
+				step = len(p.Choices) - 1
+			}
+			i := slices.Index(p.Choices, m.values[m.cursor])
+			m.values[m.cursor] = p.Choices[(i+step)%len(p.Choices)]
+		}
+	case tea.KeyBackspace:
+		if len(p.Choices) == 0 && m.values[m.cursor] != "" {
+			runes := []rune(m.values[m.cursor])
+			m.values[m.cursor] = string(runes[:len(runes)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		if len(p.Choices) == 0 {
+			m.values[m.cursor] += string(keyMsg.Runes)
+		}
+	}
+
+	return m, nil
+}
+
+func (m PromptFormModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render("📝 " + m.title))
+	b.WriteString("\n")
+
+	for i, p := range m.prompts {
+		value := m.values[i]
+		if len(p.Choices) > 0 {
+			value = "◂ " + value + " ▸"
+		}
+		if i == m.cursor {
+			if len(p.Choices) == 0 {
+				value += "█"
+			}
+			b.WriteString(selectedItemStyle.Render("▸ " + p.Label + ": "))
+			b.WriteString(inputStyle.Render(value))
+		} else {
+			b.WriteString(normalItemStyle.Render(promptLabelStyle.Render(p.Label + ": ")))
+			b.WriteString(value)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString(helpStyle.Render("[Tab/↓] next • [Shift+Tab/↑] previous • [←/→] change choice • [Enter] next/create • [Esc] cancel"))
+	return b.String()
+}
+
+// Values returns the answers keyed by prompt name, or nil when the form
+// was cancelled
+func (m PromptFormModel) Values() map[string]string {
+	if !m.done {
+		return nil
+	}
+	values := make(map[string]string, len(m.prompts))
+	for i, p := range m.prompts {
+		values[p.Name] = m.values[i]
+	}
+	return values
+}
+
+// RunPromptForm asks for the values of prompts for a note titled title
+func RunPromptForm(title string, prompts []snippet.Prompt) (map[string]string, error) {
+	finalModel, err := runProgram(NewPromptFormModel(title, prompts))
+	if err != nil {
+		return nil, err
+	}
+
+	values := finalModel.(PromptFormModel).Values()
+	if values == nil {
+		return nil, fmt.Errorf("cancelled")
+	}
+	return values, nil
+}
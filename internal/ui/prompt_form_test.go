@@ -0,0 +1,66 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gcaixeta/marginalia/internal/snippet"
+)
+
+func newTestPromptForm() PromptFormModel {
+	return NewPromptFormModel("Sync", []snippet.Prompt{
+		{Name: "attendees", Label: "Who attended?", Default: "me"},
+		{Name: "kind", Label: "Kind", Choices: []string{"standup", "retro", "planning"}},
+	})
+}
+
+func pressFormKeys(m PromptFormModel, keys ...tea.KeyMsg) PromptFormModel {
+	for _, key := range keys {
+		updated, _ := m.Update(key)
+		m = updated.(PromptFormModel)
+	}
+	return m
+}
+
+func runes(s string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func TestPromptForm(t *testing.T) {
+	m := pressFormKeys(newTestPromptForm(),
+		tea.KeyMsg{Type: tea.KeyBackspace},
+		tea.KeyMsg{Type: tea.KeyBackspace},
+		runes("ana"),
+		tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")},
+		runes("& bo"),
+		tea.KeyMsg{Type: tea.KeyEnter},
+		tea.KeyMsg{Type: tea.KeyLeft},
+		runes("x"),
+	)
+	if m.Values() != nil {
+		t.Fatal("the form finished before Enter on the last field")
+	}
+	m = pressFormKeys(m, tea.KeyMsg{Type: tea.KeyEnter})
+
+	got := m.Values()
+	if got["attendees"] != "ana & bo" || got["kind"] != "planning" {
+		t.Errorf("Values = %v", got)
+	}
+}
+
+func TestPromptForm_Cancel(t *testing.T) {
+	m := pressFormKeys(newTestPromptForm(), tea.KeyMsg{Type: tea.KeyEsc})
+	if m.Values() != nil {
+		t.Errorf("cancelled form returned %v", m.Values())
+	}
+}
+
+func TestPromptFormView(t *testing.T) {
+	view := newTestPromptForm().View()
+	for _, want := range []string{"Sync", "Who attended?", "me█", "◂ standup ▸"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view is missing %q:\n%s", want, view)
+		}
+	}
+}
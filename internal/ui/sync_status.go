@@ -0,0 +1,43 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var pendingStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("214")).
+	Bold(true)
+
+// PendingCounter reports how many commits are waiting to be pushed
+type PendingCounter interface {
+	Pending() int
+}
+
+// pendingCounter feeds the "not pushed" indicator of the pickers' status
+// lines; nil when no backup is configured
+var pendingCounter PendingCounter
+
+// UsePendingCounter makes the pickers show c's pending commits in their
+// status line
+func UsePendingCounter(c PendingCounter) {
+	pendingCounter = c
+}
+
+// pendingIndicator renders the "N commits not pushed" status line segment,
+// or "" when everything is pushed
+func pendingIndicator() string {
+	if pendingCounter == nil {
+		return ""
+	}
+	n := pendingCounter.Pending()
+	if n == 0 {
+		return ""
+	}
+	plural := "s"
+	if n == 1 {
+		plural = ""
+	}
+	return pendingStyle.Render(fmt.Sprintf("  ⇡ %d commit%s not pushed", n, plural))
+}
@@ -0,0 +1,26 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+type fakePendingCounter int
+
+func (f fakePendingCounter) Pending() int { return int(f) }
+
+func TestPickerStatusLineShowsPendingPushes(t *testing.T) {
+	m := newTestDeleteModel()
+	if strings.Contains(m.View(), "not pushed") {
+		t.Error("Expected no pending indicator without a counter")
+	}
+
+	UsePendingCounter(fakePendingCounter(3))
+	defer UsePendingCounter(nil)
+	if !strings.Contains(m.View(), "3 commits not pushed") {
+		t.Error("Expected the delete picker to show pending pushes")
+	}
+	if !strings.Contains(newTestBrowseModel().View(), "3 commits not pushed") {
+		t.Error("Expected the browse picker to show pending pushes")
+	}
+}